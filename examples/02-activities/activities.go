@@ -7,23 +7,24 @@ import (
 	"time"
 
 	"go.temporal.io/sdk/activity"
+
+	"temporal-go-examples/shared/errs"
+	"temporal-go-examples/shared/notify"
+	"temporal-go-examples/shared/ratelimit"
 )
 
+// Limiter keeps ProcessPayment and SendConfirmationEmail under the third-party
+// APIs' rate limits; the worker replaces it with ratelimit.FromEnv()
+var Limiter = ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.DefaultRules())
+
 // ValidateOrder checks if an order is valid
 // Activities can perform non-deterministic operations like database calls
 func ValidateOrder(ctx context.Context, order Order) error {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Validating order", "orderID", order.ID)
 
-	// Simulate some validation logic
-	if order.ID == "" {
-		return fmt.Errorf("order ID cannot be empty")
-	}
-	if order.Amount <= 0 {
-		return fmt.Errorf("order amount must be positive")
-	}
-	if order.Email == "" {
-		return fmt.Errorf("email cannot be empty")
+	if err := CheckOrder(order); err != nil {
+		return err
 	}
 
 	// Simulate some processing time
@@ -31,53 +32,90 @@ func ValidateOrder(ctx context.Context, order Order) error {
 
 	// Simulate occasional failures (10% chance)
 	if rand.Float32() < 0.1 {
-		return fmt.Errorf("validation service temporarily unavailable")
+		return errs.ServiceUnavailable.New("validation service temporarily unavailable")
 	}
 
 	logger.Info("Order validation successful", "orderID", order.ID)
 	return nil
 }
 
-// ProcessPayment processes the payment for an order
-// Returns a payment ID if successful
+// CheckOrder validates an order's fields without calling any service
+// The intake API runs it before starting a workflow, so bad orders are
+// rejected with a 400 instead of failing inside the workflow
+func CheckOrder(order Order) error {
+	if order.ID == "" {
+		return errs.InvalidOrder.New("order ID cannot be empty")
+	}
+	if order.Amount <= 0 {
+		return errs.InvalidOrder.New("order amount must be positive", order.ID)
+	}
+	if order.Email == "" {
+		return errs.InvalidOrder.New("email cannot be empty", order.ID)
+	}
+	if err := validateChannels(order); err != nil {
+		return err
+	}
+	for i, item := range order.Items {
+		if item.SKU == "" || item.Quantity <= 0 || item.UnitPrice < 0 {
+			return errs.InvalidOrder.New(fmt.Sprintf("line item %d needs a SKU, a positive quantity and a price", i+1), order.ID)
+		}
+	}
+	return nil
+}
+
+// ProcessPayment submits the payment for an order to the gateway
+// The gateway confirms by webhook, so the activity returns ErrResultPending and
+// the webhook receiver completes it later with the payment ID (see payments.go)
 func ProcessPayment(ctx context.Context, order Order) (string, error) {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Processing payment", "orderID", order.ID, "amount", order.Amount)
 
-	// Simulate payment processing time
+	// A retried attempt must not charge the customer twice: if an earlier
+	// attempt already submitted this payment, wait for that payment's webhook,
+	// or return its outcome if the webhook came while no attempt was waiting
+	if pending, resumed, err := resumePayment(ctx, order); err != nil {
+		return "", err
+	} else if resumed && pending.Status != "" {
+		logger.Info("Payment settled before this attempt started", "orderID", order.ID, "paymentID", pending.PaymentID, "status", pending.Status)
+		return paymentOutcome(pending.PaymentID, pending.Status, pending.Reason)
+	} else if resumed {
+		logger.Info("Payment already submitted, waiting for gateway webhook", "orderID", order.ID, "paymentID", pending.PaymentID)
+		return "", activity.ErrResultPending
+	}
+
+	// The gateway limits calls overall and per merchant
+	if err := Limiter.Wait(ctx, order.Merchant); err != nil {
+		return "", err
+	}
+
+	// Simulate the gateway accepting the request
 	time.Sleep(time.Millisecond * 200)
 
-	// Simulate payment failures (5% chance)
+	// Simulate the gateway rejecting the request (5% chance)
 	if rand.Float32() < 0.05 {
-		return "", fmt.Errorf("payment gateway error: insufficient funds")
+		return "", errs.DependencyFailure.New("payment gateway error: request rejected")
 	}
 
-	// Generate a mock payment ID
-	paymentID := fmt.Sprintf("pay_%d", time.Now().Unix())
+	paymentID, err := submitPayment(ctx, order)
+	if err != nil {
+		return "", err
+	}
 
-	logger.Info("Payment processed successfully", "orderID", order.ID, "paymentID", paymentID)
-	return paymentID, nil
+	// The activity stays open until the webhook arrives or StartToCloseTimeout passes
+	logger.Info("Payment submitted, waiting for gateway webhook", "orderID", order.ID, "paymentID", paymentID)
+	return "", activity.ErrResultPending
 }
 
 // SendConfirmationEmail sends a confirmation email to the customer
+// The email channel is SMTP when NOTIFY_SMTP_ADDR is set (see notifications.go)
 func SendConfirmationEmail(ctx context.Context, order Order, paymentID string) error {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Sending confirmation email", "orderID", order.ID, "email", order.Email)
 
-	// Simulate email sending time
-	time.Sleep(time.Millisecond * 150)
-
-	// Simulate email service failures (3% chance)
-	if rand.Float32() < 0.03 {
-		return fmt.Errorf("email service temporarily unavailable")
+	if err := Limiter.Wait(ctx, ""); err != nil {
+		return err
 	}
-
-	logger.Info("Confirmation email sent successfully",
-		"orderID", order.ID,
-		"email", order.Email,
-		"paymentID", paymentID,
-	)
-	return nil
+	return sendConfirmation(ctx, notify.Email, order, paymentID)
 }
 
 // Pro tip: Activities should be idempotent when possible
@@ -0,0 +1,83 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/workflow"
+
+	"temporal-go-examples/shared/errs"
+	"temporal-go-examples/shared/heartbeat"
+	"temporal-go-examples/shared/policies"
+)
+
+// OrderBatch is a large set of orders processed by one long-running activity
+type OrderBatch struct {
+	BatchID   string  `json:"batch_id"`
+	Orders    []Order `json:"orders"`
+	ChunkSize int     `json:"chunk_size"` // Orders processed between heartbeats
+}
+
+// OrderBatchResult summarises a processed batch
+type OrderBatchResult struct {
+	BatchID   string `json:"batch_id"`
+	Processed int    `json:"processed"`
+	Attempts  int32  `json:"attempts"` // Activity attempts it took
+	Resumes   int    `json:"resumes"`  // Attempts that resumed from a heartbeat
+}
+
+// OrderBatchWorkflow processes a batch of orders in a single heartbeating activity
+// The "long-running" policy gives ProcessOrderBatch a 10s HeartbeatTimeout, so a
+// crashed worker is noticed quickly and the retry resumes from the last chunk
+func OrderBatchWorkflow(ctx workflow.Context, batch OrderBatch) (OrderBatchResult, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("OrderBatchWorkflow started", "batchID", batch.BatchID, "orders", len(batch.Orders))
+
+	var result OrderBatchResult
+	err := policies.ExecuteActivity(ctx, ProcessOrderBatch, batch).Get(ctx, &result)
+	if err != nil {
+		logger.Error("Order batch failed", "error", err)
+		return result, fmt.Errorf("order batch %s failed: %w", batch.BatchID, err)
+	}
+
+	logger.Info("OrderBatchWorkflow completed", "processed", result.Processed, "attempts", result.Attempts)
+	return result, nil
+}
+
+// ProcessOrderBatch processes orders chunk by chunk, heartbeating after each chunk
+// Kill the worker mid-batch and restart it: the next attempt starts from the last
+// heartbeated chunk, not from the first order
+func ProcessOrderBatch(ctx context.Context, batch OrderBatch) (OrderBatchResult, error) {
+	logger := activity.GetLogger(ctx)
+	info := activity.GetInfo(ctx)
+	logger.Info("Processing order batch", "batchID", batch.BatchID, "orders", len(batch.Orders), "attempt", info.Attempt)
+
+	progress, err := heartbeat.ProcessChunks(ctx, len(batch.Orders), batch.ChunkSize, func(ctx context.Context, start, end int) error {
+		for _, order := range batch.Orders[start:end] {
+			// Simulate per-order work
+			time.Sleep(time.Millisecond * 100)
+			logger.Debug("Order processed", "orderID", order.ID)
+		}
+
+		// Simulate the order service failing mid-batch (5% per chunk)
+		if rand.Float32() < 0.05 {
+			return errs.ServiceUnavailable.New(fmt.Sprintf("order service unavailable after order %d", end))
+		}
+		logger.Info("Chunk processed", "batchID", batch.BatchID, "done", end, "total", len(batch.Orders))
+		return nil
+	})
+	if err != nil {
+		logger.Warn("Order batch stopped", "batchID", batch.BatchID, "done", progress.Offset, "error", err)
+		return OrderBatchResult{}, err
+	}
+
+	return OrderBatchResult{
+		BatchID:   batch.BatchID,
+		Processed: progress.Offset,
+		Attempts:  info.Attempt,
+		Resumes:   progress.Resumes,
+	}, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"go.temporal.io/sdk/client"
+
+	activities "temporal-go-examples/examples/02-activities"
+	"temporal-go-examples/shared"
+)
+
+func main() {
+	count := flag.Int("orders", 200, "Number of orders in the batch")
+	chunkSize := flag.Int("chunk", 20, "Orders processed between heartbeats")
+	flag.Parse()
+
+	// Create Temporal client
+	c, err := shared.CreateTemporalClient()
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	batch := activities.OrderBatch{
+		BatchID:   "order-batch-" + shared.RandomID(),
+		ChunkSize: *chunkSize,
+	}
+	for i := 0; i < *count; i++ {
+		batch.Orders = append(batch.Orders, activities.Order{
+			ID:      fmt.Sprintf("%s-%d", batch.BatchID, i),
+			UserID:  fmt.Sprintf("user-%d", i%50),
+			Email:   fmt.Sprintf("customer%d@example.com", i),
+			Amount:  19.99,
+			Product: "Monthly Box",
+		})
+	}
+
+	workflowRun, err := c.ExecuteWorkflow(context.Background(), client.StartWorkflowOptions{
+		ID:        batch.BatchID,
+		TaskQueue: shared.TaskQueue,
+	}, activities.OrderBatchWorkflow, batch)
+	if err != nil {
+		log.Fatalln("Unable to execute workflow", err)
+	}
+	shared.LogInfo("📦 Processing %d orders in chunks of %d. WorkflowID: %s", *count, *chunkSize, workflowRun.GetID())
+	shared.LogInfo("Try stopping the worker mid-batch and starting it again - the batch resumes from its last heartbeat")
+
+	var result activities.OrderBatchResult
+	if err := workflowRun.Get(context.Background(), &result); err != nil {
+		log.Fatalln("Workflow failed", err)
+	}
+	shared.LogInfo("✅ Processed %d orders in %d attempt(s), %d resumed from a heartbeat", result.Processed, result.Attempts, result.Resumes)
+}
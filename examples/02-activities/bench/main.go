@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+
+	activities "temporal-go-examples/examples/02-activities"
+	"temporal-go-examples/shared"
+	"temporal-go-examples/shared/policies"
+)
+
+// benchTaskQueue keeps benchmark runs away from the example's regular worker
+const benchTaskQueue = "local-activity-bench"
+
+// localActivityMarker is the marker name the SDK records a local activity's
+// result under. Other markers, such as GetVersion's and the catalog's
+// MutableSideEffect policies, are recorded in both modes and aren't counted
+const localActivityMarker = "LocalActivity"
+
+// stats summarises the runs of one mode
+type stats struct {
+	mode       string
+	runs       int
+	events     int
+	activities int // ActivityTaskScheduled events
+	markers    int // Local activity result markers
+	total      time.Duration
+}
+
+// Benchmark of regular vs local activities for ValidateOrder
+// Runs OrderProcessingWorkflow with ValidateOrder mapped first to a remote
+// policy, then to the local-check policy, and compares history size and latency
+func main() {
+	runs := flag.Int("runs", 10, "Workflows per mode")
+	flag.Parse()
+
+	// Create Temporal client
+	c, err := shared.CreateTemporalClient()
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	// Confirm payments straight away through an in-process webhook receiver,
+	// so the gateway's delay doesn't drown out the difference being measured
+	storePath := filepath.Join(os.TempDir(), "bench-pending-payments.json")
+	defer os.Remove(storePath)
+	activities.Payments = activities.NewPaymentStore(storePath)
+	receiver := httptest.NewServer(&activities.WebhookReceiver{Completer: c, Store: activities.Payments})
+	defer receiver.Close()
+	activities.PaymentWebhookURL = receiver.URL
+	activities.GatewayDelay = 0
+
+	// Run our own worker on a separate task queue
+	w := worker.New(c, benchTaskQueue, worker.Options{})
+	w.RegisterWorkflow(activities.OrderProcessingWorkflow)
+	w.RegisterActivity(activities.ValidateOrder)
+	w.RegisterActivity(activities.ProcessPayment)
+	w.RegisterActivity(activities.PriceOrder)
+	w.RegisterActivity(activities.ReserveInventory)
+	w.RegisterActivity(activities.CommitReservation)
+	w.RegisterActivity(activities.ReleaseReservation)
+	w.RegisterActivity(activities.VoidPayment)
+	w.RegisterActivity(activities.RefundPayment)
+	w.RegisterActivity(activities.SendConfirmationEmail)
+	w.RegisterActivity(activities.SendConfirmationWebhook)
+	w.RegisterActivity(activities.WriteConfirmationFile)
+	w.RegisterWorkflow(activities.NotificationOutboxWorkflow)
+	if err := w.Start(); err != nil {
+		log.Fatalln("Unable to start worker", err)
+	}
+	defer w.Stop()
+
+	var results []stats
+	for _, mode := range []string{policies.FastTransient, policies.LocalCheck} {
+		// Only workflows started after this see the new mapping
+		catalog := policies.BuiltinCatalog()
+		catalog.Activities["ValidateOrder"] = policies.ActivityPolicy{Policy: mode}
+		policies.Set(catalog)
+
+		shared.LogInfo("⏱️  Running %d workflows with ValidateOrder on %q", *runs, mode)
+		results = append(results, run(c, mode, *runs))
+	}
+
+	shared.LogInfo("📊 Results (averages per workflow)")
+	shared.LogInfo("   %-16s %8s %10s %8s %10s", "ValidateOrder", "events", "activities", "markers", "latency")
+	for _, s := range results {
+		n := float64(s.runs)
+		shared.LogInfo("   %-16s %8.1f %10.1f %8.1f %10s", s.mode,
+			float64(s.events)/n, float64(s.activities)/n, float64(s.markers)/n, (s.total / time.Duration(s.runs)).Round(time.Millisecond))
+	}
+}
+
+// run executes workflows one at a time and collects their history sizes and latencies
+func run(c client.Client, mode string, runs int) stats {
+	ctx := context.Background()
+	s := stats{mode: mode}
+	for i := 0; i < runs; i++ {
+		order := activities.Order{
+			ID:      fmt.Sprintf("bench-%s-%d", mode, i),
+			UserID:  "user-bench",
+			Email:   "bench@example.com",
+			Amount:  9.99,
+			Product: "Benchmark",
+		}
+
+		start := time.Now()
+		workflowRun, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+			ID:        "bench-" + shared.RandomID(),
+			TaskQueue: benchTaskQueue,
+		}, activities.OrderProcessingWorkflow, order)
+		if err != nil {
+			log.Fatalln("Unable to execute workflow", err)
+		}
+		if err := workflowRun.Get(ctx, nil); err != nil {
+			shared.LogError("Workflow %s failed: %v", workflowRun.GetID(), err)
+			continue
+		}
+		s.total += time.Since(start)
+		s.runs++
+
+		iter := c.GetWorkflowHistory(ctx, workflowRun.GetID(), workflowRun.GetRunID(), false, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+		for iter.HasNext() {
+			event, err := iter.Next()
+			if err != nil {
+				log.Fatalln("Unable to read history", err)
+			}
+			s.events++
+			switch event.GetEventType() {
+			case enumspb.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED:
+				s.activities++
+			case enumspb.EVENT_TYPE_MARKER_RECORDED:
+				if event.GetMarkerRecordedEventAttributes().GetMarkerName() == localActivityMarker {
+					s.markers++
+				}
+			}
+		}
+	}
+	if s.runs == 0 {
+		log.Fatalf("Every %s workflow failed", mode)
+	}
+	return s
+}
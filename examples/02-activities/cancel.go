@@ -0,0 +1,144 @@
+package activities
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/workflow"
+
+	"temporal-go-examples/shared/policies"
+)
+
+// CancelOrderUpdate is the update a customer sends to cancel an order
+const CancelOrderUpdate = "cancel-order"
+
+// Order steps, in the order OrderProcessingWorkflow goes through them
+const (
+	StepValidating = "validating"
+	StepPricing    = "pricing"
+	StepReserving  = "reserving"
+	StepPaying     = "paying"
+	StepCommitting = "committing"
+	StepNotifying  = "notifying"
+	StepCompleted  = "completed"
+)
+
+// How an order ended
+const (
+	OrderCompleted = "completed"
+	OrderCanceled  = "canceled"
+	OrderFailed    = "failed"
+)
+
+// What cancellation did about the payment
+const (
+	CompensationNone     = "none"     // Nothing had been charged
+	CompensationVoided   = "voided"   // The pending payment was voided at the gateway
+	CompensationRefunded = "refunded" // The captured payment was refunded
+)
+
+// CancelRequest is the argument of the cancel-order update
+type CancelRequest struct {
+	Reason      string `json:"reason"`
+	RequestedBy string `json:"requested_by,omitempty"`
+}
+
+// OrderResult is how an order ended
+type OrderResult struct {
+	OrderID       string `json:"order_id"`
+	Status        string `json:"status"` // completed, canceled or failed
+	Step          string `json:"step"`   // Last step the order reached
+	PaymentID     string `json:"payment_id,omitempty"`
+	ReservationID string `json:"reservation_id,omitempty"`
+	Compensation  string `json:"compensation,omitempty"` // For canceled orders: none, voided or refunded
+	RefundID      string `json:"refund_id,omitempty"`
+	StockReleased bool   `json:"stock_released,omitempty"`
+	Reason        string `json:"reason,omitempty"`    // Why the order was canceled
+	OutboxID      string `json:"outbox_id,omitempty"` // Outbox workflow still retrying failed confirmations
+	Message       string `json:"message"`
+
+	// The price breakdown PriceOrder computed, for line-item orders that got that far
+	Pricing *PriceBreakdown `json:"pricing,omitempty"`
+}
+
+// orderState tracks how far an order got, so a cancellation can be validated
+// against the current step and undo exactly what was done
+type orderState struct {
+	step            string
+	reservationID   string // Set once reserving starts; the ID is deterministic
+	paymentID       string // Set once paying starts; the ID is deterministic
+	paymentCaptured bool
+	committed       bool
+	cancel          *CancelRequest
+	result          *OrderResult    // Set once the order has ended
+	pricing         *PriceBreakdown // Set once PriceOrder succeeded
+}
+
+// validateCancel rejects cancellations the order can no longer honour
+// Validators must not change workflow state; rejected updates leave no trace in history
+func (s *orderState) validateCancel(ctx workflow.Context, request CancelRequest) error {
+	switch {
+	case s.result != nil:
+		return fmt.Errorf("order already %s", s.result.Status)
+	case s.cancel != nil:
+		return fmt.Errorf("order is already being canceled")
+	case s.step == StepNotifying || s.step == StepCompleted:
+		return fmt.Errorf("order is complete and can no longer be canceled")
+	}
+	return nil
+}
+
+// compensate undoes the order's side effects after a cancellation
+// It runs on a disconnected context so it still completes when the workflow
+// itself is being canceled
+func (s *orderState) compensate(ctx workflow.Context, order Order, reason string) OrderResult {
+	logger := workflow.GetLogger(ctx)
+	cleanupCtx, cancel := workflow.NewDisconnectedContext(ctx)
+	defer cancel()
+
+	result := OrderResult{
+		OrderID:       order.ID,
+		Status:        OrderCanceled,
+		Step:          s.step,
+		PaymentID:     s.paymentID,
+		ReservationID: s.reservationID,
+		Pricing:       s.pricing,
+		Compensation:  CompensationNone,
+		Reason:        reason,
+	}
+	logger.Info("Canceling order", "orderID", order.ID, "step", s.step, "reason", reason)
+
+	// Money first: void a payment that is still pending, refund a captured one
+	switch {
+	case s.paymentCaptured:
+		var refundID string
+		err := policies.ExecuteActivity(cleanupCtx, RefundPayment, s.paymentID, order.Amount).Get(cleanupCtx, &refundID)
+		if err != nil {
+			logger.Error("Unable to refund payment", "paymentID", s.paymentID, "error", err)
+		} else {
+			result.Compensation = CompensationRefunded
+			result.RefundID = refundID
+		}
+	case s.paymentID != "":
+		err := policies.ExecuteActivity(cleanupCtx, VoidPayment, s.paymentID).Get(cleanupCtx, nil)
+		if err != nil {
+			logger.Error("Unable to void payment", "paymentID", s.paymentID, "error", err)
+		} else {
+			result.Compensation = CompensationVoided
+		}
+	}
+
+	// Then stock, unless it was already committed
+	if s.reservationID != "" && !s.committed {
+		err := policies.ExecuteActivity(cleanupCtx, ReleaseReservation, s.reservationID).Get(cleanupCtx, nil)
+		if err != nil {
+			logger.Error("Unable to release reservation", "reservationID", s.reservationID, "error", err)
+		} else {
+			result.StockReleased = true
+		}
+	}
+
+	result.Message = fmt.Sprintf("Order %s canceled while %s: payment %s, stock released: %v",
+		order.ID, s.step, result.Compensation, result.StockReleased)
+	logger.Info("Order canceled", "orderID", order.ID, "compensation", result.Compensation, "stockReleased", result.StockReleased)
+	return result
+}
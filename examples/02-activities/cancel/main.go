@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"go.temporal.io/sdk/client"
+
+	activities "temporal-go-examples/examples/02-activities"
+	"temporal-go-examples/shared"
+)
+
+func main() {
+	workflowID := flag.String("workflow-id", "", "Order workflow to cancel; empty starts a new order and cancels it")
+	after := flag.Duration("after", 2*time.Second, "How long to let a new order run before canceling it")
+	reason := flag.String("reason", "customer changed their mind", "Why the order is canceled")
+	cancelWorkflow := flag.Bool("cancel-workflow", false, "Cancel the workflow itself instead of sending the cancel-order update")
+	flag.Parse()
+
+	// Create Temporal client
+	c, err := shared.CreateTemporalClient()
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	if *workflowID == "" {
+		order := activities.Order{
+			ID:      "order-" + shared.RandomID(),
+			UserID:  "user-67890",
+			Email:   "customer@example.com",
+			Amount:  49.99,
+			Product: "Premium Subscription",
+		}
+		workflowRun, err := shared.ExecuteWorkflow(c, activities.OrderProcessingWorkflow, order)
+		if err != nil {
+			log.Fatalln("Unable to execute workflow", err)
+		}
+		*workflowID = workflowRun.GetID()
+		shared.LogInfo("Started order %s (WorkflowID: %s), canceling in %s", order.ID, *workflowID, *after)
+		time.Sleep(*after)
+	}
+
+	if *cancelWorkflow {
+		// The workflow compensates on a disconnected context, then ends as canceled
+		if err := c.CancelWorkflow(ctx, *workflowID, ""); err != nil {
+			log.Fatalln("Unable to cancel workflow", err)
+		}
+		var result activities.OrderResult
+		err := c.GetWorkflow(ctx, *workflowID, "").Get(ctx, &result)
+		shared.LogInfo("Workflow ended: %v", err)
+		return
+	}
+
+	// The update's validator rejects the cancel if the order is already complete;
+	// otherwise the reply comes once compensation has finished
+	handle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+		WorkflowID:   *workflowID,
+		UpdateName:   activities.CancelOrderUpdate,
+		Args:         []interface{}{activities.CancelRequest{Reason: *reason, RequestedBy: "cancel-cli"}},
+		WaitForStage: client.WorkflowUpdateStageCompleted,
+	})
+	if err != nil {
+		log.Fatalln("Unable to send cancel-order update", err)
+	}
+	var result activities.OrderResult
+	if err := handle.Get(ctx, &result); err != nil {
+		shared.LogError("Cancel rejected: %v", err)
+		return
+	}
+
+	shared.LogInfo("Order %s %s at step %s", result.OrderID, result.Status, result.Step)
+	shared.LogInfo("   Payment: %s %s %s", result.PaymentID, result.Compensation, result.RefundID)
+	shared.LogInfo("   Stock released: %v", result.StockReleased)
+	shared.LogInfo("   %s", result.Message)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"log"
 
+	"go.temporal.io/sdk/client"
+
 	activities "temporal-go-examples/examples/02-activities"
 	"temporal-go-examples/shared"
 )
@@ -24,7 +26,38 @@ func main() {
 		Amount:  99.99,
 		Product: "Premium Subscription",
 	}
+	runOrder(c, order)
+
+	// A line-item order: quote the total first, then order for exactly that amount
+	cart := activities.Order{
+		ID:     "order-" + shared.RandomID(),
+		UserID: "user-67890",
+		Email:  "customer@example.com",
+		Items: []activities.LineItem{
+			{SKU: "TSHIRT-M", Quantity: 2, UnitPrice: 19.99},
+			{SKU: "MUG", Quantity: 1, UnitPrice: 12.50},
+		},
+		DiscountCode:   "SAVE10",
+		ShippingMethod: "standard",
+		// Confirm by email and keep a copy in the file sink
+		Channels: []string{"email", "file"},
+	}
+	quote, err := activities.Price(cart)
+	if err != nil {
+		log.Fatalln("Unable to price order", err)
+	}
+	for _, line := range quote.Lines {
+		shared.LogInfo("   %-9s %-28s %8.2f", line.Kind, line.Description, line.Amount)
+	}
+	cart.Amount = quote.Total
+	runOrder(c, cart)
 
+	// Pro tip: You can also get workflow execution info
+	shared.LogInfo("Check the Temporal Web UI at http://localhost:8080 to see the workflow execution!")
+}
+
+// runOrder starts OrderProcessingWorkflow for an order and waits for it
+func runOrder(c client.Client, order activities.Order) {
 	// Start the workflow
 	shared.LogInfo("Starting OrderProcessingWorkflow...")
 	shared.LogInfo("Order details: ID=%s, Amount=$%.2f, Email=%s",
@@ -39,16 +72,21 @@ func main() {
 		workflowRun.GetID(), workflowRun.GetRunID())
 
 	// Wait for the workflow to complete
-	var result string
+	var result activities.OrderResult
 	err = workflowRun.Get(context.Background(), &result)
 	if err != nil {
 		log.Fatalln("Workflow failed", err)
 	}
 
 	// Print the result
-	shared.LogInfo("Workflow result: %s", result)
+	shared.LogInfo("Workflow result: %s", result.Message)
+	if result.Pricing != nil {
+		shared.LogInfo("Charged %.2f: subtotal %.2f, discount %.2f, shipping %.2f, tax %.2f",
+			result.Pricing.Total, result.Pricing.Subtotal, result.Pricing.Discount, result.Pricing.Shipping, result.Pricing.Tax)
+	}
+	if result.Status == activities.OrderCanceled {
+		shared.LogInfo("Order was canceled: %s", result.Reason)
+		return
+	}
 	shared.LogInfo("Order processing completed! 🎉")
-
-	// Pro tip: You can also get workflow execution info
-	shared.LogInfo("Check the Temporal Web UI at http://localhost:8080 to see the workflow execution!")
 }
@@ -0,0 +1,340 @@
+package activities
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+
+	"temporal-go-examples/shared"
+	"temporal-go-examples/shared/errs"
+)
+
+// Order file formats
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// orderIDPattern is what an intake order ID may look like. The ID ends up in
+// workflow IDs, URL paths and email headers, so it can't hold "/", spaces or
+// line breaks
+var orderIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// OrderWorkflowID is the workflow ID for an order: the order ID is the business
+// key, so the same order can't be started twice unless its last run failed
+func OrderWorkflowID(orderID string) string {
+	return "order-processing-" + orderID
+}
+
+// FormatFromPath guesses a file's format from its extension
+func FormatFromPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	}
+	return "", fmt.Errorf("unknown order file format %q; use .csv or .jsonl", filepath.Ext(path))
+}
+
+// ReadOrders parses orders from CSV or JSONL
+//
+// JSONL has one Order per line, in the same JSON as the workflow input. CSV
+// needs a header row naming its columns: id, user_id, email, amount, product,
+// merchant, shipping_address, discount_code, shipping_method, channels and
+// items. channels is separated by ";" and items is "SKU:quantity:unit price"
+// separated by ";", e.g. "TSHIRT-M:2:19.99;MUG:1:12.50"
+func ReadOrders(r io.Reader, format string) ([]Order, error) {
+	switch format {
+	case FormatCSV:
+		return readCSVOrders(r)
+	case FormatJSONL:
+		return readJSONLOrders(r)
+	}
+	return nil, fmt.Errorf("unknown order format %q", format)
+}
+
+func readJSONLOrders(r io.Reader) ([]Order, error) {
+	var orders []Order
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var order Order
+		if err := json.Unmarshal([]byte(text), &order); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		orders = append(orders, order)
+	}
+	return orders, scanner.Err()
+}
+
+func readCSVOrders(r io.Reader) ([]Order, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["id"]; !ok {
+		return nil, fmt.Errorf("CSV header needs an id column")
+	}
+
+	var orders []Order
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return orders, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		order := Order{
+			ID:              field("id"),
+			UserID:          field("user_id"),
+			Email:           field("email"),
+			Product:         field("product"),
+			Merchant:        field("merchant"),
+			ShippingAddress: field("shipping_address"),
+			DiscountCode:    field("discount_code"),
+			ShippingMethod:  field("shipping_method"),
+		}
+		if amount := field("amount"); amount != "" {
+			if order.Amount, err = strconv.ParseFloat(amount, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid amount %q", line, amount)
+			}
+		}
+		if channels := field("channels"); channels != "" {
+			order.Channels = strings.Split(channels, ";")
+		}
+		if items := field("items"); items != "" {
+			if order.Items, err = parseItems(items); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		orders = append(orders, order)
+	}
+}
+
+// parseItems parses "SKU:quantity:unit price;..."
+func parseItems(s string) ([]LineItem, error) {
+	var items []LineItem
+	for _, part := range strings.Split(s, ";") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid item %q; want SKU:quantity:unit price", part)
+		}
+		quantity, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity in item %q", part)
+		}
+		price, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unit price in item %q", part)
+		}
+		items = append(items, LineItem{SKU: fields[0], Quantity: quantity, UnitPrice: price})
+	}
+	return items, nil
+}
+
+// CheckIntakeOrder is CheckOrder plus a check of the order ID's characters and,
+// for line-item orders, a check that the amount matches the priced total, so
+// the workflow's PriceOrder step won't reject it
+func CheckIntakeOrder(order Order) error {
+	if err := CheckOrder(order); err != nil {
+		return err
+	}
+	if err := CheckOrderID(order.ID); err != nil {
+		return err
+	}
+	if len(order.Items) == 0 {
+		return nil
+	}
+	quote, err := Price(order)
+	if err != nil {
+		return err
+	}
+	if math.Abs(quote.Total-order.Amount) > 0.005 {
+		return errs.InvalidOrder.New(fmt.Sprintf("amount %.2f does not match the priced total %.2f", order.Amount, quote.Total), order.ID)
+	}
+	return nil
+}
+
+// CheckOrderID rejects an order ID that isn't 1-128 letters, digits, ".", "_"
+// or "-", starting with a letter or digit
+func CheckOrderID(orderID string) error {
+	if !orderIDPattern.MatchString(orderID) {
+		return errs.InvalidOrder.New(fmt.Sprintf("order ID %q must be 1-128 letters, digits, '.', '_' or '-'", orderID))
+	}
+	return nil
+}
+
+// ErrDuplicateOrder means the order's workflow is running or has completed
+var ErrDuplicateOrder = errors.New("order already submitted")
+
+// OrderStarter starts and inspects order workflows; client.Client implements it
+type OrderStarter interface {
+	ExecuteWorkflow(ctx context.Context, options client.StartWorkflowOptions, workflow interface{}, args ...interface{}) (client.WorkflowRun, error)
+	DescribeWorkflowExecution(ctx context.Context, workflowID, runID string) (*workflowservice.DescribeWorkflowExecutionResponse, error)
+	GetWorkflow(ctx context.Context, workflowID string, runID string) client.WorkflowRun
+}
+
+// StartOrder starts OrderProcessingWorkflow under the order's business-key ID
+// Resubmitting an order that is running or completed returns ErrDuplicateOrder.
+// An order whose workflow failed, timed out or was terminated can be
+// resubmitted; the new run gets its own reservation and payment IDs
+func StartOrder(ctx context.Context, c OrderStarter, order Order) (client.WorkflowRun, error) {
+	run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:                                       OrderWorkflowID(order.ID),
+		TaskQueue:                                shared.TaskQueue,
+		WorkflowIDReusePolicy:                    enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY,
+		WorkflowExecutionErrorWhenAlreadyStarted: true,
+	}, OrderProcessingWorkflow, order)
+	var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+	if errors.As(err, &alreadyStarted) {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateOrder, order.ID)
+	}
+	return run, err
+}
+
+// IntakeServer is the HTTP intake API for orders
+//
+//	POST /orders       validate an order and start its workflow
+//	GET  /orders/{id}  the order workflow's status, and its result once closed
+type IntakeServer struct {
+	Starter OrderStarter
+}
+
+// IntakeResponse is returned by POST /orders
+type IntakeResponse struct {
+	OrderID    string `json:"order_id"`
+	WorkflowID string `json:"workflow_id,omitempty"`
+	RunID      string `json:"run_id,omitempty"`
+	StatusURL  string `json:"status_url,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// OrderStatusResponse is returned by GET /orders/{id}
+type OrderStatusResponse struct {
+	OrderID    string       `json:"order_id"`
+	WorkflowID string       `json:"workflow_id"`
+	RunID      string       `json:"run_id,omitempty"`
+	Status     string       `json:"status,omitempty"` // Running, Completed, Failed, Canceled, ...
+	Result     *OrderResult `json:"result,omitempty"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// Handler routes the intake API
+func (s *IntakeServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /orders", s.createOrder)
+	mux.HandleFunc("GET /orders/{id}", s.orderStatus)
+	return mux
+}
+
+func (s *IntakeServer) createOrder(w http.ResponseWriter, req *http.Request) {
+	var order Order
+	decoder := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&order); err != nil {
+		writeJSON(w, http.StatusBadRequest, IntakeResponse{Error: "invalid order: " + err.Error()})
+		return
+	}
+	if err := CheckIntakeOrder(order); err != nil {
+		writeJSON(w, http.StatusBadRequest, IntakeResponse{OrderID: order.ID, Error: errs.Classify(err).Message})
+		return
+	}
+
+	response := IntakeResponse{
+		OrderID:    order.ID,
+		WorkflowID: OrderWorkflowID(order.ID),
+		StatusURL:  "/orders/" + order.ID,
+	}
+	run, err := StartOrder(req.Context(), s.Starter, order)
+	switch {
+	case errors.Is(err, ErrDuplicateOrder):
+		response.Error = err.Error()
+		writeJSON(w, http.StatusConflict, response)
+		return
+	case err != nil:
+		response.Error = "unable to start order workflow: " + err.Error()
+		writeJSON(w, http.StatusBadGateway, response)
+		return
+	}
+
+	response.RunID = run.GetRunID()
+	shared.LogInfo("Order %s accepted, workflow %s", order.ID, response.WorkflowID)
+	w.Header().Set("Location", response.StatusURL)
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+func (s *IntakeServer) orderStatus(w http.ResponseWriter, req *http.Request) {
+	orderID := req.PathValue("id")
+	if err := CheckOrderID(orderID); err != nil {
+		writeJSON(w, http.StatusBadRequest, OrderStatusResponse{OrderID: orderID, Error: errs.Classify(err).Message})
+		return
+	}
+	workflowID := OrderWorkflowID(orderID)
+	description, err := s.Starter.DescribeWorkflowExecution(req.Context(), workflowID, "")
+	var notFound *serviceerror.NotFound
+	switch {
+	case errors.As(err, &notFound):
+		writeJSON(w, http.StatusNotFound, OrderStatusResponse{OrderID: orderID, WorkflowID: workflowID, Error: "unknown order"})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusBadGateway, OrderStatusResponse{OrderID: orderID, WorkflowID: workflowID, Error: err.Error()})
+		return
+	}
+
+	info := description.GetWorkflowExecutionInfo()
+	response := OrderStatusResponse{
+		OrderID:    orderID,
+		WorkflowID: workflowID,
+		RunID:      info.GetExecution().GetRunId(),
+		Status:     info.GetStatus().String(),
+	}
+	if info.GetStatus() != enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		var result OrderResult
+		if err := s.Starter.GetWorkflow(req.Context(), workflowID, response.RunID).Get(req.Context(), &result); err != nil {
+			response.Error = err.Error()
+		} else {
+			response.Result = &result
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	activities "temporal-go-examples/examples/02-activities"
+	"temporal-go-examples/shared"
+)
+
+func main() {
+	file := flag.String("file", "", "CSV or JSONL file of orders to start")
+	format := flag.String("format", "", "File format: csv or jsonl (default: from the file extension)")
+	rate := flag.Float64("rate", 5, "Maximum orders started per second")
+	addr := flag.String("addr", "", "Serve the HTTP intake API on this address (e.g. :8091) instead of reading a file")
+	flag.Parse()
+
+	// Create Temporal client
+	c, err := shared.CreateTemporalClient()
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	if *addr != "" {
+		server := &activities.IntakeServer{Starter: c}
+		shared.LogInfo("📥 Order intake API listening on %s (POST /orders, GET /orders/{id})", *addr)
+		log.Fatalln(http.ListenAndServe(*addr, server.Handler()))
+	}
+	if *file == "" || *rate <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *format == "" {
+		if *format, err = activities.FormatFromPath(*file); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalln("Unable to open order file", err)
+	}
+	orders, err := activities.ReadOrders(f, *format)
+	f.Close()
+	if err != nil {
+		log.Fatalln("Unable to read orders", err)
+	}
+	shared.LogInfo("Read %d orders from %s; starting at most %.1f/s", len(orders), *file, *rate)
+
+	// One start per tick keeps the intake from flooding the workers
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+	defer ticker.Stop()
+
+	var started, duplicates, invalid, failed int
+	for _, order := range orders {
+		if err := activities.CheckIntakeOrder(order); err != nil {
+			shared.LogError("❌ Order %q is invalid: %v", order.ID, err)
+			invalid++
+			continue
+		}
+		<-ticker.C
+
+		run, err := activities.StartOrder(context.Background(), c, order)
+		switch {
+		case errors.Is(err, activities.ErrDuplicateOrder):
+			shared.LogInfo("⏭️  Order %s was already submitted", order.ID)
+			duplicates++
+		case err != nil:
+			shared.LogError("❌ Unable to start order %s: %v", order.ID, err)
+			failed++
+		default:
+			shared.LogInfo("✅ Order %s started: %s", order.ID, run.GetID())
+			started++
+		}
+	}
+
+	shared.LogInfo("Started %d, already submitted %d, invalid %d, failed %d", started, duplicates, invalid, failed)
+	if invalid+failed > 0 {
+		os.Exit(1)
+	}
+}
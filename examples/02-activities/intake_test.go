@@ -0,0 +1,244 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+
+	"temporal-go-examples/shared/errs"
+)
+
+func TestReadCSVOrders(t *testing.T) {
+	input := `id, email, amount, product, channels, items
+order-1, a@example.com, 24.99, MUG, email;file,
+order-2, b@example.com, 52.48, , , TSHIRT-M:2:19.99;MUG:1:12.50
+`
+	orders, err := ReadOrders(strings.NewReader(input), FormatCSV)
+
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, Order{ID: "order-1", Email: "a@example.com", Amount: 24.99, Product: "MUG", Channels: []string{"email", "file"}}, orders[0])
+	assert.Equal(t, []LineItem{{SKU: "TSHIRT-M", Quantity: 2, UnitPrice: 19.99}, {SKU: "MUG", Quantity: 1, UnitPrice: 12.50}}, orders[1].Items)
+}
+
+func TestReadCSVOrdersErrors(t *testing.T) {
+	for _, test := range []struct {
+		name, input, err string
+	}{
+		{"no id column", "email,amount\na@example.com,1\n", "CSV header needs an id column"},
+		{"empty file", "", "unable to read CSV header"},
+		{"bad amount", "id,amount\norder-1,lots\n", `line 2: invalid amount "lots"`},
+		{"bad item", "id,items\norder-1,MUG:1\n", `line 2: invalid item "MUG:1"`},
+		{"bad item on a later line", "id,items\norder-1,MUG:1:12.50\norder-2,MUG:x:12.50\n", `line 3: invalid quantity in item "MUG:x:12.50"`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadOrders(strings.NewReader(test.input), FormatCSV)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestParseItems(t *testing.T) {
+	items, err := parseItems("TSHIRT-M:2:19.99; MUG:1:12.50")
+	require.NoError(t, err)
+	assert.Equal(t, []LineItem{{SKU: "TSHIRT-M", Quantity: 2, UnitPrice: 19.99}, {SKU: "MUG", Quantity: 1, UnitPrice: 12.50}}, items)
+
+	for input, want := range map[string]string{
+		"MUG":            `invalid item "MUG"; want SKU:quantity:unit price`,
+		"MUG:1:2:3":      `invalid item "MUG:1:2:3"`,
+		"MUG:two:12.50":  `invalid quantity in item "MUG:two:12.50"`,
+		"MUG:1:cheap":    `invalid unit price in item "MUG:1:cheap"`,
+		"MUG:1:1;;":      `invalid item ""`,
+		"MUG:1.5:12.50":  `invalid quantity`,
+		"MUG:1:12.50:EU": `invalid item`,
+	} {
+		_, err := parseItems(input)
+		if assert.Error(t, err, input) {
+			assert.Contains(t, err.Error(), want, input)
+		}
+	}
+}
+
+func TestReadJSONLOrders(t *testing.T) {
+	input := `{"id":"order-1","email":"a@example.com","amount":24.99,"product":"MUG"}
+
+{"id":"order-2","email":"b@example.com","amount":12.5,"items":[{"sku":"MUG","quantity":1,"unit_price":12.5}]}
+`
+	orders, err := ReadOrders(strings.NewReader(input), FormatJSONL)
+	require.NoError(t, err)
+	require.Len(t, orders, 2, "blank lines are skipped")
+	assert.Equal(t, "order-2", orders[1].ID)
+	assert.Len(t, orders[1].Items, 1)
+
+	_, err = ReadOrders(strings.NewReader(`{"id":"order-1"}`+"\n"+`{"id":`), FormatJSONL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+func TestCheckOrderID(t *testing.T) {
+	for _, id := range []string{"order-1", "ORD_2024.01", "1"} {
+		assert.NoError(t, CheckOrderID(id), id)
+	}
+	for _, id := range []string{"", "order/1", "../orders", "order 1", "order-1\r\nBcc: x@example.com", "order-1\n", "-order", strings.Repeat("a", 129)} {
+		err := CheckOrderID(id)
+		assert.True(t, errs.InvalidOrder.Is(err), "%q: %v", id, err)
+	}
+}
+
+// fakeRun is a started or closed order workflow
+type fakeRun struct {
+	id, runID string
+	result    OrderResult
+}
+
+func (r fakeRun) GetID() string    { return r.id }
+func (r fakeRun) GetRunID() string { return r.runID }
+
+func (r fakeRun) Get(ctx context.Context, valuePtr interface{}) error {
+	*valuePtr.(*OrderResult) = r.result
+	return nil
+}
+
+func (r fakeRun) GetWithOptions(ctx context.Context, valuePtr interface{}, options client.WorkflowRunGetOptions) error {
+	return r.Get(ctx, valuePtr)
+}
+
+// fakeStarter records the starts the intake asks Temporal for
+type fakeStarter struct {
+	started  []client.StartWorkflowOptions
+	statuses map[string]enumspb.WorkflowExecutionStatus // By workflow ID
+	results  map[string]OrderResult
+}
+
+func (f *fakeStarter) ExecuteWorkflow(ctx context.Context, options client.StartWorkflowOptions, workflow interface{}, args ...interface{}) (client.WorkflowRun, error) {
+	if _, ok := f.statuses[options.ID]; ok {
+		return nil, serviceerror.NewWorkflowExecutionAlreadyStarted("workflow already exists", "", "run-0")
+	}
+	f.started = append(f.started, options)
+	f.statuses[options.ID] = enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING
+	return fakeRun{id: options.ID, runID: "run-1"}, nil
+}
+
+func (f *fakeStarter) DescribeWorkflowExecution(ctx context.Context, workflowID, runID string) (*workflowservice.DescribeWorkflowExecutionResponse, error) {
+	status, ok := f.statuses[workflowID]
+	if !ok {
+		return nil, serviceerror.NewNotFound("workflow not found")
+	}
+	return &workflowservice.DescribeWorkflowExecutionResponse{
+		WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{
+			Execution: &commonpb.WorkflowExecution{WorkflowId: workflowID, RunId: "run-1"},
+			Status:    status,
+		},
+	}, nil
+}
+
+func (f *fakeStarter) GetWorkflow(ctx context.Context, workflowID string, runID string) client.WorkflowRun {
+	return fakeRun{id: workflowID, runID: runID, result: f.results[workflowID]}
+}
+
+func newTestIntake() (*IntakeServer, *fakeStarter) {
+	starter := &fakeStarter{
+		statuses: map[string]enumspb.WorkflowExecutionStatus{
+			OrderWorkflowID("order-done"): enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED,
+		},
+		results: map[string]OrderResult{
+			OrderWorkflowID("order-done"): {OrderID: "order-done", Status: OrderCompleted, PaymentID: "pay_1"},
+		},
+	}
+	return &IntakeServer{Starter: starter}, starter
+}
+
+func serveIntake(s *IntakeServer, method, path, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return recorder
+}
+
+const intakeOrder = `{"id":"order-1","user_id":"u-1","email":"a@example.com","amount":24.99,"product":"MUG"}`
+
+func TestIntakeStartsOrder(t *testing.T) {
+	s, starter := newTestIntake()
+
+	resp := serveIntake(s, http.MethodPost, "/orders", intakeOrder)
+
+	require.Equal(t, http.StatusAccepted, resp.Code, resp.Body.String())
+	assert.Equal(t, "/orders/order-1", resp.Header().Get("Location"))
+	var body IntakeResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, IntakeResponse{OrderID: "order-1", WorkflowID: "order-processing-order-1", RunID: "run-1", StatusURL: "/orders/order-1"}, body)
+	require.Len(t, starter.started, 1)
+	assert.Equal(t, enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY, starter.started[0].WorkflowIDReusePolicy)
+
+	// The same order again is a duplicate while it runs
+	resp = serveIntake(s, http.MethodPost, "/orders", intakeOrder)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.Len(t, starter.started, 1)
+}
+
+func TestIntakeRejectsInvalidOrders(t *testing.T) {
+	for _, test := range []struct {
+		name, body string
+	}{
+		{"not JSON", `{"id":`},
+		{"unknown field", `{"id":"order-1","email":"a@example.com","amount":1,"colour":"red"}`},
+		{"no email", `{"id":"order-1","amount":24.99}`},
+		{"slash in ID", `{"id":"order/1","email":"a@example.com","amount":24.99}`},
+		{"space in ID", `{"id":"order 1","email":"a@example.com","amount":24.99}`},
+		{"line break in ID", `{"id":"order-1\r\nBcc: x@example.com","email":"a@example.com","amount":24.99}`},
+		{"amount off the priced total", `{"id":"order-1","email":"a@example.com","amount":1,"items":[{"sku":"MUG","quantity":1,"unit_price":12.5}]}`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, starter := newTestIntake()
+
+			resp := serveIntake(s, http.MethodPost, "/orders", test.body)
+
+			assert.Equal(t, http.StatusBadRequest, resp.Code, resp.Body.String())
+			assert.Empty(t, starter.started)
+		})
+	}
+}
+
+func TestIntakeOrderStatus(t *testing.T) {
+	s, _ := newTestIntake()
+	require.Equal(t, http.StatusAccepted, serveIntake(s, http.MethodPost, "/orders", intakeOrder).Code)
+
+	for _, test := range []struct {
+		path   string
+		code   int
+		status string
+		result bool
+	}{
+		{"/orders/order-1", http.StatusOK, "Running", false},
+		{"/orders/order-done", http.StatusOK, "Completed", true},
+		{"/orders/order-unknown", http.StatusNotFound, "", false},
+		{"/orders/order%201", http.StatusBadRequest, "", false},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			resp := serveIntake(s, http.MethodGet, test.path, "")
+
+			require.Equal(t, test.code, resp.Code, resp.Body.String())
+			var body OrderStatusResponse
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			assert.Equal(t, test.status, body.Status)
+			if test.result {
+				require.NotNil(t, body.Result)
+				assert.Equal(t, "pay_1", body.Result.PaymentID)
+			} else {
+				assert.Nil(t, body.Result)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+
+	"temporal-go-examples/shared/errs"
+)
+
+// Reservation statuses
+const (
+	ReservationHeld      = "held"
+	ReservationCommitted = "committed"
+	ReservationReleased  = "released"
+)
+
+// ReservedItem is the stock of one SKU held by a reservation
+type ReservedItem struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+// Reservation is stock held for one order
+type Reservation struct {
+	ID      string         `json:"id"`
+	OrderID string         `json:"order_id"`
+	Items   []ReservedItem `json:"items"`
+	Status  string         `json:"status"`
+}
+
+// Inventory is an in-memory stand-in for the inventory service
+// Reserving takes stock off the shelf, releasing puts it back, committing makes it final
+type Inventory struct {
+	mu           sync.Mutex
+	stock        map[string]int // Units available to reserve, by SKU
+	reservations map[string]*Reservation
+}
+
+// NewInventory creates an inventory with the given stock levels
+func NewInventory(stock map[string]int) *Inventory {
+	inv := &Inventory{stock: make(map[string]int), reservations: make(map[string]*Reservation)}
+	for product, units := range stock {
+		inv.stock[product] = units
+	}
+	return inv
+}
+
+// DefaultInventory is the stock the inventory activities work against
+// Single-product orders use the product name as SKU; "Limited Edition Print"
+// runs out after three orders
+var DefaultInventory = NewInventory(map[string]int{
+	"Premium Subscription":  1000,
+	"Monthly Box":           5000,
+	"Benchmark":             100000,
+	"Limited Edition Print": 3,
+	"TSHIRT-M":              200,
+	"MUG":                   150,
+	"STICKER-PACK":          1000,
+})
+
+// reservationID is the same for every attempt of one workflow run, which makes
+// reserving idempotent and lets the workflow release a reservation whose
+// ReserveInventory result it never saw
+func reservationID(orderID, runID string) string {
+	return fmt.Sprintf("res_%s_%s", orderID, runID)
+}
+
+// Reserve holds stock for every item, or for none of them
+// Reserving a held or committed ID again returns it unchanged. A released ID is
+// rejected with ReservationReleased: the order gave up its stock, and a late or
+// retried reserve must not take it again behind the order's back
+func (inv *Inventory) Reserve(id, orderID string, items []ReservedItem) (Reservation, error) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if r, ok := inv.reservations[id]; ok {
+		if r.Status == ReservationReleased {
+			return *r, errs.ReservationReleased.New(fmt.Sprintf("reservation %s was already released", id), id)
+		}
+		return *r, nil
+	}
+
+	// Check everything before taking anything
+	wanted := make(map[string]int)
+	for _, item := range items {
+		wanted[item.SKU] += item.Quantity
+	}
+	for sku, quantity := range wanted {
+		available, ok := inv.stock[sku]
+		if !ok {
+			return Reservation{}, errs.InvalidOrder.New(fmt.Sprintf("unknown SKU %q", sku), orderID)
+		}
+		if available < quantity {
+			return Reservation{}, errs.OutOfStock.New(
+				fmt.Sprintf("only %d of %q left, %d requested", available, sku, quantity), sku, available)
+		}
+	}
+	for sku, quantity := range wanted {
+		inv.stock[sku] -= quantity
+	}
+
+	r := &Reservation{ID: id, OrderID: orderID, Items: items, Status: ReservationHeld}
+	inv.reservations[id] = r
+	return *r, nil
+}
+
+// Commit makes a held reservation final; committing twice is fine
+func (inv *Inventory) Commit(id string) (Reservation, error) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	r, ok := inv.reservations[id]
+	if !ok {
+		return Reservation{}, errs.Internal.New(fmt.Sprintf("reservation %s does not exist", id))
+	}
+	if r.Status == ReservationReleased {
+		return *r, errs.Internal.New(fmt.Sprintf("reservation %s was already released", id))
+	}
+	r.Status = ReservationCommitted
+	return *r, nil
+}
+
+// Release puts held stock back on the shelf
+// Releasing an already released reservation does nothing, so compensation can
+// always be retried safely. Releasing an unknown one records it as released:
+// the reserve may still be on its way, and Reserve refuses released IDs
+func (inv *Inventory) Release(id string) (Reservation, error) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	r, ok := inv.reservations[id]
+	if !ok {
+		r = &Reservation{ID: id, Status: ReservationReleased}
+		inv.reservations[id] = r
+		return *r, nil
+	}
+	switch r.Status {
+	case ReservationCommitted:
+		return *r, errs.Internal.New(fmt.Sprintf("reservation %s is committed and cannot be released", id))
+	case ReservationHeld:
+		for _, item := range r.Items {
+			inv.stock[item.SKU] += item.Quantity
+		}
+		r.Status = ReservationReleased
+	}
+	return *r, nil
+}
+
+// Available returns the units of a SKU that can still be reserved
+func (inv *Inventory) Available(sku string) int {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	return inv.stock[sku]
+}
+
+// ReserveInventory holds stock for the order's items and returns the reservation ID
+// Out of stock is a non-retryable OutOfStock error, and so is ReservationReleased
+// for an attempt that arrives after the order released its reservation
+func ReserveInventory(ctx context.Context, order Order) (string, error) {
+	logger := activity.GetLogger(ctx)
+	items := order.lineItems()
+	logger.Info("Reserving inventory", "orderID", order.ID, "items", len(items))
+
+	// Simulate the inventory service
+	time.Sleep(time.Millisecond * 100)
+
+	// Simulate inventory service failures (5% chance)
+	if rand.Float32() < 0.05 {
+		return "", errs.ServiceUnavailable.New("inventory service temporarily unavailable")
+	}
+
+	id := reservationID(order.ID, activity.GetInfo(ctx).WorkflowExecution.RunID)
+	reserved := make([]ReservedItem, 0, len(items))
+	for _, item := range items {
+		reserved = append(reserved, ReservedItem{SKU: item.SKU, Quantity: item.Quantity})
+	}
+	reservation, err := DefaultInventory.Reserve(id, order.ID, reserved)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Info("Inventory reserved", "orderID", order.ID, "reservationID", reservation.ID)
+	return reservation.ID, nil
+}
+
+// CommitReservation makes a reservation final once the payment is captured
+func CommitReservation(ctx context.Context, reservationID string) error {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Committing reservation", "reservationID", reservationID)
+
+	if _, err := DefaultInventory.Commit(reservationID); err != nil {
+		return err
+	}
+
+	logger.Info("Reservation committed", "reservationID", reservationID)
+	return nil
+}
+
+// ReleaseReservation puts a reservation's stock back when the order cannot go ahead
+func ReleaseReservation(ctx context.Context, reservationID string) error {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Releasing reservation", "reservationID", reservationID)
+
+	reservation, err := DefaultInventory.Release(reservationID)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Reservation released", "reservationID", reservationID, "items", len(reservation.Items))
+	return nil
+}
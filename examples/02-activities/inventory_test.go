@@ -0,0 +1,101 @@
+package activities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"temporal-go-examples/shared/errs"
+)
+
+var mugs = []ReservedItem{{SKU: "MUG", Quantity: 2}}
+
+func TestReserveIsIdempotent(t *testing.T) {
+	inv := NewInventory(map[string]int{"MUG": 5})
+
+	first, err := inv.Reserve("res-1", "order-1", mugs)
+	require.NoError(t, err)
+	// A retried attempt reserves the same ID again
+	second, err := inv.Reserve("res-1", "order-1", mugs)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, ReservationHeld, second.Status)
+	assert.Equal(t, 3, inv.Available("MUG"), "stock is taken once")
+}
+
+func TestReserveTakesAllOrNothing(t *testing.T) {
+	inv := NewInventory(map[string]int{"MUG": 5, "TSHIRT-M": 1})
+
+	_, err := inv.Reserve("res-1", "order-1", []ReservedItem{{SKU: "MUG", Quantity: 2}, {SKU: "TSHIRT-M", Quantity: 2}})
+	require.True(t, errs.OutOfStock.Is(err), "got %v", err)
+	assert.Equal(t, 5, inv.Available("MUG"), "nothing is taken when one item is short")
+
+	_, err = inv.Reserve("res-2", "order-2", []ReservedItem{{SKU: "NOPE", Quantity: 1}})
+	assert.True(t, errs.InvalidOrder.Is(err), "got %v", err)
+}
+
+func TestCommitIsIdempotent(t *testing.T) {
+	inv := NewInventory(map[string]int{"MUG": 5})
+	_, err := inv.Reserve("res-1", "order-1", mugs)
+	require.NoError(t, err)
+
+	for range 2 {
+		r, err := inv.Commit("res-1")
+		require.NoError(t, err)
+		assert.Equal(t, ReservationCommitted, r.Status)
+	}
+	assert.Equal(t, 3, inv.Available("MUG"))
+
+	// Committed stock is sold; it can't go back
+	_, err = inv.Release("res-1")
+	assert.True(t, errs.Internal.Is(err), "got %v", err)
+	assert.Equal(t, 3, inv.Available("MUG"))
+
+	_, err = inv.Commit("res-unknown")
+	assert.True(t, errs.Internal.Is(err), "got %v", err)
+}
+
+func TestReleaseIsIdempotent(t *testing.T) {
+	inv := NewInventory(map[string]int{"MUG": 5})
+	_, err := inv.Reserve("res-1", "order-1", mugs)
+	require.NoError(t, err)
+
+	for range 2 {
+		r, err := inv.Release("res-1")
+		require.NoError(t, err)
+		assert.Equal(t, ReservationReleased, r.Status)
+	}
+	assert.Equal(t, 5, inv.Available("MUG"), "stock is put back once")
+
+	_, err = inv.Commit("res-1")
+	assert.True(t, errs.Internal.Is(err), "a released reservation can't be committed: %v", err)
+}
+
+func TestReleasedReservationCannotBeReservedAgain(t *testing.T) {
+	inv := NewInventory(map[string]int{"MUG": 5})
+	_, err := inv.Reserve("res-1", "order-1", mugs)
+	require.NoError(t, err)
+	_, err = inv.Release("res-1")
+	require.NoError(t, err)
+
+	_, err = inv.Reserve("res-1", "order-1", mugs)
+	assert.True(t, errs.ReservationReleased.Is(err), "got %v", err)
+	assert.Equal(t, 5, inv.Available("MUG"))
+}
+
+func TestReleaseBeforeReserveLeavesATombstone(t *testing.T) {
+	inv := NewInventory(map[string]int{"MUG": 5})
+
+	// A cancel releases the reservation before the reserve attempt lands
+	r, err := inv.Release("res-1")
+	require.NoError(t, err)
+	assert.Equal(t, ReservationReleased, r.Status)
+
+	// The late attempt is refused instead of holding stock for a canceled order
+	_, err = inv.Reserve("res-1", "order-1", mugs)
+	require.True(t, errs.ReservationReleased.Is(err), "got %v", err)
+	assert.False(t, errs.Classify(err).Retryable)
+	assert.Equal(t, 5, inv.Available("MUG"))
+}
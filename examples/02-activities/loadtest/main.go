@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/testsuite"
+
+	activities "temporal-go-examples/examples/02-activities"
+	"temporal-go-examples/shared"
+	"temporal-go-examples/shared/errs"
+	"temporal-go-examples/shared/ratelimit"
+)
+
+// call is the outcome of one ProcessPayment call
+type call struct {
+	merchant string
+	finished time.Time
+	err      error
+}
+
+// Load test for the payment rate limiter
+// Fires ProcessPayment calls from many goroutines through Temporal's test
+// activity environment (no server needed) and reports the achieved rate
+// against the configured caps
+func main() {
+	calls := flag.Int("calls", 60, "Number of ProcessPayment calls")
+	concurrency := flag.Int("concurrency", 20, "Calls in flight at once")
+	merchants := flag.Int("merchants", 3, "Number of merchants the calls are spread over")
+	maxWait := flag.Duration("max-wait", time.Minute, "Longest a call waits for a token before failing with RateLimited")
+	flag.Parse()
+
+	limiter, err := ratelimit.FromEnv()
+	if err != nil {
+		shared.LogError("Unable to load rate limits: %v", err)
+		os.Exit(1)
+	}
+	limiter.MaxWait = *maxWait
+	activities.Limiter = limiter
+
+	// Payments are confirmed by webhook; the load test only measures submissions
+	activities.PaymentWebhookURL = ""
+	activities.Payments = activities.NewPaymentStore(filepath.Join(os.TempDir(), "loadtest-pending-payments.json"))
+	defer os.Remove(filepath.Join(os.TempDir(), "loadtest-pending-payments.json"))
+	rule := limiter.Rules["ProcessPayment"]
+
+	suite := &testsuite.WorkflowTestSuite{}
+	suite.SetLogger(log.NewStructuredLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))))
+
+	shared.LogInfo("🚀 %d calls, %d at a time, over %d merchants", *calls, *concurrency, *merchants)
+	shared.LogInfo("   Cap: %.1f/s overall (burst %d), %.1f/s per merchant (burst %d)",
+		rule.Activity.Rate, rule.Activity.Burst, rule.PerKey.Rate, rule.PerKey.Burst)
+
+	start := time.Now()
+	results := make(chan call, *calls)
+	sem := make(chan struct{}, *concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < *calls; i++ {
+		order := activities.Order{
+			ID:       fmt.Sprintf("load-%d", i),
+			Email:    "load@example.com",
+			Amount:   10,
+			Merchant: fmt.Sprintf("merchant-%d", i%*merchants),
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			env := suite.NewTestActivityEnvironment()
+			env.RegisterActivity(activities.ProcessPayment)
+			_, err := env.ExecuteActivity(activities.ProcessPayment, order)
+			results <- call{merchant: order.Merchant, finished: time.Now(), err: err}
+		}()
+	}
+	wg.Wait()
+	close(results)
+	elapsed := time.Since(start)
+
+	// Tally the results overall and per merchant
+	var succeeded, limited, failed int
+	perMerchant := make(map[string]int)
+	for r := range results {
+		switch {
+		case r.err == nil, errors.Is(r.err, activity.ErrResultPending):
+			succeeded++
+			perMerchant[r.merchant]++
+		case errs.RateLimited.Is(r.err):
+			limited++
+		default:
+			failed++
+		}
+	}
+
+	shared.LogInfo("📊 %d succeeded, %d rate limited, %d failed in %s", succeeded, limited, failed, elapsed.Round(time.Millisecond))
+	shared.LogInfo("   Achieved %.2f calls/s overall (cap %.1f/s plus a burst of %d)",
+		float64(succeeded)/elapsed.Seconds(), rule.Activity.Rate, rule.Activity.Burst)
+	names := make([]string, 0, len(perMerchant))
+	for name := range perMerchant {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		shared.LogInfo("   %s: %.2f calls/s (cap %.1f/s)", name, float64(perMerchant[name])/elapsed.Seconds(), rule.PerKey.Rate)
+	}
+
+	// Sustained rate can exceed the cap only by the burst
+	if rule.Activity.Rate > 0 {
+		allowed := float64(rule.Activity.Burst) + rule.Activity.Rate*elapsed.Seconds()
+		if float64(succeeded+failed) > allowed+1 {
+			shared.LogError("❌ %d calls got through, more than the %.0f the cap allows", succeeded+failed, allowed)
+			os.Exit(1)
+		}
+		shared.LogInfo("✅ Cap held: %d calls got through, at most %.0f allowed", succeeded+failed, allowed)
+	}
+}
@@ -0,0 +1,105 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+
+	"go.temporal.io/sdk/activity"
+
+	"temporal-go-examples/shared/errs"
+	"temporal-go-examples/shared/notify"
+)
+
+var (
+	// Notifiers sends confirmations on each channel; see notify.FromEnv for configuration
+	Notifiers = notify.FromEnv()
+
+	// Deliveries records every confirmation sent, so retries never send one twice
+	Deliveries = notify.DeliveryLogFromEnv()
+)
+
+// confirmationTemplate is rendered from confirmationData
+var confirmationTemplate = notify.MustParseTemplate("order-confirmation",
+	`Your order {{.Order.ID}} is confirmed`,
+	`Thanks for your order!
+
+Order: {{.Order.ID}}
+{{range .Items}}  {{.Quantity}} x {{.SKU}} @ {{printf "%.2f" .UnitPrice}}
+{{end}}
+Total charged: ${{printf "%.2f" .Order.Amount}}
+Payment reference: {{.PaymentID}}
+`)
+
+// confirmationData is what confirmation templates can use
+type confirmationData struct {
+	Order     Order
+	Items     []LineItem
+	PaymentID string
+}
+
+// Each channel has its own activity so the policy catalog can give it its own
+// timeouts and retries; the workflow picks the activity by name
+var confirmationActivities = map[string]string{
+	notify.Email:   "SendConfirmationEmail",
+	notify.Webhook: "SendConfirmationWebhook",
+	notify.File:    "WriteConfirmationFile",
+}
+
+// confirmationChannels returns the channels the customer chose, or email
+func (o Order) confirmationChannels() []string {
+	if len(o.Channels) == 0 {
+		return []string{notify.Email}
+	}
+	return o.Channels
+}
+
+// sendConfirmation renders the confirmation and delivers it on one channel
+func sendConfirmation(ctx context.Context, channel string, order Order, paymentID string) error {
+	logger := activity.GetLogger(ctx)
+
+	subject, body, err := confirmationTemplate.Render(confirmationData{Order: order, Items: order.lineItems(), PaymentID: paymentID})
+	if err != nil {
+		return err
+	}
+	// The payment ID is unique to the workflow run, so a retried activity finds
+	// its earlier delivery, but a new run that reuses the order ID still sends
+	msg := notify.Message{
+		Key:     fmt.Sprintf("%s:%s:%s:confirmation", order.ID, paymentID, channel),
+		Channel: channel,
+		To:      order.Email,
+		Subject: subject,
+		Body:    body,
+		Data:    map[string]string{"order_id": order.ID, "payment_id": paymentID, "amount": fmt.Sprintf("%.2f", order.Amount)},
+	}
+
+	delivery, sent, err := notify.Deliver(ctx, Notifiers, Deliveries, msg)
+	if err != nil {
+		return err
+	}
+	if !sent {
+		logger.Info("Confirmation already delivered", "orderID", order.ID, "channel", channel, "deliveredAt", delivery.DeliveredAt)
+		return nil
+	}
+	logger.Info("Confirmation delivered", "orderID", order.ID, "channel", channel, "to", order.Email, "paymentID", paymentID)
+	return nil
+}
+
+// SendConfirmationWebhook posts the confirmation to NOTIFY_WEBHOOK_URL
+func SendConfirmationWebhook(ctx context.Context, order Order, paymentID string) error {
+	return sendConfirmation(ctx, notify.Webhook, order, paymentID)
+}
+
+// WriteConfirmationFile appends the confirmation to the file sink
+func WriteConfirmationFile(ctx context.Context, order Order, paymentID string) error {
+	return sendConfirmation(ctx, notify.File, order, paymentID)
+}
+
+// validateChannels rejects channels the order cannot be confirmed on
+func validateChannels(order Order) error {
+	for _, channel := range order.Channels {
+		if _, ok := confirmationActivities[channel]; !ok {
+			return errs.InvalidOrder.New(fmt.Sprintf("unknown notification channel %q", channel), order.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	activities "temporal-go-examples/examples/02-activities"
+	"temporal-go-examples/shared"
+	"temporal-go-examples/shared/notify/notifytest"
+)
+
+func main() {
+	fakeSMTP := flag.String("fake-smtp", "", "Run a fake SMTP server on this address (e.g. :2525) and print the mail it receives")
+	reject := flag.String("reject", "", "Comma-separated recipients the fake SMTP server refuses with 550")
+	flag.Parse()
+
+	if *fakeSMTP != "" {
+		runFakeSMTP(*fakeSMTP, *reject)
+		return
+	}
+
+	// Without -fake-smtp, list what the workers have delivered
+	deliveries, err := activities.Deliveries.List()
+	if err != nil {
+		log.Fatalln("Unable to read delivery log", err)
+	}
+	shared.LogInfo("Configured channels: %s", strings.Join(activities.Notifiers.Channels(), ", "))
+	if len(deliveries) == 0 {
+		shared.LogInfo("No notifications delivered yet")
+		return
+	}
+	for _, d := range deliveries {
+		shared.LogInfo("%s  %-8s %-24s %s", d.DeliveredAt.Format("15:04:05"), d.Channel, d.To, d.Subject)
+	}
+}
+
+// runFakeSMTP serves until interrupted; point the worker at it with NOTIFY_SMTP_ADDR
+func runFakeSMTP(addr, reject string) {
+	server, err := notifytest.ListenSMTP(addr)
+	if err != nil {
+		log.Fatalln("Unable to start fake SMTP server", err)
+	}
+	defer server.Close()
+
+	server.Reject = make(map[string]bool)
+	for _, to := range strings.Split(reject, ",") {
+		if to != "" {
+			server.Reject[to] = true
+		}
+	}
+	server.OnMail = func(mail notifytest.ReceivedMail) {
+		shared.LogInfo("📧 Mail from %s to %s\n%s", mail.From, strings.Join(mail.To, ", "), mail.Data)
+	}
+
+	shared.LogInfo("Fake SMTP server listening on %s", server.Addr())
+	shared.LogInfo("Start the worker with NOTIFY_SMTP_ADDR=localhost%s", addr[strings.LastIndex(addr, ":"):])
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	<-stop
+}
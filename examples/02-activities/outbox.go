@@ -0,0 +1,220 @@
+package activities
+
+import (
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/workflow"
+
+	"temporal-go-examples/shared/errs"
+	"temporal-go-examples/shared/policies"
+)
+
+// PendingMessagesQuery returns the outbox's undelivered notifications
+const PendingMessagesQuery = "pending-messages"
+
+// Outbox notification statuses
+const (
+	NotificationPending   = "pending"
+	NotificationDelivered = "delivered"
+	NotificationRejected  = "rejected" // The channel refused it; retrying cannot help
+	NotificationExpired   = "expired"  // Still failing when the outbox gave up
+)
+
+// Outbox retry defaults: back off from 5 minutes up to 6 hours, give up after a week
+const (
+	DefaultOutboxInitialBackoff = time.Minute * 5
+	DefaultOutboxMaximumBackoff = time.Hour * 6
+	DefaultOutboxGiveUpAfter    = time.Hour * 24 * 7
+)
+
+// OutboxRequest hands failed confirmations over to NotificationOutboxWorkflow
+type OutboxRequest struct {
+	Order     Order    `json:"order"`
+	PaymentID string   `json:"payment_id"`
+	Channels  []string `json:"channels"`   // Channels whose confirmation failed
+	LastError string   `json:"last_error"` // Why the order workflow gave up
+
+	// Zero values use the defaults above
+	InitialBackoff time.Duration `json:"initial_backoff,omitempty"`
+	MaximumBackoff time.Duration `json:"maximum_backoff,omitempty"`
+	GiveUpAfter    time.Duration `json:"give_up_after,omitempty"`
+}
+
+// OutboxNotification is one confirmation the outbox is delivering
+type OutboxNotification struct {
+	Channel       string    `json:"channel"`
+	To            string    `json:"to"`
+	Status        string    `json:"status"`
+	Rounds        int       `json:"rounds"` // Activity executions so far, each with its own retries
+	LastError     string    `json:"last_error,omitempty"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt   time.Time `json:"delivered_at,omitempty"`
+}
+
+// OutboxResult is how each notification ended
+type OutboxResult struct {
+	OrderID       string               `json:"order_id"`
+	Notifications []OutboxNotification `json:"notifications"`
+}
+
+// OutboxWorkflowID is the outbox workflow for one run of an order's workflow
+// The same order ID can run again, e.g. as FulfillmentWorkflow's child, while
+// an earlier run's outbox is still retrying, so the ID includes the run ID.
+// An empty runID gives the ID outboxes had before that
+func OutboxWorkflowID(orderID, runID string) string {
+	if runID == "" {
+		return "notification-outbox-" + orderID
+	}
+	return "notification-outbox-" + orderID + "-" + runID
+}
+
+// NotificationOutboxWorkflow keeps retrying confirmations the order workflow
+// gave up on, with backoff of hours between rounds, for up to a week
+// The first round runs at once; each failed round waits the backoff, which
+// starts at the initial backoff and doubles up to the maximum. Each round is one execution of the channel's activity, which still applies
+// its catalog policy; the outbox only adds the long waits in between. The
+// delivery log makes sure a confirmation is never sent twice
+func NotificationOutboxWorkflow(ctx workflow.Context, request OutboxRequest) (OutboxResult, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("NotificationOutboxWorkflow started", "orderID", request.Order.ID, "channels", request.Channels)
+
+	initial, maximum, giveUpAfter := request.InitialBackoff, request.MaximumBackoff, request.GiveUpAfter
+	if initial <= 0 {
+		initial = DefaultOutboxInitialBackoff
+	}
+	if maximum <= 0 {
+		maximum = DefaultOutboxMaximumBackoff
+	}
+	if giveUpAfter <= 0 {
+		giveUpAfter = DefaultOutboxGiveUpAfter
+	}
+
+	// Outboxes started before the backoff fix waited the initial backoff before
+	// the first round and doubled it before the first wait between rounds;
+	// GetVersion keeps their timers on replay
+	backoffFixed := workflow.GetVersion(ctx, "outbox-backoff", workflow.DefaultVersion, 1) != workflow.DefaultVersion
+
+	now := workflow.Now(ctx)
+	notifications := make([]*OutboxNotification, len(request.Channels))
+	for i, channel := range request.Channels {
+		notifications[i] = &OutboxNotification{
+			Channel:       channel,
+			To:            request.Order.Email,
+			Status:        NotificationPending,
+			LastError:     request.LastError,
+			FirstFailedAt: now,
+			NextAttemptAt: now,
+		}
+		if !backoffFixed {
+			notifications[i].NextAttemptAt = now.Add(initial)
+		}
+	}
+
+	err := workflow.SetQueryHandler(ctx, PendingMessagesQuery, func() ([]OutboxNotification, error) {
+		pending := []OutboxNotification{}
+		for _, n := range notifications {
+			if n.Status == NotificationPending {
+				pending = append(pending, *n)
+			}
+		}
+		return pending, nil
+	})
+	if err != nil {
+		return OutboxResult{}, err
+	}
+
+	// Each channel retries on its own schedule
+	wg := workflow.NewWaitGroup(ctx)
+	for _, n := range notifications {
+		n := n
+		wg.Add(1)
+		workflow.Go(ctx, func(ctx workflow.Context) {
+			defer wg.Done()
+			deliverFromOutbox(ctx, request, n, initial, maximum, giveUpAfter, backoffFixed)
+		})
+	}
+	wg.Wait(ctx)
+
+	result := OutboxResult{OrderID: request.Order.ID}
+	for _, n := range notifications {
+		result.Notifications = append(result.Notifications, *n)
+	}
+	logger.Info("NotificationOutboxWorkflow completed", "orderID", request.Order.ID)
+	return result, nil
+}
+
+// deliverFromOutbox retries one notification until it is delivered, rejected or expired
+func deliverFromOutbox(ctx workflow.Context, request OutboxRequest, n *OutboxNotification, initial, maximum, giveUpAfter time.Duration, backoffFixed bool) {
+	logger := workflow.GetLogger(ctx)
+	deadline := n.FirstFailedAt.Add(giveUpAfter)
+	backoff := initial
+
+	for {
+		if wait := n.NextAttemptAt.Sub(workflow.Now(ctx)); wait > 0 {
+			if err := workflow.Sleep(ctx, wait); err != nil {
+				return
+			}
+		}
+
+		n.Rounds++
+		err := policies.ExecuteActivity(ctx, confirmationActivities[n.Channel], request.Order, request.PaymentID).Get(ctx, nil)
+		if err == nil {
+			n.Status = NotificationDelivered
+			n.DeliveredAt = workflow.Now(ctx)
+			n.NextAttemptAt = time.Time{}
+			logger.Info("Outbox delivered notification", "channel", n.Channel, "rounds", n.Rounds)
+			return
+		}
+		n.LastError = err.Error()
+
+		if errs.NotificationRejected.Is(err) {
+			n.Status = NotificationRejected
+			n.NextAttemptAt = time.Time{}
+			logger.Error("Notification rejected, not retrying", "channel", n.Channel, "error", err)
+			return
+		}
+
+		wait := min(backoff, maximum)
+		if !backoffFixed {
+			wait = min(backoff*2, maximum)
+		}
+		backoff = min(backoff*2, maximum)
+		n.NextAttemptAt = workflow.Now(ctx).Add(wait)
+		if n.NextAttemptAt.After(deadline) {
+			n.Status = NotificationExpired
+			n.NextAttemptAt = time.Time{}
+			logger.Error("Giving up on notification", "channel", n.Channel, "rounds", n.Rounds, "error", err)
+			return
+		}
+		logger.Warn("Outbox round failed", "channel", n.Channel, "rounds", n.Rounds, "nextAttemptAt", n.NextAttemptAt, "error", err)
+	}
+}
+
+// handOffToOutbox starts the order's outbox workflow for the failed channels
+// The outbox is abandoned rather than canceled when the order workflow closes,
+// so it keeps retrying after the order has completed
+// Earlier runs keyed the outbox on the order ID alone; GetVersion keeps
+// their replays on that ID
+func handOffToOutbox(ctx workflow.Context, order Order, runID, paymentID string, channels []string, lastErr error) (string, error) {
+	if workflow.GetVersion(ctx, "outbox-per-run", workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		runID = ""
+	}
+	workflowID := OutboxWorkflowID(order.ID, runID)
+	childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+		WorkflowID:        workflowID,
+		ParentClosePolicy: enumspb.PARENT_CLOSE_POLICY_ABANDON,
+	})
+	child := workflow.ExecuteChildWorkflow(childCtx, NotificationOutboxWorkflow, OutboxRequest{
+		Order:     order,
+		PaymentID: paymentID,
+		Channels:  channels,
+		LastError: lastErr.Error(),
+	})
+	// Only wait for the child to start; its result comes days later, if ever
+	if err := child.GetChildWorkflowExecution().Get(ctx, nil); err != nil {
+		return "", err
+	}
+	return workflowID, nil
+}
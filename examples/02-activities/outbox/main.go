@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	activities "temporal-go-examples/examples/02-activities"
+	"temporal-go-examples/shared"
+)
+
+func main() {
+	orderID := flag.String("order-id", "", "Order whose notification outbox to inspect")
+	runID := flag.String("run-id", "", "Run of the order workflow that handed off; empty for outboxes keyed on the order alone")
+	outboxID := flag.String("outbox-id", "", "Outbox workflow ID, as printed in the order result's outbox_id")
+	flag.Parse()
+	if *orderID == "" && *outboxID == "" {
+		log.Fatalln("-order-id or -outbox-id is required")
+	}
+
+	// Create Temporal client
+	c, err := shared.CreateTemporalClient()
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	workflowID := *outboxID
+	if workflowID == "" {
+		workflowID = activities.OutboxWorkflowID(*orderID, *runID)
+	}
+	value, err := c.QueryWorkflow(context.Background(), workflowID, "", activities.PendingMessagesQuery)
+	if err != nil {
+		log.Fatalln("Unable to query outbox", err)
+	}
+	var pending []activities.OutboxNotification
+	if err := value.Get(&pending); err != nil {
+		log.Fatalln("Unable to decode pending messages", err)
+	}
+
+	if len(pending) == 0 {
+		shared.LogInfo("Outbox %s has no pending messages", workflowID)
+		return
+	}
+	shared.LogInfo("Outbox %s has %d pending message(s):", workflowID, len(pending))
+	for _, n := range pending {
+		shared.LogInfo("   %-8s to %s: %d round(s), next attempt %s",
+			n.Channel, n.To, n.Rounds, n.NextAttemptAt.Local().Format("Jan 2 15:04:05"))
+		shared.LogInfo("      last error: %s", n.LastError)
+	}
+}
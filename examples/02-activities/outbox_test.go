@@ -0,0 +1,115 @@
+package activities
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+
+	"temporal-go-examples/shared/errs"
+)
+
+// outboxRequest is an email confirmation with backoff short enough to read
+// the schedule off the test clock: 5 minutes, doubling up to 20
+func outboxRequest(giveUpAfter time.Duration) OutboxRequest {
+	return OutboxRequest{
+		Order:          testOrder,
+		PaymentID:      paymentIDFor(testOrder.ID, testRunID),
+		Channels:       []string{"email"},
+		LastError:      "SMTP server unreachable",
+		InitialBackoff: 5 * time.Minute,
+		MaximumBackoff: 20 * time.Minute,
+		GiveUpAfter:    giveUpAfter,
+	}
+}
+
+// newOutboxEnv runs the outbox with the email activity failing the first
+// failures rounds, and records when each round ran
+func newOutboxEnv(failures int, roundErr error) (*testsuite.TestWorkflowEnvironment, *[]time.Duration) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(NotificationOutboxWorkflow)
+	env.RegisterActivity(SendConfirmationEmail)
+	start := env.Now()
+	rounds := &[]time.Duration{}
+	env.OnActivity(SendConfirmationEmail, mock.Anything, mock.Anything, mock.Anything).Return(func(ctx context.Context, order Order, paymentID string) error {
+		*rounds = append(*rounds, env.Now().Sub(start))
+		if len(*rounds) <= failures {
+			return roundErr
+		}
+		return nil
+	})
+	return env, rounds
+}
+
+// roundFailed fails a round without the activity's own retries, so each
+// round is a single call
+var roundFailed = temporal.NewNonRetryableApplicationError("SMTP server unreachable", "SMTPDown", nil)
+
+func TestOutboxBacksOffBetweenRounds(t *testing.T) {
+	env, rounds := newOutboxEnv(4, roundFailed)
+
+	env.ExecuteWorkflow(NotificationOutboxWorkflow, outboxRequest(DefaultOutboxGiveUpAfter))
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	// The first round runs at once, then the waits are 5, 10, 20 and 20 minutes
+	assert.Equal(t, []time.Duration{0, 5 * time.Minute, 15 * time.Minute, 35 * time.Minute, 55 * time.Minute}, *rounds)
+	var result OutboxResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Len(t, result.Notifications, 1)
+	assert.Equal(t, NotificationDelivered, result.Notifications[0].Status)
+	assert.Equal(t, 5, result.Notifications[0].Rounds)
+}
+
+func TestOutboxGivesUpAfterItsDeadline(t *testing.T) {
+	env, rounds := newOutboxEnv(100, roundFailed)
+
+	env.ExecuteWorkflow(NotificationOutboxWorkflow, outboxRequest(time.Hour))
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	// The round after 55 minutes would come at 75, past the hour
+	assert.Equal(t, []time.Duration{0, 5 * time.Minute, 15 * time.Minute, 35 * time.Minute, 55 * time.Minute}, *rounds)
+	var result OutboxResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	assert.Equal(t, NotificationExpired, result.Notifications[0].Status)
+	assert.Contains(t, result.Notifications[0].LastError, "SMTP server unreachable")
+}
+
+func TestOutboxStopsOnRejection(t *testing.T) {
+	env, rounds := newOutboxEnv(100, errs.NotificationRejected.New("no such mailbox"))
+
+	env.ExecuteWorkflow(NotificationOutboxWorkflow, outboxRequest(DefaultOutboxGiveUpAfter))
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	assert.Len(t, *rounds, 1)
+	var result OutboxResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	assert.Equal(t, NotificationRejected, result.Notifications[0].Status)
+}
+
+func TestOutboxIsKeyedOnTheOrderRun(t *testing.T) {
+	env := newOrderEnv()
+	env.RegisterWorkflow(NotificationOutboxWorkflow)
+	env.OnActivity(ProcessPayment, mock.Anything, mock.Anything).Return(paymentIDFor(testOrder.ID, testRunID), nil)
+	env.OnActivity(CommitReservation, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(SendConfirmationEmail, mock.Anything, mock.Anything, mock.Anything).Return(errs.ServiceUnavailable.New("SMTP server unreachable"))
+	env.OnWorkflow(NotificationOutboxWorkflow, mock.Anything, mock.Anything).Return(OutboxResult{}, nil)
+
+	env.ExecuteWorkflow(OrderProcessingWorkflow, testOrder)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var result OrderResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	// Another run of the same order, e.g. FulfillmentWorkflow's next child, gets its own outbox
+	assert.Equal(t, "notification-outbox-order-1-"+testRunID, result.OutboxID)
+	assert.Equal(t, OutboxWorkflowID(testOrder.ID, testRunID), result.OutboxID)
+}
@@ -0,0 +1,154 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/workflow"
+
+	"temporal-go-examples/shared/errs"
+	"temporal-go-examples/shared/fanout"
+	"temporal-go-examples/shared/policies"
+)
+
+// FraudThreshold is the score above which an order is rejected
+const FraudThreshold = 0.8
+
+// ParallelOptions tunes ParallelOrderProcessingWorkflow
+type ParallelOptions struct {
+	MaxConcurrency int `json:"max_concurrency"` // Checks running at once; 0 runs all of them together
+}
+
+// ParallelOrderResult is what ParallelOrderProcessingWorkflow returns
+type ParallelOrderResult struct {
+	OrderID         string          `json:"order_id"`
+	PaymentID       string          `json:"payment_id"`
+	FraudScore      float64         `json:"fraud_score"`
+	ReservationID   string          `json:"reservation_id"`
+	AddressVerified bool            `json:"address_verified"`
+	Checks          []fanout.Result `json:"checks"` // In start order, with the order they finished in
+}
+
+// ParallelOrderProcessingWorkflow is OrderProcessingWorkflow with the independent
+// checks - fraud scoring, inventory reservation and address verification - run
+// in parallel. A rejected fraud check cancels the other checks and releases any
+// stock already reserved; a failed address check only flags the order. Payment,
+// commit and email still run in sequence afterwards
+func ParallelOrderProcessingWorkflow(ctx workflow.Context, order Order, options ParallelOptions) (ParallelOrderResult, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("ParallelOrderProcessingWorkflow started", "orderID", order.ID, "maxConcurrency", options.MaxConcurrency)
+	result := ParallelOrderResult{OrderID: order.ID}
+
+	// Step 1: Validate the order
+	if err := policies.ExecuteActivity(ctx, ValidateOrder, order).Get(ctx, nil); err != nil {
+		return result, fmt.Errorf("order validation failed: %w", err)
+	}
+
+	// Step 2: Fan out the independent checks, fan in their results
+	// A canceled reservation can still complete on the worker after the cancel,
+	// so it waits for the activity to settle before the release below runs.
+	// Runs started before that keep returning as soon as the cancel is requested
+	settleReserve := workflow.GetVersion(ctx, "settle-reserve-before-release", workflow.DefaultVersion, 1) != workflow.DefaultVersion
+	checks, err := fanout.Run(ctx, options.MaxConcurrency,
+		fanout.Task{
+			Name: "ScoreFraud",
+			Run: func(ctx workflow.Context) error {
+				return policies.ExecuteActivity(ctx, ScoreFraud, order).Get(ctx, &result.FraudScore)
+			},
+		},
+		fanout.Task{
+			Name: "ReserveInventory",
+			Run: func(ctx workflow.Context) error {
+				if !settleReserve {
+					return policies.ExecuteActivity(ctx, ReserveInventory, order).Get(ctx, &result.ReservationID)
+				}
+				reserveOptions := policies.ActivityOptions(ctx, ReserveInventory)
+				reserveOptions.WaitForCancellation = true
+				ctx = workflow.WithActivityOptions(ctx, reserveOptions)
+				return workflow.ExecuteActivity(ctx, ReserveInventory, order).Get(ctx, &result.ReservationID)
+			},
+		},
+		fanout.Task{
+			Name:     "VerifyAddress",
+			Optional: true,
+			Run: func(ctx workflow.Context) error {
+				err := policies.ExecuteActivity(ctx, VerifyAddress, order).Get(ctx, nil)
+				result.AddressVerified = err == nil
+				return err
+			},
+		},
+	)
+	result.Checks = checks
+	for _, check := range checks {
+		logger.Info("Check finished", "check", check.Name, "status", check.Status, "finished", check.Finished)
+	}
+	if err != nil {
+		logger.Error("Order checks failed", "error", err)
+		// The reservation may have gone through even if it was canceled, so
+		// release by its deterministic ID; the reserve activity has settled by now
+		releaseReservation(ctx, reservationID(order.ID, workflow.GetInfo(ctx).WorkflowExecution.RunID))
+		return result, fmt.Errorf("order checks failed: %w", err)
+	}
+	if !result.AddressVerified {
+		logger.Warn("Address could not be verified, flagging order for manual review", "orderID", order.ID)
+	}
+
+	// Step 3: Process payment
+	if err := policies.ExecuteActivity(ctx, ProcessPayment, order).Get(ctx, &result.PaymentID); err != nil {
+		releaseReservation(ctx, result.ReservationID)
+		return result, fmt.Errorf("payment processing failed: %w", err)
+	}
+	if err := policies.ExecuteActivity(ctx, CommitReservation, result.ReservationID).Get(ctx, nil); err != nil {
+		return result, fmt.Errorf("order %s paid (%s) but reservation %s could not be committed: %w", order.ID, result.PaymentID, result.ReservationID, err)
+	}
+
+	// Step 4: Send confirmation email (best effort, as in OrderProcessingWorkflow)
+	if err := policies.ExecuteActivity(ctx, SendConfirmationEmail, order, result.PaymentID).Get(ctx, nil); err != nil {
+		logger.Warn("Order processed but confirmation email failed", "error", err)
+	}
+
+	logger.Info("ParallelOrderProcessingWorkflow completed", "orderID", order.ID, "paymentID", result.PaymentID)
+	return result, nil
+}
+
+// ScoreFraud rates how likely an order is to be fraudulent, from 0 to 1
+// Orders scoring above FraudThreshold are rejected with a non-retryable error
+func ScoreFraud(ctx context.Context, order Order) (float64, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Scoring fraud risk", "orderID", order.ID, "amount", order.Amount)
+
+	// Simulate the scoring model
+	time.Sleep(time.Millisecond * 300)
+
+	// Large orders look riskier
+	score := order.Amount/10000 + rand.Float64()*0.1
+	if score > 1 {
+		score = 1
+	}
+	if score > FraudThreshold {
+		return score, errs.OrderRejected.New(fmt.Sprintf("fraud score %.2f is above %.2f", score, FraudThreshold), order.ID, score)
+	}
+
+	logger.Info("Fraud score computed", "orderID", order.ID, "score", score)
+	return score, nil
+}
+
+// VerifyAddress checks the shipping address with the address service
+func VerifyAddress(ctx context.Context, order Order) error {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Verifying address", "orderID", order.ID)
+
+	// Simulate the address service
+	time.Sleep(time.Millisecond * 250)
+
+	if strings.TrimSpace(order.ShippingAddress) == "" {
+		return errs.InvalidOrder.New("shipping address is missing", order.ID)
+	}
+
+	logger.Info("Address verified", "orderID", order.ID)
+	return nil
+}
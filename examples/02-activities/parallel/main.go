@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	activities "temporal-go-examples/examples/02-activities"
+	"temporal-go-examples/shared"
+)
+
+func main() {
+	concurrency := flag.Int("concurrency", 0, "Checks running at once (0 = all)")
+	amount := flag.Float64("amount", 99.99, "Order amount; above 8000 the fraud check rejects the order")
+	address := flag.String("address", "1 Main Street, Springfield", "Shipping address; empty fails address verification")
+	flag.Parse()
+
+	// Create Temporal client
+	c, err := shared.CreateTemporalClient()
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	order := activities.Order{
+		ID:              "order-" + shared.RandomID(),
+		UserID:          "user-67890",
+		Email:           "customer@example.com",
+		Amount:          *amount,
+		Product:         "Premium Subscription",
+		ShippingAddress: *address,
+	}
+
+	shared.LogInfo("Starting ParallelOrderProcessingWorkflow for %s ($%.2f, concurrency %d)...", order.ID, order.Amount, *concurrency)
+	workflowRun, err := shared.ExecuteWorkflow(c, activities.ParallelOrderProcessingWorkflow, order,
+		activities.ParallelOptions{MaxConcurrency: *concurrency})
+	if err != nil {
+		log.Fatalln("Unable to execute workflow", err)
+	}
+
+	var result activities.ParallelOrderResult
+	if err := workflowRun.Get(context.Background(), &result); err != nil {
+		shared.LogError("Order failed: %v", err)
+		shared.LogInfo("See the check statuses in the workflow's history at http://localhost:8080")
+		return
+	}
+
+	for _, check := range result.Checks {
+		shared.LogInfo("   %-16s %-9s finished #%d %s", check.Name, check.Status, check.Finished, check.Error)
+	}
+	shared.LogInfo("✅ Order %s paid (%s), fraud score %.2f, reservation %s, address verified: %v",
+		result.OrderID, result.PaymentID, result.FraudScore, result.ReservationID, result.AddressVerified)
+}
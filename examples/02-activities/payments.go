@@ -0,0 +1,390 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/activity"
+
+	"temporal-go-examples/shared"
+	"temporal-go-examples/shared/errs"
+	"temporal-go-examples/shared/filestore"
+)
+
+// Webhook payment statuses
+const (
+	PaymentSucceeded = "succeeded"
+	PaymentFailed    = "failed"
+)
+
+// PaymentVoided is the status VoidPayment leaves behind; it is never a webhook status
+const PaymentVoided = "voided"
+
+// DefaultWebhookAddr is where the webhook receiver listens by default
+const DefaultWebhookAddr = ":8090"
+
+// PendingPayment is a payment submitted to the gateway and waiting for its webhook
+// The task token (or the IDs, for CompleteActivityByID) lets the webhook receiver
+// complete the ProcessPayment activity from another process
+type PendingPayment struct {
+	PaymentID   string    `json:"payment_id"`
+	OrderID     string    `json:"order_id"`
+	Amount      float64   `json:"amount"`
+	TaskToken   []byte    `json:"task_token"`
+	WorkflowID  string    `json:"workflow_id"`
+	RunID       string    `json:"run_id"`
+	ActivityID  string    `json:"activity_id"`
+	Attempt     int32     `json:"attempt"`
+	SubmittedAt time.Time `json:"submitted_at"`
+	// Set when the webhook arrived while no attempt was waiting for it; the
+	// next attempt returns this outcome instead of submitting again. A voided
+	// payment keeps its entry as a tombstone that refuses submits and webhooks
+	Status string `json:"status,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// PaymentStore persists pending payments in a JSON file shared by the worker
+// and the webhook receiver
+type PaymentStore struct {
+	file filestore.Map[PendingPayment]
+}
+
+// NewPaymentStore creates a store backed by the file at path
+func NewPaymentStore(path string) *PaymentStore {
+	return &PaymentStore{file: filestore.Map[PendingPayment]{Path: path}}
+}
+
+// PaymentStoreFromEnv uses PAYMENT_TOKEN_STORE_FILE, or a file in the temp directory
+func PaymentStoreFromEnv() *PaymentStore {
+	path := os.Getenv("PAYMENT_TOKEN_STORE_FILE")
+	if path == "" {
+		path = filepath.Join(os.TempDir(), "temporal-pending-payments.json")
+	}
+	return NewPaymentStore(path)
+}
+
+// Save records a pending payment, replacing an earlier attempt's token
+func (s *PaymentStore) Save(p PendingPayment) error {
+	_, err := s.file.Update(p.PaymentID, func(stored *PendingPayment) { *stored = p })
+	return err
+}
+
+// Get returns a pending payment
+func (s *PaymentStore) Get(paymentID string) (PendingPayment, bool, error) {
+	payments, err := s.file.Read()
+	if err != nil {
+		return PendingPayment{}, false, err
+	}
+	p, ok := payments[paymentID]
+	return p, ok, nil
+}
+
+// Submit records a new pending payment unless the payment was voided
+// It reports false, and stores nothing, for a voided payment
+func (s *PaymentStore) Submit(p PendingPayment) (bool, error) {
+	submitted := false
+	_, err := s.file.Update(p.PaymentID, func(stored *PendingPayment) {
+		if stored.Status == PaymentVoided {
+			return
+		}
+		*stored = p
+		submitted = true
+	})
+	return submitted, err
+}
+
+// Void marks a payment voided, recording a tombstone if it isn't stored yet
+// It reports true, and changes nothing, if the payment was already captured
+func (s *PaymentStore) Void(paymentID string) (bool, error) {
+	captured := false
+	_, err := s.file.Update(paymentID, func(p *PendingPayment) {
+		if p.Status == PaymentSucceeded {
+			captured = true
+			return
+		}
+		p.PaymentID = paymentID
+		p.Status = PaymentVoided
+		p.TaskToken = nil
+	})
+	return captured, err
+}
+
+// Update applies fn to a stored payment atomically
+// Unknown payments are left alone and reported with false
+func (s *PaymentStore) Update(paymentID string, fn func(*PendingPayment)) (PendingPayment, bool, error) {
+	return s.file.UpdateExisting(paymentID, fn)
+}
+
+// Remove forgets a payment once its activity is completed
+func (s *PaymentStore) Remove(paymentID string) error {
+	_, _, err := s.file.Delete(paymentID)
+	return err
+}
+
+var (
+	// Payments is where ProcessPayment keeps task tokens
+	Payments = PaymentStoreFromEnv()
+
+	// PaymentWebhookURL is where the simulated gateway posts its webhooks
+	// Empty disables the callbacks, so payments only complete by timing out
+	PaymentWebhookURL = webhookURLFromEnv()
+
+	// GatewayDelay is the simulated gateway's minimum time to confirm a payment;
+	// each webhook arrives between one and three times this after submission
+	GatewayDelay = time.Second
+)
+
+// webhookURLFromEnv reads PAYMENT_WEBHOOK_URL, defaulting to the local receiver
+func webhookURLFromEnv() string {
+	if url, ok := os.LookupEnv("PAYMENT_WEBHOOK_URL"); ok {
+		return url
+	}
+	return "http://localhost" + DefaultWebhookAddr + "/webhooks/payments"
+}
+
+// PaymentWebhook is the body the gateway posts when a payment settles
+type PaymentWebhook struct {
+	PaymentID string `json:"payment_id"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// simulateGateway plays the gateway: a few seconds after a payment is submitted
+// it posts a webhook saying whether the payment went through
+func simulateGateway(paymentID string) {
+	if PaymentWebhookURL == "" {
+		return
+	}
+	time.Sleep(GatewayDelay + time.Duration(rand.Int63n(int64(GatewayDelay)*2+1)))
+
+	webhook := PaymentWebhook{PaymentID: paymentID, Status: PaymentSucceeded}
+	// Simulate declined cards (5% chance)
+	if rand.Float32() < 0.05 {
+		webhook.Status = PaymentFailed
+		webhook.Reason = "card declined"
+	}
+	body, _ := json.Marshal(webhook)
+	resp, err := http.Post(PaymentWebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		shared.LogError("Gateway could not deliver webhook for %s: %v", paymentID, err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// ActivityCompleter completes activities from outside the worker; client.Client implements it
+type ActivityCompleter interface {
+	CompleteActivity(ctx context.Context, taskToken []byte, result interface{}, err error) error
+	CompleteActivityByID(ctx context.Context, namespace, workflowID, runID, activityID string, result interface{}, err error) error
+}
+
+// WebhookReceiver turns payment gateway webhooks into activity completions
+// With ByID it uses CompleteActivityByID instead of the stored task token
+type WebhookReceiver struct {
+	Completer ActivityCompleter
+	Store     *PaymentStore
+	ByID      bool
+}
+
+// ServeHTTP handles POST /webhooks/payments
+func (r *WebhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var webhook PaymentWebhook
+	if err := json.NewDecoder(req.Body).Decode(&webhook); err != nil {
+		http.Error(w, "invalid webhook body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if webhook.Status != PaymentSucceeded && webhook.Status != PaymentFailed {
+		http.Error(w, fmt.Sprintf("unknown status %q", webhook.Status), http.StatusBadRequest)
+		return
+	}
+
+	pending, ok, err := r.Store.Get(webhook.PaymentID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "unknown payment "+webhook.PaymentID, http.StatusNotFound)
+		return
+	}
+	if pending.Status == PaymentVoided {
+		// The order gave up on this payment; completing it would charge a canceled order
+		http.Error(w, "payment "+pending.PaymentID+" was voided", http.StatusConflict)
+		return
+	}
+
+	// The activity's result is the payment ID, or a non-retryable decline
+	var result interface{}
+	confirmedID, paymentErr := paymentOutcome(pending.PaymentID, webhook.Status, webhook.Reason)
+	if paymentErr == nil {
+		result = confirmedID
+	}
+
+	if r.ByID {
+		err = r.Completer.CompleteActivityByID(req.Context(), shared.Namespace, pending.WorkflowID, pending.RunID, pending.ActivityID, result, paymentErr)
+	} else {
+		err = r.Completer.CompleteActivity(req.Context(), pending.TaskToken, result, paymentErr)
+	}
+
+	var notFound *serviceerror.NotFound
+	switch {
+	case errors.As(err, &notFound):
+		// No attempt is waiting: the last one timed out and its retry hasn't
+		// started, or the workflow gave up. Forgetting the payment now would
+		// make the retry submit it again and charge twice, so keep it with its
+		// outcome: a retry returns that outcome, and the workflow can still void it
+		_, ok, err := r.Store.Update(pending.PaymentID, func(p *PendingPayment) {
+			if p.Status != PaymentVoided {
+				p.Status = webhook.Status
+				p.Reason = webhook.Reason
+			}
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.Error(w, "unknown payment "+webhook.PaymentID, http.StatusNotFound)
+			return
+		}
+		shared.LogInfo("Payment %s %s with no attempt waiting; kept for the next attempt (order %s)", pending.PaymentID, webhook.Status, pending.OrderID)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"payment_id": pending.PaymentID, "status": webhook.Status})
+		return
+	case err != nil:
+		http.Error(w, "unable to complete payment activity: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if err := r.Store.Remove(pending.PaymentID); err != nil {
+		shared.LogError("Unable to remove completed payment %s: %v", pending.PaymentID, err)
+	}
+	shared.LogInfo("Payment %s %s (order %s)", pending.PaymentID, webhook.Status, pending.OrderID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"payment_id": pending.PaymentID, "status": webhook.Status})
+}
+
+// paymentOutcome is the ProcessPayment result for a settled payment: the
+// payment ID, or a non-retryable decline or void
+func paymentOutcome(paymentID, status, reason string) (string, error) {
+	switch status {
+	case PaymentFailed:
+		return "", errs.PaymentDeclined.New(fmt.Sprintf("payment %s declined: %s", paymentID, reason), paymentID)
+	case PaymentVoided:
+		return "", errs.PaymentVoided.New(fmt.Sprintf("payment %s was voided", paymentID), paymentID)
+	}
+	return paymentID, nil
+}
+
+// paymentIDFor is the same for every attempt of one workflow run, so a retried
+// attempt reuses it and the workflow can void a payment whose result it never saw
+func paymentIDFor(orderID, runID string) string {
+	return fmt.Sprintf("pay_%s_%s", orderID, runID)
+}
+
+// resumePayment hands a payment an earlier attempt already submitted over to
+// this attempt: the gateway is not called again, and the webhook completes this
+// attempt because its task token replaces the old one. If the webhook already
+// came while no attempt was waiting, the payment's Status holds its outcome
+func resumePayment(ctx context.Context, order Order) (PendingPayment, bool, error) {
+	info := activity.GetInfo(ctx)
+	paymentID := paymentIDFor(order.ID, info.WorkflowExecution.RunID)
+	// One atomic update, so a webhook settling the payment meanwhile isn't overwritten
+	pending, ok, err := Payments.Update(paymentID, func(p *PendingPayment) {
+		if p.Status != "" {
+			return
+		}
+		p.TaskToken = info.TaskToken
+		p.ActivityID = info.ActivityID
+		p.Attempt = info.Attempt
+	})
+	if err != nil {
+		return PendingPayment{}, false, errs.DependencyFailure.Wrap(err, "unable to save payment task token")
+	}
+	return pending, ok, nil
+}
+
+// submitPayment records the activity's task token and hands the payment to the gateway
+// The payment is keyed by its ID, so a retried attempt finds it (see resumePayment).
+// A payment voided before this attempt got here is never handed to the gateway
+func submitPayment(ctx context.Context, order Order) (string, error) {
+	info := activity.GetInfo(ctx)
+	paymentID := paymentIDFor(order.ID, info.WorkflowExecution.RunID)
+	submitted, err := Payments.Submit(PendingPayment{
+		PaymentID:   paymentID,
+		OrderID:     order.ID,
+		Amount:      order.Amount,
+		TaskToken:   info.TaskToken,
+		WorkflowID:  info.WorkflowExecution.ID,
+		RunID:       info.WorkflowExecution.RunID,
+		ActivityID:  info.ActivityID,
+		Attempt:     info.Attempt,
+		SubmittedAt: time.Now(),
+	})
+	if err != nil {
+		return "", errs.DependencyFailure.Wrap(err, "unable to save payment task token")
+	}
+	if !submitted {
+		return "", errs.PaymentVoided.New(fmt.Sprintf("payment %s was voided", paymentID), paymentID)
+	}
+	go simulateGateway(paymentID)
+	return paymentID, nil
+}
+
+// VoidPayment cancels a payment the gateway has not settled yet
+// It leaves a voided tombstone rather than deleting the payment: a submit still
+// in flight, or retried later, and a webhook arriving afterwards all find it
+// and refuse to charge. Voiding an unknown payment records the tombstone ahead
+// of the submit; voiding twice does nothing. A payment the gateway already
+// captured can't be voided and needs a refund instead
+func VoidPayment(ctx context.Context, paymentID string) error {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Voiding payment", "paymentID", paymentID)
+
+	// Simulate the gateway call
+	time.Sleep(time.Millisecond * 150)
+
+	captured, err := Payments.Void(paymentID)
+	if err != nil {
+		return errs.DependencyFailure.Wrap(err, "unable to record voided payment")
+	}
+	if captured {
+		return errs.ManualReviewRequired.New(fmt.Sprintf("payment %s was captured before it could be voided; refund it", paymentID), paymentID)
+	}
+
+	logger.Info("Payment voided", "paymentID", paymentID)
+	return nil
+}
+
+// RefundPayment returns a captured payment to the customer and returns the refund ID
+func RefundPayment(ctx context.Context, paymentID string, amount float64) (string, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Refunding payment", "paymentID", paymentID, "amount", amount)
+
+	// Simulate the gateway call
+	time.Sleep(time.Millisecond * 200)
+
+	// Simulate gateway failures (5% chance)
+	if rand.Float32() < 0.05 {
+		return "", errs.ServiceUnavailable.New("payment gateway temporarily unavailable")
+	}
+
+	// The refund ID is derived from the payment, so a retried refund is not paid twice
+	refundID := "rf_" + paymentID
+	logger.Info("Payment refunded", "paymentID", paymentID, "refundID", refundID)
+	return refundID, nil
+}
@@ -0,0 +1,295 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+
+	"temporal-go-examples/shared/errs"
+)
+
+// completion is one call a fakeCompleter received
+type completion struct {
+	taskToken []byte
+	result    interface{}
+	err       error
+}
+
+// fakeCompleter stands in for the Temporal client behind the webhook receiver
+type fakeCompleter struct {
+	mu          sync.Mutex
+	completions []completion
+	fail        error // Returned from every call when set
+}
+
+func (f *fakeCompleter) CompleteActivity(ctx context.Context, taskToken []byte, result interface{}, err error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.completions = append(f.completions, completion{taskToken: taskToken, result: result, err: err})
+	return f.fail
+}
+
+func (f *fakeCompleter) CompleteActivityByID(ctx context.Context, namespace, workflowID, runID, activityID string, result interface{}, err error) error {
+	return f.CompleteActivity(ctx, nil, result, err)
+}
+
+func (f *fakeCompleter) calls() []completion {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]completion(nil), f.completions...)
+}
+
+// newWebhookServer serves a WebhookReceiver backed by a store in a temp directory
+func newWebhookServer(t *testing.T, completer *fakeCompleter) (*httptest.Server, *PaymentStore) {
+	store := NewPaymentStore(filepath.Join(t.TempDir(), "pending-payments.json"))
+	server := httptest.NewServer(&WebhookReceiver{Completer: completer, Store: store})
+	t.Cleanup(server.Close)
+	return server, store
+}
+
+func postWebhook(t *testing.T, url string, webhook PaymentWebhook) *http.Response {
+	body, err := json.Marshal(webhook)
+	require.NoError(t, err)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestWebhookCompletesPendingPayment(t *testing.T) {
+	completer := &fakeCompleter{}
+	server, store := newWebhookServer(t, completer)
+	require.NoError(t, store.Save(PendingPayment{PaymentID: "pay_1", OrderID: "order-1", TaskToken: []byte("token-1")}))
+
+	resp := postWebhook(t, server.URL, PaymentWebhook{PaymentID: "pay_1", Status: PaymentSucceeded})
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	calls := completer.calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, []byte("token-1"), calls[0].taskToken)
+	assert.Equal(t, "pay_1", calls[0].result)
+	assert.NoError(t, calls[0].err)
+	_, ok, err := store.Get("pay_1")
+	require.NoError(t, err)
+	assert.False(t, ok, "completed payment should be removed from the store")
+}
+
+func TestWebhookFailsDeclinedPayment(t *testing.T) {
+	completer := &fakeCompleter{}
+	server, store := newWebhookServer(t, completer)
+	require.NoError(t, store.Save(PendingPayment{PaymentID: "pay_1", OrderID: "order-1", TaskToken: []byte("token-1")}))
+
+	resp := postWebhook(t, server.URL, PaymentWebhook{PaymentID: "pay_1", Status: PaymentFailed, Reason: "card declined"})
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	calls := completer.calls()
+	require.Len(t, calls, 1)
+	assert.Nil(t, calls[0].result)
+	assert.True(t, errs.PaymentDeclined.Is(calls[0].err))
+}
+
+func TestWebhookAfterActivityTimedOut(t *testing.T) {
+	// The attempt timed out before the gateway answered and its retry hasn't
+	// started, so Temporal no longer knows the task token
+	completer := &fakeCompleter{fail: serviceerror.NewNotFound("activity already timed out")}
+	server, store := newWebhookServer(t, completer)
+	require.NoError(t, store.Save(PendingPayment{PaymentID: "pay_1", OrderID: "order-1", TaskToken: []byte("token-1")}))
+
+	resp := postWebhook(t, server.URL, PaymentWebhook{PaymentID: "pay_1", Status: PaymentFailed, Reason: "card declined"})
+
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	pending, ok, err := store.Get("pay_1")
+	require.NoError(t, err)
+	require.True(t, ok, "the payment must be kept, or the retry would submit it again")
+	assert.Equal(t, PaymentFailed, pending.Status)
+	assert.Equal(t, "card declined", pending.Reason)
+	assert.Equal(t, []byte("token-1"), pending.TaskToken)
+}
+
+func TestWebhookRejectsUnknownPayment(t *testing.T) {
+	completer := &fakeCompleter{}
+	server, _ := newWebhookServer(t, completer)
+
+	resp := postWebhook(t, server.URL, PaymentWebhook{PaymentID: "pay_unknown", Status: PaymentSucceeded})
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Empty(t, completer.calls())
+}
+
+func TestRetriedPaymentIsSubmittedOnce(t *testing.T) {
+	// The simulated gateway posts to a real webhook receiver; count every post
+	completer := &fakeCompleter{}
+	store := NewPaymentStore(filepath.Join(t.TempDir(), "pending-payments.json"))
+	receiver := &WebhookReceiver{Completer: completer, Store: store}
+	var mu sync.Mutex
+	webhooks := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		webhooks++
+		mu.Unlock()
+		receiver.ServeHTTP(w, req)
+	}))
+	t.Cleanup(server.Close)
+	received := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return webhooks
+	}
+
+	storeBefore, urlBefore, delayBefore := Payments, PaymentWebhookURL, GatewayDelay
+	t.Cleanup(func() { Payments, PaymentWebhookURL, GatewayDelay = storeBefore, urlBefore, delayBefore })
+	Payments, PaymentWebhookURL, GatewayDelay = store, server.URL, 200*time.Millisecond
+
+	suite := &testsuite.WorkflowTestSuite{}
+	order := Order{ID: "order-1", Email: "test@example.com", Amount: 10, Merchant: "merchant-1"}
+	process := func() error {
+		env := suite.NewTestActivityEnvironment()
+		env.RegisterActivity(ProcessPayment)
+		_, err := env.ExecuteActivity(ProcessPayment, order)
+		return err
+	}
+
+	// The first attempt submits the payment; the gateway may reject the request
+	// outright, which Temporal would simply retry
+	err := process()
+	for errs.DependencyFailure.Is(err) {
+		err = process()
+	}
+	require.ErrorIs(t, err, activity.ErrResultPending)
+	// A retry of the same run, before the webhook arrives, finds the payment pending
+	require.ErrorIs(t, process(), activity.ErrResultPending)
+
+	require.Eventually(t, func() bool { return len(completer.calls()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(4 * GatewayDelay)
+	assert.Equal(t, 1, received(), "the gateway should see one submission and send one webhook")
+	assert.Len(t, completer.calls(), 1)
+}
+
+func TestRetryAfterLateWebhookReturnsItsOutcome(t *testing.T) {
+	completer := &fakeCompleter{fail: serviceerror.NewNotFound("activity already timed out")}
+	server, store := newWebhookServer(t, completer)
+	storeBefore, urlBefore := Payments, PaymentWebhookURL
+	t.Cleanup(func() { Payments, PaymentWebhookURL = storeBefore, urlBefore })
+	// No simulated gateway: the test posts the webhook itself
+	Payments, PaymentWebhookURL = store, ""
+
+	suite := &testsuite.WorkflowTestSuite{}
+	order := Order{ID: "order-1", Email: "test@example.com", Amount: 10, Merchant: "merchant-1"}
+	process := func() (string, error) {
+		env := suite.NewTestActivityEnvironment()
+		env.RegisterActivity(ProcessPayment)
+		value, err := env.ExecuteActivity(ProcessPayment, order)
+		if err != nil {
+			return "", err
+		}
+		var paymentID string
+		require.NoError(t, value.Get(&paymentID))
+		return paymentID, nil
+	}
+
+	_, err := process()
+	for errs.DependencyFailure.Is(err) {
+		_, err = process()
+	}
+	require.ErrorIs(t, err, activity.ErrResultPending)
+	paymentID := paymentIDFor(order.ID, "default-test-run-id")
+	_, ok, err := store.Get(paymentID)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// The webhook arrives between the timed out attempt and its retry
+	resp := postWebhook(t, server.URL, PaymentWebhook{PaymentID: paymentID, Status: PaymentSucceeded})
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	// The retry returns the payment at once instead of submitting it again
+	result, err := process()
+	require.NoError(t, err)
+	assert.Equal(t, paymentID, result)
+	pending, ok, err := store.Get(paymentID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, PaymentSucceeded, pending.Status)
+}
+
+func TestWebhookRefusesVoidedPayment(t *testing.T) {
+	completer := &fakeCompleter{}
+	server, store := newWebhookServer(t, completer)
+	require.NoError(t, store.Save(PendingPayment{PaymentID: "pay_1", OrderID: "order-1", TaskToken: []byte("token-1")}))
+
+	captured, err := store.Void("pay_1")
+	require.NoError(t, err)
+	require.False(t, captured)
+	resp := postWebhook(t, server.URL, PaymentWebhook{PaymentID: "pay_1", Status: PaymentSucceeded})
+
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Empty(t, completer.calls(), "a voided payment must not complete the activity")
+	pending, ok, err := store.Get("pay_1")
+	require.NoError(t, err)
+	require.True(t, ok, "the void is kept as a tombstone")
+	assert.Equal(t, PaymentVoided, pending.Status)
+	assert.Nil(t, pending.TaskToken)
+}
+
+func TestPaymentVoidedBeforeSubmitIsNeverSubmitted(t *testing.T) {
+	store := NewPaymentStore(filepath.Join(t.TempDir(), "pending-payments.json"))
+	storeBefore, urlBefore := Payments, PaymentWebhookURL
+	t.Cleanup(func() { Payments, PaymentWebhookURL = storeBefore, urlBefore })
+	Payments, PaymentWebhookURL = store, ""
+
+	suite := &testsuite.WorkflowTestSuite{}
+	order := Order{ID: "order-1", Email: "test@example.com", Amount: 10, Merchant: "merchant-1"}
+	paymentID := paymentIDFor(order.ID, "default-test-run-id")
+
+	// The order was canceled while its ProcessPayment attempt was still on its way
+	voidEnv := suite.NewTestActivityEnvironment()
+	voidEnv.RegisterActivity(VoidPayment)
+	_, err := voidEnv.ExecuteActivity(VoidPayment, paymentID)
+	require.NoError(t, err)
+
+	process := func() error {
+		env := suite.NewTestActivityEnvironment()
+		env.RegisterActivity(ProcessPayment)
+		_, err := env.ExecuteActivity(ProcessPayment, order)
+		return err
+	}
+	err = process()
+	for errs.DependencyFailure.Is(err) {
+		err = process()
+	}
+	assert.True(t, errs.PaymentVoided.Is(err), "got %v", err)
+	pending, ok, err := store.Get(paymentID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, PaymentVoided, pending.Status)
+	assert.Nil(t, pending.TaskToken, "the gateway never got the payment")
+}
+
+func TestVoidingACapturedPaymentNeedsARefund(t *testing.T) {
+	store := NewPaymentStore(filepath.Join(t.TempDir(), "pending-payments.json"))
+	storeBefore := Payments
+	t.Cleanup(func() { Payments = storeBefore })
+	Payments = store
+	require.NoError(t, store.Save(PendingPayment{PaymentID: "pay_1", OrderID: "order-1", Status: PaymentSucceeded}))
+
+	env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+	env.RegisterActivity(VoidPayment)
+	_, err := env.ExecuteActivity(VoidPayment, "pay_1")
+
+	assert.True(t, errs.ManualReviewRequired.Is(err), "got %v", err)
+	pending, ok, err := store.Get("pay_1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, PaymentSucceeded, pending.Status, "the capture stays on record")
+}
@@ -0,0 +1,145 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"go.temporal.io/sdk/activity"
+
+	"temporal-go-examples/shared/errs"
+)
+
+// Price line kinds
+const (
+	LineItemKind = "item"
+	DiscountKind = "discount"
+	ShippingKind = "shipping"
+	TaxKind      = "tax"
+)
+
+// TaxRate is the sales tax applied to discounted goods (shipping is not taxed)
+const TaxRate = 0.0825
+
+// LineItem is one product on an order
+type LineItem struct {
+	SKU       string  `json:"sku"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"`
+}
+
+// discountCodes maps codes to a percentage off (below 1) or a fixed amount off
+var discountCodes = map[string]float64{
+	"SAVE10":   0.10,
+	"WELCOME5": 5.00,
+}
+
+// shippingMethods maps shipping methods to their price
+var shippingMethods = map[string]float64{
+	"standard": 5.99,
+	"express":  14.99,
+}
+
+// FreeShippingOver is the discounted subtotal from which standard shipping is free
+const FreeShippingOver = 100.0
+
+// PriceLine is one line of a price breakdown
+type PriceLine struct {
+	Kind        string  `json:"kind"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"` // Negative for discounts
+}
+
+// PriceBreakdown is the computed price of an order
+type PriceBreakdown struct {
+	Lines    []PriceLine `json:"lines"`
+	Subtotal float64     `json:"subtotal"`
+	Discount float64     `json:"discount"`
+	Shipping float64     `json:"shipping"`
+	Tax      float64     `json:"tax"`
+	Total    float64     `json:"total"`
+}
+
+// roundCents rounds an amount to whole cents
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// lineItems returns the order's items; a single-product order is one item
+// costing the whole amount
+func (o Order) lineItems() []LineItem {
+	if len(o.Items) > 0 {
+		return o.Items
+	}
+	return []LineItem{{SKU: o.Product, Quantity: 1, UnitPrice: o.Amount}}
+}
+
+// Price computes an order's line items, discount, shipping and tax
+// Clients use it to quote a total before placing the order
+func Price(order Order) (PriceBreakdown, error) {
+	var b PriceBreakdown
+	for _, item := range order.Items {
+		amount := roundCents(float64(item.Quantity) * item.UnitPrice)
+		b.Lines = append(b.Lines, PriceLine{
+			Kind:        LineItemKind,
+			Description: fmt.Sprintf("%d x %s @ %.2f", item.Quantity, item.SKU, item.UnitPrice),
+			Amount:      amount,
+		})
+		b.Subtotal += amount
+	}
+	b.Subtotal = roundCents(b.Subtotal)
+
+	if code := strings.ToUpper(order.DiscountCode); code != "" {
+		off, ok := discountCodes[code]
+		if !ok {
+			return PriceBreakdown{}, errs.InvalidOrder.New(fmt.Sprintf("unknown discount code %q", order.DiscountCode), order.ID)
+		}
+		if off < 1 {
+			off = off * b.Subtotal
+		}
+		b.Discount = roundCents(math.Min(off, b.Subtotal))
+		b.Lines = append(b.Lines, PriceLine{Kind: DiscountKind, Description: code, Amount: -b.Discount})
+	}
+	goods := b.Subtotal - b.Discount
+
+	method := order.ShippingMethod
+	if method == "" {
+		method = "standard"
+	}
+	shipping, ok := shippingMethods[method]
+	if !ok {
+		return PriceBreakdown{}, errs.InvalidOrder.New(fmt.Sprintf("unknown shipping method %q", order.ShippingMethod), order.ID)
+	}
+	if method == "standard" && goods >= FreeShippingOver {
+		shipping = 0
+	}
+	b.Shipping = shipping
+	b.Lines = append(b.Lines, PriceLine{Kind: ShippingKind, Description: method, Amount: b.Shipping})
+
+	b.Tax = roundCents(goods * TaxRate)
+	b.Lines = append(b.Lines, PriceLine{Kind: TaxKind, Description: fmt.Sprintf("%.2f%% sales tax", TaxRate*100), Amount: b.Tax})
+
+	b.Total = roundCents(goods + b.Shipping + b.Tax)
+	return b, nil
+}
+
+// PriceOrder prices a line-item order and checks the total matches the amount
+// the customer agreed to pay; a mismatch is a non-retryable InvalidOrder error
+func PriceOrder(ctx context.Context, order Order) (PriceBreakdown, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Pricing order", "orderID", order.ID, "items", len(order.Items))
+
+	breakdown, err := Price(order)
+	if err != nil {
+		return PriceBreakdown{}, err
+	}
+	if math.Abs(breakdown.Total-order.Amount) >= 0.005 {
+		return breakdown, errs.InvalidOrder.New(
+			fmt.Sprintf("order amount %.2f does not match computed total %.2f", order.Amount, breakdown.Total), order.ID, breakdown)
+	}
+
+	logger.Info("Order priced", "orderID", order.ID, "subtotal", breakdown.Subtotal, "discount", breakdown.Discount,
+		"shipping", breakdown.Shipping, "tax", breakdown.Tax, "total", breakdown.Total)
+	return breakdown, nil
+}
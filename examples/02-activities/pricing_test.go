@@ -0,0 +1,68 @@
+package activities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"temporal-go-examples/shared/errs"
+)
+
+func TestPrice(t *testing.T) {
+	cart := []LineItem{{SKU: "TSHIRT-M", Quantity: 2, UnitPrice: 19.99}, {SKU: "MUG", Quantity: 1, UnitPrice: 12.50}}
+	mugsAt := func(n int, price float64) []LineItem { return []LineItem{{SKU: "MUG", Quantity: n, UnitPrice: price}} }
+
+	for _, test := range []struct {
+		name                                     string
+		order                                    Order
+		subtotal, discount, shipping, tax, total float64
+	}{
+		{"percentage discount", Order{Items: cart, DiscountCode: "SAVE10"}, 52.48, 5.25, 5.99, 3.90, 57.12},
+		{"codes are case-insensitive", Order{Items: cart, DiscountCode: "save10"}, 52.48, 5.25, 5.99, 3.90, 57.12},
+		{"no discount", Order{Items: cart}, 52.48, 0, 5.99, 4.33, 62.80},
+		{"fixed discount", Order{Items: cart, DiscountCode: "WELCOME5"}, 52.48, 5, 5.99, 3.92, 57.39},
+		{"fixed discount capped at the subtotal", Order{Items: mugsAt(1, 3), DiscountCode: "WELCOME5"}, 3, 3, 5.99, 0, 5.99},
+		{"free standard shipping from 100", Order{Items: mugsAt(4, 25)}, 100, 0, 0, 8.25, 108.25},
+		{"free shipping counts the discounted goods", Order{Items: mugsAt(4, 27.50), DiscountCode: "SAVE10"}, 110, 11, 5.99, 8.17, 113.16},
+		{"express is never free", Order{Items: mugsAt(5, 25), ShippingMethod: "express"}, 125, 0, 14.99, 10.31, 150.30},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := Price(test.order)
+			require.NoError(t, err)
+			assert.Equal(t, test.subtotal, b.Subtotal, "subtotal")
+			assert.Equal(t, test.discount, b.Discount, "discount")
+			assert.Equal(t, test.shipping, b.Shipping, "shipping")
+			assert.Equal(t, test.tax, b.Tax, "tax")
+			assert.Equal(t, test.total, b.Total, "total")
+
+			// The lines add up to the total
+			sum := 0.0
+			for _, line := range b.Lines {
+				sum += line.Amount
+			}
+			assert.InDelta(t, b.Total, sum, 0.005)
+		})
+	}
+}
+
+func TestPriceLines(t *testing.T) {
+	b, err := Price(Order{Items: []LineItem{{SKU: "MUG", Quantity: 2, UnitPrice: 12.50}}, DiscountCode: "SAVE10"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []PriceLine{
+		{Kind: LineItemKind, Description: "2 x MUG @ 12.50", Amount: 25},
+		{Kind: DiscountKind, Description: "SAVE10", Amount: -2.5},
+		{Kind: ShippingKind, Description: "standard", Amount: 5.99},
+		{Kind: TaxKind, Description: "8.25% sales tax", Amount: 1.86},
+	}, b.Lines)
+}
+
+func TestPriceRejectsUnknownOptions(t *testing.T) {
+	items := []LineItem{{SKU: "MUG", Quantity: 1, UnitPrice: 12.50}}
+
+	_, err := Price(Order{ID: "order-1", Items: items, DiscountCode: "FREE"})
+	assert.True(t, errs.InvalidOrder.Is(err), "got %v", err)
+	_, err = Price(Order{ID: "order-1", Items: items, ShippingMethod: "teleport"})
+	assert.True(t, errs.InvalidOrder.Is(err), "got %v", err)
+}
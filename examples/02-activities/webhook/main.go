@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	activities "temporal-go-examples/examples/02-activities"
+	"temporal-go-examples/shared"
+)
+
+func main() {
+	addr := flag.String("addr", activities.DefaultWebhookAddr, "Address to listen on")
+	byID := flag.Bool("by-id", false, "Complete activities by workflow and activity ID instead of task token")
+	flag.Parse()
+
+	// Create Temporal client
+	c, err := shared.CreateTemporalClient()
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	// The receiver reads the task tokens ProcessPayment saved; point
+	// PAYMENT_TOKEN_STORE_FILE at the same file as the worker
+	http.Handle("/webhooks/payments", &activities.WebhookReceiver{
+		Completer: c,
+		Store:     activities.PaymentStoreFromEnv(),
+		ByID:      *byID,
+	})
+
+	shared.LogInfo("🔔 Payment webhook receiver listening on %s/webhooks/payments", *addr)
+	log.Fatalln(http.ListenAndServe(*addr, nil))
+}
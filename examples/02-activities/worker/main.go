@@ -5,6 +5,8 @@ import (
 
 	activities "temporal-go-examples/examples/02-activities"
 	"temporal-go-examples/shared"
+	"temporal-go-examples/shared/policies"
+	"temporal-go-examples/shared/ratelimit"
 )
 
 func main() {
@@ -15,6 +17,18 @@ func main() {
 	}
 	defer c.Close()
 
+	// Load the activity policy catalog - set ACTIVITY_POLICIES_FILE to tune
+	// retries and timeouts; the file is re-read whenever it changes
+	if err := policies.LoadFromEnv(); err != nil {
+		log.Fatalln("Unable to load activity policies", err)
+	}
+
+	// Rate limits for the payment gateway and email provider - set
+	// RATE_LIMIT_STORE_FILE so every worker draws from the same buckets
+	if activities.Limiter, err = ratelimit.FromEnv(); err != nil {
+		log.Fatalln("Unable to load rate limits", err)
+	}
+
 	// Create worker
 	w := shared.CreateTemporalWorker(c)
 
@@ -23,12 +37,26 @@ func main() {
 	w.RegisterWorkflow(activities.OrderProcessingWorkflow)
 	w.RegisterActivity(activities.ValidateOrder)
 	w.RegisterActivity(activities.ProcessPayment)
+	w.RegisterActivity(activities.PriceOrder)
+	w.RegisterActivity(activities.ReserveInventory)
+	w.RegisterActivity(activities.CommitReservation)
+	w.RegisterActivity(activities.ReleaseReservation)
+	w.RegisterActivity(activities.VoidPayment)
+	w.RegisterActivity(activities.RefundPayment)
 	w.RegisterActivity(activities.SendConfirmationEmail)
+	w.RegisterActivity(activities.SendConfirmationWebhook)
+	w.RegisterActivity(activities.WriteConfirmationFile)
+	w.RegisterWorkflow(activities.NotificationOutboxWorkflow)
+	w.RegisterWorkflow(activities.OrderBatchWorkflow)
+	w.RegisterActivity(activities.ProcessOrderBatch)
+	w.RegisterWorkflow(activities.ParallelOrderProcessingWorkflow)
+	w.RegisterActivity(activities.ScoreFraud)
+	w.RegisterActivity(activities.VerifyAddress)
 
 	// Start the worker
 	shared.LogInfo("Worker is starting...")
-	shared.LogInfo("Registered workflow: OrderProcessingWorkflow")
-	shared.LogInfo("Registered activities: ValidateOrder, ProcessPayment, SendConfirmationEmail")
+	shared.LogInfo("Registered workflows: OrderProcessingWorkflow, NotificationOutboxWorkflow, OrderBatchWorkflow, ParallelOrderProcessingWorkflow")
+	shared.LogInfo("Registered activities: ValidateOrder, PriceOrder, ReserveInventory, ProcessPayment, CommitReservation, ReleaseReservation, VoidPayment, RefundPayment, SendConfirmationEmail, SendConfirmationWebhook, WriteConfirmationFile, ProcessOrderBatch, ScoreFraud, VerifyAddress")
 	shared.LogInfo("Press Ctrl+C to stop the worker")
 	shared.StartWorker(w)
 }
@@ -1,11 +1,13 @@
 package activities
 
 import (
+	"errors"
 	"fmt"
-	"time"
 
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
+
+	"temporal-go-examples/shared/policies"
 )
 
 // Order represents an order to be processed
@@ -14,55 +16,273 @@ type Order struct {
 	UserID  string  `json:"user_id"`
 	Email   string  `json:"email"`
 	Amount  float64 `json:"amount"`
-	Product string  `json:"product"`
+	Product string  `json:"product"` // Single-product orders only; see Items
+	// Line items; when set, Amount must equal the total PriceOrder computes
+	Items          []LineItem `json:"items,omitempty"`
+	DiscountCode   string     `json:"discount_code,omitempty"`
+	ShippingMethod string     `json:"shipping_method,omitempty"` // "standard" (default) or "express"
+	// Merchant selling the product; the payment gateway rate-limits each merchant
+	Merchant string `json:"merchant,omitempty"`
+	// Where the order ships; checked by VerifyAddress in ParallelOrderProcessingWorkflow
+	ShippingAddress string `json:"shipping_address,omitempty"`
+	// Channels to send the confirmation on: "email" (default), "webhook", "file"
+	Channels []string `json:"channels,omitempty"`
 }
 
 // OrderProcessingWorkflow orchestrates the order processing steps
-// This workflow calls multiple activities in sequence
-func OrderProcessingWorkflow(ctx workflow.Context, order Order) (string, error) {
+// This workflow calls multiple activities in sequence. Until the order is
+// committed it can be canceled with the cancel-order update, or by canceling
+// the workflow; either way the result says how the order ended
+func OrderProcessingWorkflow(ctx workflow.Context, order Order) (OrderResult, error) {
 	logger := workflow.GetLogger(ctx)
 	logger.Info("OrderProcessingWorkflow started", "orderID", order.ID)
 
-	// Activity options - configure timeouts and retry policies
-	activityOptions := workflow.ActivityOptions{
-		StartToCloseTimeout: time.Minute * 2, // Max time for activity to complete
-		RetryPolicy: &temporal.RetryPolicy{
-			InitialInterval:    time.Second,
-			BackoffCoefficient: 2.0,
-			MaximumInterval:    time.Second * 30,
-			MaximumAttempts:    3,
+	// The steps run on orderCtx; the cancel-order update cancels it to stop
+	// whatever activity is in flight. Workflow cancellation cancels it too
+	orderCtx, cancelOrder := workflow.WithCancel(ctx)
+	state := &orderState{step: StepValidating}
+	runID := workflow.GetInfo(ctx).WorkflowExecution.RunID
+
+	err := workflow.SetUpdateHandlerWithOptions(ctx, CancelOrderUpdate,
+		func(ctx workflow.Context, request CancelRequest) (OrderResult, error) {
+			logger.Info("Cancel requested", "orderID", order.ID, "step", state.step, "reason", request.Reason)
+			state.cancel = &request
+			cancelOrder()
+			// Reply once compensation has finished, with how the order ended
+			if err := workflow.Await(ctx, func() bool { return state.result != nil }); err != nil {
+				return OrderResult{}, err
+			}
+			return *state.result, nil
 		},
+		workflow.UpdateHandlerOptions{Validator: state.validateCancel},
+	)
+	if err != nil {
+		return OrderResult{}, err
+	}
+
+	// canceled ends a canceled order: it compensates, then returns the result,
+	// or the cancellation error when the workflow itself was canceled
+	canceled := func() (OrderResult, error) {
+		reason := "workflow canceled"
+		if state.cancel != nil {
+			reason = state.cancel.Reason
+		}
+		result := state.compensate(ctx, order, reason)
+		state.result = &result
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		return result, nil
+	}
+
+	// failed ends an order whose step failed. The result is recorded first, so
+	// a cancel-order update accepted meanwhile replies instead of waiting forever
+	failed := func(err error) (OrderResult, error) {
+		state.result = &OrderResult{
+			OrderID:       order.ID,
+			Status:        OrderFailed,
+			Step:          state.step,
+			PaymentID:     state.paymentID,
+			ReservationID: state.reservationID,
+			Pricing:       state.pricing,
+			Message:       err.Error(),
+		}
+		return OrderResult{}, err
 	}
-	ctx = workflow.WithActivityOptions(ctx, activityOptions)
 
 	// Step 1: Validate the order
 	logger.Info("Validating order", "orderID", order.ID)
-	err := workflow.ExecuteActivity(ctx, ValidateOrder, order).Get(ctx, nil)
+	err = policies.ExecuteActivity(orderCtx, ValidateOrder, order).Get(orderCtx, nil)
+	if orderCtx.Err() != nil {
+		return canceled()
+	}
 	if err != nil {
 		logger.Error("Order validation failed", "error", err)
-		return "", fmt.Errorf("order validation failed: %w", err)
+		return failed(fmt.Errorf("order validation failed: %w", err))
 	}
 
-	// Step 2: Process payment
+	// Step 2: Price line-item orders and check the amount matches
+	// Workflows started before pricing existed never ran PriceOrder; GetVersion
+	// keeps their replays on the old path. Single-product orders have nothing to price
+	pricing := workflow.GetVersion(ctx, "line-item-pricing", workflow.DefaultVersion, 1)
+	if pricing != workflow.DefaultVersion && len(order.Items) > 0 {
+		state.step = StepPricing
+		logger.Info("Pricing order", "items", len(order.Items))
+		var breakdown PriceBreakdown
+		err = policies.ExecuteActivity(orderCtx, PriceOrder, order).Get(orderCtx, &breakdown)
+		if orderCtx.Err() != nil {
+			return canceled()
+		}
+		if err != nil {
+			logger.Error("Order pricing failed", "error", err)
+			return failed(fmt.Errorf("order pricing failed: %w", err))
+		}
+		logger.Info("Order priced", "subtotal", breakdown.Subtotal, "tax", breakdown.Tax, "total", breakdown.Total)
+		state.pricing = &breakdown
+	}
+
+	// A canceled reserve or charge can still land on the worker after the
+	// cancel, so those steps wait for their activity to settle before the
+	// compensation runs; otherwise the release or void could come first and
+	// leak the stock or charge a canceled order. Runs started before that
+	// keep returning as soon as the cancel is requested
+	settleBeforeCompensating := workflow.GetVersion(ctx, "settle-before-compensate", workflow.DefaultVersion, 1) != workflow.DefaultVersion
+	settled := func(activity interface{}, args ...interface{}) workflow.Future {
+		if !settleBeforeCompensating {
+			return policies.ExecuteActivity(orderCtx, activity, args...)
+		}
+		options := policies.ActivityOptions(orderCtx, activity)
+		options.WaitForCancellation = true
+		return workflow.ExecuteActivity(workflow.WithActivityOptions(orderCtx, options), activity, args...)
+	}
+
+	// Step 3: Reserve stock before taking any money
+	// The reservation ID is deterministic, so a cancel during this step can
+	// release a reservation whose result the workflow never saw. Workflows
+	// started before reservations existed went straight to payment; GetVersion
+	// keeps their replays without the reserve and commit steps
+	reserving := workflow.GetVersion(ctx, "reserve-inventory", workflow.DefaultVersion, 1) != workflow.DefaultVersion
+	if reserving {
+		state.step = StepReserving
+		state.reservationID = reservationID(order.ID, runID)
+		logger.Info("Reserving inventory", "items", len(order.lineItems()))
+		err = settled(ReserveInventory, order).Get(orderCtx, nil)
+		if orderCtx.Err() != nil {
+			return canceled()
+		}
+		if err != nil {
+			logger.Error("Inventory reservation failed", "error", err)
+			return failed(fmt.Errorf("inventory reservation failed: %w", err))
+		}
+	}
+
+	// Step 4: Process payment - if it fails, put the stock back
+	// Until the webhook confirms it, the payment is only authorized and a
+	// cancel voids it
+	state.step = StepPaying
+	state.paymentID = paymentIDFor(order.ID, runID)
 	logger.Info("Processing payment", "amount", order.Amount)
 	var paymentID string
-	err = workflow.ExecuteActivity(ctx, ProcessPayment, order).Get(ctx, &paymentID)
+	err = settled(ProcessPayment, order).Get(orderCtx, &paymentID)
+	if err == nil {
+		// Record the capture before honoring a cancel that arrived with it: a
+		// captured payment has to be refunded, voiding it would keep the money
+		state.paymentID = paymentID
+		state.paymentCaptured = true
+	}
+	if orderCtx.Err() != nil {
+		return canceled()
+	}
 	if err != nil {
+		if reserving {
+			releaseReservation(ctx, state.reservationID)
+		}
+
+		// The gateway never sent its webhook before the policy's timeouts ran out
+		// The payment may still settle, and a late webhook keeps it on record,
+		// so void it rather than leave a failed order open to a charge.
+		// Orders that failed here before voiding existed replay without it
+		var timeoutErr *temporal.TimeoutError
+		if errors.As(err, &timeoutErr) {
+			logger.Error("No payment confirmation received", "timeoutType", timeoutErr.TimeoutType())
+			if workflow.GetVersion(ctx, "void-unconfirmed-payment", workflow.DefaultVersion, 1) != workflow.DefaultVersion {
+				voidPayment(ctx, state.paymentID)
+			}
+			return failed(fmt.Errorf("payment for order %s was never confirmed by the gateway: %w", order.ID, err))
+		}
 		logger.Error("Payment processing failed", "error", err)
-		return "", fmt.Errorf("payment processing failed: %w", err)
+		return failed(fmt.Errorf("payment processing failed: %w", err))
 	}
 
-	// Step 3: Send confirmation email
-	logger.Info("Sending confirmation email", "email", order.Email)
-	err = workflow.ExecuteActivity(ctx, SendConfirmationEmail, order, paymentID).Get(ctx, nil)
-	if err != nil {
-		logger.Error("Failed to send confirmation email", "error", err)
-		// Note: We don't fail the workflow if email fails
-		// This is a business decision - order is still processed
-		logger.Warn("Order processed but confirmation email failed")
+	// Step 5: The payment is captured, so the reservation becomes final
+	// A cancel from here on refunds the payment
+	if reserving {
+		state.step = StepCommitting
+		logger.Info("Committing reservation", "reservationID", state.reservationID)
+		err = policies.ExecuteActivity(orderCtx, CommitReservation, state.reservationID).Get(orderCtx, nil)
+		if err == nil {
+			// Likewise a committed reservation can't be released any more
+			state.committed = true
+		}
+		if orderCtx.Err() != nil {
+			return canceled()
+		}
+		if err != nil {
+			// The customer has paid; don't release the stock, have someone look at it
+			logger.Error("Reservation commit failed after payment", "reservationID", state.reservationID, "paymentID", paymentID, "error", err)
+			return failed(fmt.Errorf("order %s paid (%s) but reservation %s could not be committed: %w", order.ID, paymentID, state.reservationID, err))
+		}
+	}
+
+	// Step 6: Send the confirmation on each channel the customer chose
+	// The order is complete now; the validator rejects cancels from here on
+	// Orders without Channels get the single SendConfirmationEmail call they always did
+	state.step = StepNotifying
+	var failedChannels []string
+	var notifyErr error
+	for _, channel := range order.confirmationChannels() {
+		logger.Info("Sending confirmation", "channel", channel, "email", order.Email)
+		err = policies.ExecuteActivity(ctx, confirmationActivities[channel], order, paymentID).Get(ctx, nil)
+		if err != nil {
+			logger.Error("Failed to send confirmation", "channel", channel, "error", err)
+			// Note: We don't fail the workflow if a notification fails
+			// This is a business decision - order is still processed
+			failedChannels = append(failedChannels, channel)
+			notifyErr = err
+		}
 	}
 
-	result := fmt.Sprintf("Order %s processed successfully! Payment ID: %s", order.ID, paymentID)
-	logger.Info("OrderProcessingWorkflow completed", "result", result)
+	// Hand failed confirmations to the outbox, which retries them for days
+	// Earlier runs only logged the failure; GetVersion keeps their replays there
+	var outboxID string
+	if len(failedChannels) > 0 {
+		if workflow.GetVersion(ctx, "notification-outbox", workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+			logger.Warn("Order processed but confirmation failed", "channels", failedChannels)
+		} else if outboxID, err = handOffToOutbox(ctx, order, runID, paymentID, failedChannels, notifyErr); err != nil {
+			logger.Error("Unable to start notification outbox", "channels", failedChannels, "error", err)
+		} else {
+			logger.Warn("Order processed, confirmation handed to the outbox", "channels", failedChannels, "outboxWorkflowID", outboxID)
+		}
+	}
+
+	state.step = StepCompleted
+	result := OrderResult{
+		OrderID:       order.ID,
+		Status:        OrderCompleted,
+		Step:          state.step,
+		PaymentID:     paymentID,
+		ReservationID: state.reservationID,
+		Pricing:       state.pricing,
+		OutboxID:      outboxID,
+		Message:       fmt.Sprintf("Order %s processed successfully! Payment ID: %s", order.ID, paymentID),
+	}
+	state.result = &result
+	logger.Info("OrderProcessingWorkflow completed", "result", result.Message)
 	return result, nil
 }
+
+// releaseReservation puts reserved stock back after the order failed
+// It uses a disconnected context so the release still runs when the workflow
+// itself is being canceled. A failed release is logged, not returned: the
+// caller is already failing with the error that matters
+func releaseReservation(ctx workflow.Context, reservationID string) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Releasing reservation", "reservationID", reservationID)
+	cleanupCtx, cancel := workflow.NewDisconnectedContext(ctx)
+	defer cancel()
+	if err := policies.ExecuteActivity(cleanupCtx, ReleaseReservation, reservationID).Get(cleanupCtx, nil); err != nil {
+		logger.Error("Unable to release reservation", "reservationID", reservationID, "error", err)
+	}
+}
+
+// voidPayment voids a payment the gateway never confirmed, after the order failed
+// Like releaseReservation it runs on a disconnected context and only logs a failure
+func voidPayment(ctx workflow.Context, paymentID string) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Voiding unconfirmed payment", "paymentID", paymentID)
+	cleanupCtx, cancel := workflow.NewDisconnectedContext(ctx)
+	defer cancel()
+	if err := policies.ExecuteActivity(cleanupCtx, VoidPayment, paymentID).Get(cleanupCtx, nil); err != nil {
+		logger.Error("Unable to void payment", "paymentID", paymentID, "error", err)
+	}
+}
@@ -0,0 +1,258 @@
+package activities
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+// testOrder is a single-product order, so PriceOrder doesn't run
+var testOrder = Order{ID: "order-1", UserID: "user-1", Email: "test@example.com", Amount: 99.99, Product: "widget", Merchant: "merchant-1"}
+
+// testRunID is the run ID of every workflow the test environment starts
+const testRunID = "default-test-run-id"
+
+// newOrderEnv registers OrderProcessingWorkflow and its activities; validation
+// and reservation are mocked to succeed, the rest is left to each test
+func newOrderEnv() *testsuite.TestWorkflowEnvironment {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(OrderProcessingWorkflow)
+	for _, activity := range []interface{}{
+		ValidateOrder, PriceOrder, ReserveInventory, ProcessPayment, CommitReservation,
+		ReleaseReservation, VoidPayment, RefundPayment, SendConfirmationEmail,
+	} {
+		env.RegisterActivity(activity)
+	}
+	env.OnActivity(ValidateOrder, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(ReserveInventory, mock.Anything, mock.Anything).Return(reservationID(testOrder.ID, testRunID), nil)
+	return env
+}
+
+func TestPaymentWebhookNeverArrives(t *testing.T) {
+	env := newOrderEnv()
+	paymentID := paymentIDFor(testOrder.ID, testRunID)
+	// Every attempt waits for a webhook that never comes, until the policy's
+	// 5 minute ScheduleToCloseTimeout runs out; time skipping makes that instant
+	env.OnActivity(ProcessPayment, mock.Anything, mock.Anything).After(5*time.Minute).
+		Return("", temporal.NewTimeoutError(enumspb.TIMEOUT_TYPE_SCHEDULE_TO_CLOSE, nil))
+	env.OnActivity(ReleaseReservation, mock.Anything, reservationID(testOrder.ID, testRunID)).Return(nil).Once()
+	env.OnActivity(VoidPayment, mock.Anything, paymentID).Return(nil).Once()
+	start := env.Now()
+
+	env.ExecuteWorkflow(OrderProcessingWorkflow, testOrder)
+
+	require.True(t, env.IsWorkflowCompleted())
+	err := env.GetWorkflowError()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "was never confirmed by the gateway")
+	assert.GreaterOrEqual(t, env.Now().Sub(start), 5*time.Minute)
+	// The stock goes back, and the payment is voided so it can't settle later
+	env.AssertExpectations(t)
+	env.AssertActivityNotCalled(t, "CommitReservation", mock.Anything, mock.Anything)
+}
+
+func TestCompletedOrderCarriesItsPricing(t *testing.T) {
+	env := newOrderEnv()
+	order := testOrder
+	order.Product, order.Amount = "", 57.12
+	order.Items = []LineItem{{SKU: "TSHIRT-M", Quantity: 2, UnitPrice: 19.99}, {SKU: "MUG", Quantity: 1, UnitPrice: 12.50}}
+	order.DiscountCode = "SAVE10"
+	paymentID := paymentIDFor(order.ID, testRunID)
+	env.OnActivity(ProcessPayment, mock.Anything, mock.Anything).Return(paymentID, nil)
+	env.OnActivity(CommitReservation, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(SendConfirmationEmail, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	env.ExecuteWorkflow(OrderProcessingWorkflow, order)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var result OrderResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	assert.Equal(t, OrderCompleted, result.Status)
+	require.NotNil(t, result.Pricing, "the PriceOrder breakdown should be in the result")
+	assert.Equal(t, 57.12, result.Pricing.Total)
+	assert.Equal(t, 5.25, result.Pricing.Discount)
+	assert.Len(t, result.Pricing.Lines, 5)
+}
+
+// cancelResult collects the reply to a cancel-order update
+type cancelResult struct {
+	result OrderResult
+	err    error
+}
+
+func (c *cancelResult) Accept()          {}
+func (c *cancelResult) Reject(err error) { c.err = err }
+func (c *cancelResult) Complete(success interface{}, err error) {
+	c.err = err
+	if result, ok := success.(OrderResult); ok {
+		c.result = result
+	}
+}
+
+// timeline records when activities finished and compensations started
+type timeline struct {
+	env    *testsuite.TestWorkflowEnvironment
+	start  time.Time
+	events []string
+	at     map[string]time.Duration
+}
+
+func newTimeline(env *testsuite.TestWorkflowEnvironment) *timeline {
+	tl := &timeline{env: env, start: env.Now(), at: map[string]time.Duration{}}
+	env.SetOnActivityCompletedListener(func(info *activity.Info, result converter.EncodedValue, err error) {
+		tl.record(info.ActivityType.Name + " settled")
+	})
+	return tl
+}
+
+func (tl *timeline) record(event string) {
+	tl.events = append(tl.events, event)
+	tl.at[event] = tl.env.Now().Sub(tl.start)
+}
+
+// serverCancellation makes the test environment treat WaitForCancellation
+// the way the server does. The environment resolves a canceled activity at
+// once and drops whatever it returns later; the server keeps the activity's
+// future open until the worker reports how it ended. Activities scheduled
+// with WaitForCancellation therefore run on a disconnected context here, so
+// the workflow sees the result they land with, after the cancel
+type serverCancellation struct {
+	interceptor.WorkerInterceptorBase
+}
+
+func (*serverCancellation) InterceptWorkflow(ctx workflow.Context, next interceptor.WorkflowInboundInterceptor) interceptor.WorkflowInboundInterceptor {
+	return &serverCancellationInbound{WorkflowInboundInterceptorBase: interceptor.WorkflowInboundInterceptorBase{Next: next}}
+}
+
+type serverCancellationInbound struct {
+	interceptor.WorkflowInboundInterceptorBase
+}
+
+func (i *serverCancellationInbound) Init(outbound interceptor.WorkflowOutboundInterceptor) error {
+	return i.Next.Init(&serverCancellationOutbound{WorkflowOutboundInterceptorBase: interceptor.WorkflowOutboundInterceptorBase{Next: outbound}})
+}
+
+type serverCancellationOutbound struct {
+	interceptor.WorkflowOutboundInterceptorBase
+}
+
+func (o *serverCancellationOutbound) ExecuteActivity(ctx workflow.Context, activityType string, args ...interface{}) workflow.Future {
+	if workflow.GetActivityOptions(ctx).WaitForCancellation {
+		ctx, _ = workflow.NewDisconnectedContext(ctx)
+	}
+	return o.Next.ExecuteActivity(ctx, activityType, args...)
+}
+
+// cancelAfter sends the cancel-order update once the delay has passed
+func cancelAfter(env *testsuite.TestWorkflowEnvironment, delay time.Duration) *cancelResult {
+	reply := &cancelResult{}
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(CancelOrderUpdate, "cancel-1", reply, CancelRequest{Reason: "changed my mind"})
+	}, delay)
+	return reply
+}
+
+func TestCancelDuringReserveWaitsForTheReservation(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(worker.Options{Interceptors: []interceptor.WorkerInterceptor{&serverCancellation{}}})
+	env.RegisterWorkflow(OrderProcessingWorkflow)
+	env.RegisterActivity(ValidateOrder)
+	env.RegisterActivity(ReserveInventory)
+	env.RegisterActivity(ReleaseReservation)
+	tl := newTimeline(env)
+	resID := reservationID(testOrder.ID, testRunID)
+	env.OnActivity(ValidateOrder, mock.Anything, mock.Anything).Return(nil)
+	// The reservation lands on the worker 10s in, after the cancel at 5s
+	env.OnActivity(ReserveInventory, mock.Anything, mock.Anything).After(10*time.Second).Return(resID, nil)
+	env.OnActivity(ReleaseReservation, mock.Anything, resID).Return(func(ctx context.Context, id string) error {
+		tl.record("release")
+		return nil
+	}).Once()
+	reply := cancelAfter(env, 5*time.Second)
+
+	env.ExecuteWorkflow(OrderProcessingWorkflow, testOrder)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.NoError(t, reply.err)
+	assert.Equal(t, OrderCanceled, reply.result.Status)
+	assert.Equal(t, StepReserving, reply.result.Step)
+	assert.True(t, reply.result.StockReleased)
+	// Released only once the reserve settled, so the stock it took goes back
+	assert.Less(t, indexOf(tl.events, "ReserveInventory settled"), indexOf(tl.events, "release"), "events: %v", tl.events)
+	assert.GreaterOrEqual(t, tl.at["release"], 10*time.Second)
+	env.AssertExpectations(t)
+}
+
+func TestCancelDuringPaymentWaitsForTheCharge(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		charged      bool
+		compensation string
+	}{
+		{"charge lands after the cancel", true, CompensationRefunded},
+		{"charge stops after the cancel", false, CompensationVoided},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			env := newOrderEnv()
+			env.SetWorkerOptions(worker.Options{Interceptors: []interceptor.WorkerInterceptor{&serverCancellation{}}})
+			tl := newTimeline(env)
+			paymentID := paymentIDFor(testOrder.ID, testRunID)
+			payment := env.OnActivity(ProcessPayment, mock.Anything, mock.Anything).After(10 * time.Second)
+			if test.charged {
+				payment.Return(paymentID, nil)
+				env.OnActivity(RefundPayment, mock.Anything, paymentID, testOrder.Amount).Return(func(ctx context.Context, id string, amount float64) (string, error) {
+					tl.record("refund")
+					return "rf_" + id, nil
+				}).Once()
+			} else {
+				payment.Return("", temporal.NewCanceledError())
+				env.OnActivity(VoidPayment, mock.Anything, paymentID).Return(func(ctx context.Context, id string) error {
+					tl.record("void")
+					return nil
+				}).Once()
+			}
+			env.OnActivity(ReleaseReservation, mock.Anything, mock.Anything).Return(nil).Once()
+			reply := cancelAfter(env, 5*time.Second)
+
+			env.ExecuteWorkflow(OrderProcessingWorkflow, testOrder)
+
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+			require.NoError(t, reply.err)
+			assert.Equal(t, OrderCanceled, reply.result.Status)
+			assert.Equal(t, StepPaying, reply.result.Step)
+			assert.Equal(t, test.compensation, reply.result.Compensation)
+			assert.True(t, reply.result.StockReleased)
+			// Money is compensated only once the charge settled, whichever way it went
+			money := map[bool]string{true: "refund", false: "void"}[test.charged]
+			assert.GreaterOrEqual(t, tl.at[money], 10*time.Second)
+			assert.Less(t, indexOf(tl.events, "ProcessPayment settled"), indexOf(tl.events, money), "events: %v", tl.events)
+			env.AssertExpectations(t)
+		})
+	}
+}
+
+func indexOf(events []string, event string) int {
+	for i, e := range events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
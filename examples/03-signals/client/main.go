@@ -18,9 +18,12 @@ func main() {
 	defer c.Close()
 
 	// Start the workflow
-	shared.LogInfo("Starting DeliveryOrderWorkflow...")
+	shared.LogInfo("Starting DeliveryWorkflow...")
 
-	workflowRun, err := shared.ExecuteWorkflow(c, signals.DeliveryOrderWorkflow, "Pizza", "123 Main St")
+	workflowRun, err := shared.ExecuteWorkflow(c, signals.DeliveryWorkflow, signals.DeliveryRequest{
+		Items:   []string{"Pizza"},
+		Address: "123 Main St",
+	})
 	if err != nil {
 		log.Fatalln("Unable to execute workflow", err)
 	}
@@ -35,7 +38,7 @@ func main() {
 	shared.LogInfo("Sending signals to update the order...")
 
 	// Add an item
-	err = c.SignalWorkflow(context.Background(), workflowID, "", "add-item", "Coke")
+	err = c.SignalWorkflow(context.Background(), workflowID, "", signals.AddItemSignal, "Coke")
 	if err != nil {
 		log.Fatalln("Unable to signal workflow", err)
 	}
@@ -44,7 +47,7 @@ func main() {
 	time.Sleep(time.Second)
 
 	// Add another item
-	err = c.SignalWorkflow(context.Background(), workflowID, "", "add-item", "Fries")
+	err = c.SignalWorkflow(context.Background(), workflowID, "", signals.AddItemSignal, "Fries")
 	if err != nil {
 		log.Fatalln("Unable to signal workflow", err)
 	}
@@ -53,7 +56,7 @@ func main() {
 	time.Sleep(time.Second)
 
 	// Update address
-	err = c.SignalWorkflow(context.Background(), workflowID, "", "update-address", "456 Oak Avenue")
+	err = c.SignalWorkflow(context.Background(), workflowID, "", signals.UpdateAddressSignal, "456 Oak Avenue")
 	if err != nil {
 		log.Fatalln("Unable to signal workflow", err)
 	}
@@ -61,7 +64,7 @@ func main() {
 
 	// Query the current status
 	time.Sleep(time.Second)
-	resp, err := c.QueryWorkflow(context.Background(), workflowID, "", "get-status")
+	resp, err := c.QueryWorkflow(context.Background(), workflowID, "", signals.StatusQuery)
 	if err != nil {
 		log.Fatalln("Unable to query workflow", err)
 	}
@@ -74,10 +77,22 @@ func main() {
 	shared.LogInfo("📊 Current status: %s, Items: %v, Address: %s",
 		status.Status, status.Items, status.Address)
 
+	// Updates are synchronous: the caller learns whether the change was
+	// accepted and gets the new status back
+	status, err = signals.RemoveItem(context.Background(), c, workflowID, "Fries")
+	if err != nil {
+		log.Fatalln("Unable to remove item", err)
+	}
+	shared.LogInfo("✅ Removed item: Fries, Items now: %v (version %d)", status.Items, status.Version)
+
+	if _, err := signals.UpdateAddress(context.Background(), c, workflowID, "nowhere"); err != nil {
+		shared.LogInfo("🚫 Address change rejected: %v", err)
+	}
+
 	// Wait a bit more and then complete the order
 	time.Sleep(time.Second * 3)
 
-	err = c.SignalWorkflow(context.Background(), workflowID, "", "complete-order", "Customer confirmed delivery")
+	err = c.SignalWorkflow(context.Background(), workflowID, "", signals.CompleteOrderSignal, "Customer confirmed delivery")
 	if err != nil {
 		log.Fatalln("Unable to signal workflow", err)
 	}
@@ -0,0 +1,265 @@
+package signals
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+
+	"temporal-go-examples/shared"
+	"temporal-go-examples/shared/errs"
+)
+
+// DeliveryClient is what the gateway needs from Temporal; client.Client implements it
+type DeliveryClient interface {
+	ExecuteWorkflow(ctx context.Context, options client.StartWorkflowOptions, workflow interface{}, args ...interface{}) (client.WorkflowRun, error)
+	SignalWorkflow(ctx context.Context, workflowID string, runID string, signalName string, arg interface{}) error
+	QueryWorkflow(ctx context.Context, workflowID string, runID string, queryType string, args ...interface{}) (converter.EncodedValue, error)
+	DescribeWorkflowExecution(ctx context.Context, workflowID, runID string) (*workflowservice.DescribeWorkflowExecutionResponse, error)
+	UpdateWorkflow(ctx context.Context, options client.UpdateWorkflowOptions) (client.WorkflowUpdateHandle, error)
+}
+
+// Gateway exposes deliveries over HTTP so front-ends don't need a Temporal client
+//
+//	POST /deliveries                 start a delivery (body: DeliveryRequest)
+//	GET  /deliveries/{id}            get-status query
+//	GET  /deliveries/{id}/events     status changes as Server-Sent Events (see stream.go)
+//	POST /deliveries/{id}/items      AddItem update        {"item": "Coke"}
+//	PUT  /deliveries/{id}/address    UpdateAddress update  {"address": "456 Oak Avenue"}
+//	POST /deliveries/{id}/complete   complete-order signal {"message": "Left at the door"}
+//
+// {id} is the delivery's workflow ID. Changes to items and address go through
+// the validated updates and answer 200 with the new status, 409 once the
+// delivery has been dispatched and 422 for a malformed change. Signals are
+// fire-and-forget, so the complete route answers 202 once Temporal has accepted
+// the signal. An unknown delivery, or a workflow ID that isn't a delivery, is a
+// 404 and a delivery that has already finished is a 409
+type Gateway struct {
+	Client DeliveryClient
+	// PollInterval is how often event streams poll for changes (default DefaultPollInterval)
+	PollInterval time.Duration
+}
+
+// GatewayError is the body of every error response
+type GatewayError struct {
+	Error string `json:"error"`
+}
+
+// SignalAccepted is the body of a signal route's 202 response
+type SignalAccepted struct {
+	WorkflowID string `json:"workflow_id"`
+	Signal     string `json:"signal"`
+	StatusURL  string `json:"status_url"`
+}
+
+// deliveryWorkflowTypes are the type names the delivery workflows are registered under
+// Deliveries started before DeliveryWorkflow existed still run as DeliveryOrderWorkflow
+var deliveryWorkflowTypes = map[string]bool{"DeliveryWorkflow": true, "DeliveryOrderWorkflow": true}
+
+var (
+	// errDeliveryClosed means the delivery workflow has finished and takes no more signals
+	errDeliveryClosed = errors.New("delivery is already completed")
+	// errNotDelivery means the workflow ID belongs to some other workflow
+	errNotDelivery = errors.New("unknown delivery")
+)
+
+// Handler routes the gateway
+func (g *Gateway) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /deliveries", g.startDelivery)
+	mux.HandleFunc("GET /deliveries/{id}", g.getStatus)
+	mux.HandleFunc("GET /deliveries/{id}/events", g.streamStatus)
+	mux.HandleFunc("POST /deliveries/{id}/items", g.updateRoute(AddItemUpdate, "item"))
+	mux.HandleFunc("PUT /deliveries/{id}/address", g.updateRoute(UpdateAddressUpdate, "address"))
+	mux.HandleFunc("POST /deliveries/{id}/complete", g.signalRoute(CompleteOrderSignal, "message"))
+	return mux
+}
+
+func (g *Gateway) startDelivery(w http.ResponseWriter, req *http.Request) {
+	var request DeliveryRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<20)).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, GatewayError{Error: "invalid delivery: " + err.Error()})
+		return
+	}
+	if len(request.Items) == 0 || request.Address == "" {
+		writeJSON(w, http.StatusBadRequest, GatewayError{Error: "a delivery needs items and an address"})
+		return
+	}
+
+	run, err := g.Client.ExecuteWorkflow(req.Context(), client.StartWorkflowOptions{
+		ID:        "delivery-" + shared.RandomID(),
+		TaskQueue: shared.TaskQueue,
+	}, DeliveryWorkflow, request)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, GatewayError{Error: "unable to start delivery: " + err.Error()})
+		return
+	}
+	w.Header().Set("Location", "/deliveries/"+run.GetID())
+	writeJSON(w, http.StatusCreated, map[string]string{"workflow_id": run.GetID(), "run_id": run.GetRunID()})
+}
+
+func (g *Gateway) getStatus(w http.ResponseWriter, req *http.Request) {
+	workflowID := req.PathValue("id")
+	if _, err := g.describeDelivery(req.Context(), workflowID); err != nil {
+		writeError(w, err)
+		return
+	}
+	// Queries also work on finished workflows, so a completed delivery still has a status
+	value, err := g.Client.QueryWorkflow(req.Context(), workflowID, "", StatusQuery)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var status OrderStatus
+	if err := value.Get(&status); err != nil {
+		writeJSON(w, http.StatusBadGateway, GatewayError{Error: "unable to decode status: " + err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// updateRoute sends the JSON body's field as the update's argument and answers
+// with the new status, or with the validator's rejection
+func (g *Gateway) updateRoute(updateName, field string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		workflowID := req.PathValue("id")
+		value, ok := readField(w, req, field, true)
+		if !ok {
+			return
+		}
+
+		if err := g.checkRunning(req.Context(), workflowID); err != nil {
+			writeError(w, err)
+			return
+		}
+		status, err := update(req.Context(), g.Client, workflowID, updateName, value)
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			// It finished between the describe and the update
+			err = errDeliveryClosed
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		shared.LogInfo("%s accepted by %s", updateName, workflowID)
+		writeJSON(w, http.StatusOK, status)
+	}
+}
+
+// signalRoute sends the JSON body's field as the signal's argument
+func (g *Gateway) signalRoute(signalName, field string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		workflowID := req.PathValue("id")
+		value, ok := readField(w, req, field, signalName != CompleteOrderSignal)
+		if !ok {
+			return
+		}
+
+		if err := g.signal(req.Context(), workflowID, signalName, value); err != nil {
+			writeError(w, err)
+			return
+		}
+		shared.LogInfo("Sent %s to %s", signalName, workflowID)
+		writeJSON(w, http.StatusAccepted, SignalAccepted{
+			WorkflowID: workflowID,
+			Signal:     signalName,
+			StatusURL:  "/deliveries/" + workflowID,
+		})
+	}
+}
+
+// readField decodes a JSON object body and returns one of its fields
+// It writes a 400 and returns false if the body is invalid, or the field is
+// required and empty
+func readField(w http.ResponseWriter, req *http.Request, field string, required bool) (string, bool) {
+	var body map[string]string
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<20)).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, GatewayError{Error: "invalid body: " + err.Error()})
+		return "", false
+	}
+	value := body[field]
+	if value == "" && required {
+		writeJSON(w, http.StatusBadRequest, GatewayError{Error: `body needs a non-empty "` + field + `"`})
+		return "", false
+	}
+	return value, true
+}
+
+// checkRunning checks the workflow is a delivery that is still running
+// Temporal answers a signal or update to a finished workflow with NotFound,
+// the same as for an unknown one, so the describe call tells the two apart
+func (g *Gateway) checkRunning(ctx context.Context, workflowID string) error {
+	info, err := g.describeDelivery(ctx, workflowID)
+	if err != nil {
+		return err
+	}
+	if info.GetStatus() != enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		return errDeliveryClosed
+	}
+	return nil
+}
+
+// signal checks the delivery is still running, then signals it
+func (g *Gateway) signal(ctx context.Context, workflowID, signalName string, arg interface{}) error {
+	if err := g.checkRunning(ctx, workflowID); err != nil {
+		return err
+	}
+
+	err := g.Client.SignalWorkflow(ctx, workflowID, "", signalName, arg)
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		// It finished between the describe and the signal
+		return errDeliveryClosed
+	}
+	return err
+}
+
+// describeDelivery describes a workflow and checks it is a delivery
+// Every example shares the namespace, so without this check the gateway would
+// signal or query any workflow whose ID a caller sends, orders included
+func (g *Gateway) describeDelivery(ctx context.Context, workflowID string) (*workflowpb.WorkflowExecutionInfo, error) {
+	description, err := g.Client.DescribeWorkflowExecution(ctx, workflowID, "")
+	if err != nil {
+		return nil, err
+	}
+	info := description.GetWorkflowExecutionInfo()
+	if !deliveryWorkflowTypes[info.GetType().GetName()] {
+		return nil, errNotDelivery
+	}
+	return info, nil
+}
+
+// writeError maps Temporal errors to HTTP statuses
+func writeError(w http.ResponseWriter, err error) {
+	var notFound *serviceerror.NotFound
+	var invalid *serviceerror.InvalidArgument
+	switch {
+	case errors.Is(err, errDeliveryClosed):
+		writeJSON(w, http.StatusConflict, GatewayError{Error: err.Error()})
+	case errs.DeliveryDispatched.Is(err):
+		writeJSON(w, http.StatusConflict, GatewayError{Error: errs.Classify(err).Message})
+	case errs.InvalidDeliveryChange.Is(err):
+		writeJSON(w, http.StatusUnprocessableEntity, GatewayError{Error: errs.Classify(err).Message})
+	case errors.Is(err, errNotDelivery), errors.As(err, &notFound):
+		writeJSON(w, http.StatusNotFound, GatewayError{Error: "unknown delivery"})
+	case errors.As(err, &invalid):
+		writeJSON(w, http.StatusBadRequest, GatewayError{Error: err.Error()})
+	default:
+		writeJSON(w, http.StatusBadGateway, GatewayError{Error: err.Error()})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	signals "temporal-go-examples/examples/03-signals"
+	"temporal-go-examples/shared"
+)
+
+func main() {
+	addr := flag.String("addr", ":8092", "Address to listen on")
+	poll := flag.Duration("poll", signals.DefaultPollInterval, "How often event streams poll deliveries for changes")
+	flag.Parse()
+
+	// Create Temporal client
+	c, err := shared.CreateTemporalClient()
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	gateway := &signals.Gateway{Client: c, PollInterval: *poll}
+	shared.LogInfo("🌐 Delivery gateway listening on %s/deliveries", *addr)
+	log.Fatalln(http.ListenAndServe(*addr, gateway.Handler()))
+}
@@ -0,0 +1,204 @@
+package signals
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+)
+
+// fakeWorkflow is one workflow the fake Temporal client knows about
+type fakeWorkflow struct {
+	workflowType string
+	status       enumspb.WorkflowExecutionStatus
+	delivery     OrderStatus // What the delivery's updates see
+}
+
+// fakeDeliveryClient records what the gateway sends to Temporal
+type fakeDeliveryClient struct {
+	workflows map[string]*fakeWorkflow
+	signals   []string // "workflowID/signal"
+	queries   []string // "workflowID/query"
+	updates   []string // "workflowID/update", accepted ones only
+}
+
+// fakeUpdate is the outcome of an update: the new status or the validator's error
+type fakeUpdate struct {
+	status OrderStatus
+	err    error
+}
+
+func (u fakeUpdate) WorkflowID() string { return "" }
+func (u fakeUpdate) RunID() string      { return "" }
+func (u fakeUpdate) UpdateID() string   { return "" }
+
+func (u fakeUpdate) Get(ctx context.Context, valuePtr interface{}) error {
+	if u.err != nil {
+		return u.err
+	}
+	*valuePtr.(*OrderStatus) = u.status
+	return nil
+}
+
+// UpdateWorkflow runs the delivery's real validators against the fake's status
+func (f *fakeDeliveryClient) UpdateWorkflow(ctx context.Context, options client.UpdateWorkflowOptions) (client.WorkflowUpdateHandle, error) {
+	wf := f.workflows[options.WorkflowID]
+	arg := options.Args[0].(string)
+	var err error
+	switch options.UpdateName {
+	case UpdateAddressUpdate:
+		if err = wf.delivery.checkAddress(arg); err == nil {
+			wf.delivery.Address = arg
+		}
+	case AddItemUpdate:
+		if err = wf.delivery.checkNewItem(arg); err == nil {
+			wf.delivery.Items = append(wf.delivery.Items, arg)
+		}
+	}
+	if err != nil {
+		return fakeUpdate{err: err}, nil
+	}
+	f.updates = append(f.updates, options.WorkflowID+"/"+options.UpdateName)
+	wf.delivery.Version++
+	return fakeUpdate{status: wf.delivery}, nil
+}
+
+func (f *fakeDeliveryClient) ExecuteWorkflow(ctx context.Context, options client.StartWorkflowOptions, workflow interface{}, args ...interface{}) (client.WorkflowRun, error) {
+	return nil, serviceerror.NewUnavailable("not used by these tests")
+}
+
+func (f *fakeDeliveryClient) SignalWorkflow(ctx context.Context, workflowID string, runID string, signalName string, arg interface{}) error {
+	f.signals = append(f.signals, workflowID+"/"+signalName)
+	return nil
+}
+
+func (f *fakeDeliveryClient) QueryWorkflow(ctx context.Context, workflowID string, runID string, queryType string, args ...interface{}) (converter.EncodedValue, error) {
+	f.queries = append(f.queries, workflowID+"/"+queryType)
+	return nil, serviceerror.NewUnavailable("not used by these tests")
+}
+
+func (f *fakeDeliveryClient) DescribeWorkflowExecution(ctx context.Context, workflowID, runID string) (*workflowservice.DescribeWorkflowExecutionResponse, error) {
+	wf, ok := f.workflows[workflowID]
+	if !ok {
+		return nil, serviceerror.NewNotFound("workflow not found")
+	}
+	return &workflowservice.DescribeWorkflowExecutionResponse{
+		WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{
+			Type:   &commonpb.WorkflowType{Name: wf.workflowType},
+			Status: wf.status,
+		},
+	}, nil
+}
+
+func newTestGateway() (*Gateway, *fakeDeliveryClient) {
+	running := enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING
+	fake := &fakeDeliveryClient{workflows: map[string]*fakeWorkflow{
+		"delivery-1": {"DeliveryWorkflow", running,
+			OrderStatus{Items: []string{"Pizza"}, Address: "123 Main St", Status: StatusPreparing, Version: 1}},
+		// Started with DeliveryOrderWorkflow's item and address arguments
+		"delivery-dispatched": {"DeliveryOrderWorkflow", running,
+			OrderStatus{Items: []string{"Pizza"}, Address: "123 Main St", Status: StatusOutForDelivery, Version: 2}},
+		"delivery-done":           {workflowType: "DeliveryWorkflow", status: enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED},
+		"order-processing-1":      {workflowType: "OrderProcessingWorkflow", status: running},
+		"notification-outbox-o-1": {workflowType: "NotificationOutboxWorkflow", status: running},
+	}}
+	return &Gateway{Client: fake}, fake
+}
+
+func serve(g *Gateway, method, path, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	g.Handler().ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return recorder
+}
+
+func TestGatewaySignalsRunningDelivery(t *testing.T) {
+	g, fake := newTestGateway()
+
+	resp := serve(g, http.MethodPost, "/deliveries/delivery-1/complete", `{"message":"Left at the door"}`)
+
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Equal(t, []string{"delivery-1/" + CompleteOrderSignal}, fake.signals)
+}
+
+func TestGatewayUpdatesAddressAndItems(t *testing.T) {
+	g, fake := newTestGateway()
+
+	resp := serve(g, http.MethodPut, "/deliveries/delivery-1/address", `{"address":"456 Oak Avenue"}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	resp = serve(g, http.MethodPost, "/deliveries/delivery-1/items", `{"item":"Coke"}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	var status OrderStatus
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &status))
+	assert.Equal(t, "456 Oak Avenue", status.Address)
+	assert.Equal(t, []string{"Pizza", "Coke"}, status.Items)
+	assert.Equal(t, 3, status.Version)
+	assert.Empty(t, fake.signals, "changes should go through updates, not signals")
+}
+
+func TestGatewayMapsRejectedChanges(t *testing.T) {
+	for _, test := range []struct {
+		name, method, path, body string
+		code                     int
+	}{
+		{"address after dispatch", http.MethodPut, "/deliveries/delivery-dispatched/address", `{"address":"456 Oak Avenue"}`, http.StatusConflict},
+		{"item after dispatch", http.MethodPost, "/deliveries/delivery-dispatched/items", `{"item":"Coke"}`, http.StatusConflict},
+		{"malformed address", http.MethodPut, "/deliveries/delivery-1/address", `{"address":"Main Street"}`, http.StatusUnprocessableEntity},
+		{"blank item", http.MethodPost, "/deliveries/delivery-1/items", `{"item":"   "}`, http.StatusUnprocessableEntity},
+		{"finished delivery", http.MethodPut, "/deliveries/delivery-done/address", `{"address":"456 Oak Avenue"}`, http.StatusConflict},
+		{"missing field", http.MethodPut, "/deliveries/delivery-1/address", `{}`, http.StatusBadRequest},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			g, fake := newTestGateway()
+
+			resp := serve(g, test.method, test.path, test.body)
+
+			assert.Equal(t, test.code, resp.Code, resp.Body.String())
+			var body GatewayError
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			assert.NotEmpty(t, body.Error)
+			assert.Empty(t, fake.updates)
+		})
+	}
+}
+
+func TestGatewayRejectsFinishedDelivery(t *testing.T) {
+	g, fake := newTestGateway()
+
+	resp := serve(g, http.MethodPost, "/deliveries/delivery-done/complete", `{}`)
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.Empty(t, fake.signals)
+}
+
+func TestGatewayOnlyTouchesDeliveries(t *testing.T) {
+	for _, workflowID := range []string{"order-processing-1", "notification-outbox-o-1", "no-such-workflow"} {
+		t.Run(workflowID, func(t *testing.T) {
+			g, fake := newTestGateway()
+			for _, route := range []struct{ method, path, body string }{
+				{http.MethodGet, "/deliveries/" + workflowID, ""},
+				{http.MethodGet, "/deliveries/" + workflowID + "/events", ""},
+				{http.MethodPost, "/deliveries/" + workflowID + "/complete", `{}`},
+				{http.MethodPut, "/deliveries/" + workflowID + "/address", `{"address":"456 Oak Avenue"}`},
+			} {
+				resp := serve(g, route.method, route.path, route.body)
+				require.Equal(t, http.StatusNotFound, resp.Code, "%s %s", route.method, route.path)
+			}
+			assert.Empty(t, fake.signals)
+			assert.Empty(t, fake.queries)
+			assert.Empty(t, fake.updates)
+		})
+	}
+}
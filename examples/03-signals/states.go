@@ -0,0 +1,60 @@
+package signals
+
+import (
+	"errors"
+
+	"go.temporal.io/sdk/log"
+
+	"temporal-go-examples/shared/statemachine"
+)
+
+// TransitionsQuery returns every status transition so far, oldest first
+const TransitionsQuery = "get-transitions"
+
+// DeliveryState is where a delivery is; it serialises as the plain status string
+type DeliveryState string
+
+// Delivery statuses
+const (
+	StatusPreparing      DeliveryState = "Preparing"
+	StatusOutForDelivery DeliveryState = "Out for Delivery"
+	StatusCompleted      DeliveryState = "Completed"
+)
+
+// StatusTransition is one entry of the get-transitions query
+type StatusTransition = statemachine.Transition[DeliveryState]
+
+// newDeliveryMachine declares which status changes a delivery allows
+//
+//	Preparing -> Out for Delivery   timer, only with items and an address
+//	Preparing -> Completed          complete-order signal (picked up)
+//	Out for Delivery -> Completed   timer or complete-order signal
+//
+// Completed has no way out. Every transition writes the new state into status,
+// so queries, updates and the parent always see the machine's state
+func newDeliveryMachine(status *OrderStatus, logger log.Logger) *statemachine.Machine[DeliveryState] {
+	machine := statemachine.New(StatusPreparing).
+		Allow(StatusPreparing, StatusOutForDelivery, StatusCompleted).
+		Allow(StatusOutForDelivery, StatusCompleted)
+
+	machine.Guard(StatusPreparing, StatusOutForDelivery, func() error {
+		if len(status.Items) == 0 {
+			return errors.New("order has no items")
+		}
+		if status.Address == "" {
+			return errors.New("order has no delivery address")
+		}
+		return nil
+	})
+
+	machine.OnTransition(func(t StatusTransition) {
+		status.Status = t.To
+	})
+	machine.OnEnter(StatusOutForDelivery, func(from DeliveryState) {
+		logger.Info("Order dispatched", "address", status.Address, "items", len(status.Items))
+	})
+	machine.OnEnter(StatusCompleted, func(from DeliveryState) {
+		logger.Info("Order delivered", "from", from)
+	})
+	return machine
+}
@@ -0,0 +1,75 @@
+package signals
+
+import (
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/log"
+
+	"temporal-go-examples/shared/statemachine"
+)
+
+// deliveryMoves is every status change a delivery allows
+var deliveryMoves = map[DeliveryState][]DeliveryState{
+	StatusPreparing:      {StatusOutForDelivery, StatusCompleted},
+	StatusOutForDelivery: {StatusCompleted},
+}
+
+// machineAt returns a delivery machine moved along legal transitions to state
+func machineAt(t *testing.T, status *OrderStatus, state DeliveryState) *statemachine.Machine[DeliveryState] {
+	machine := newDeliveryMachine(status, log.NewStructuredLogger(slog.New(slog.DiscardHandler)))
+	path := map[DeliveryState][]DeliveryState{
+		StatusPreparing:      nil,
+		StatusOutForDelivery: {StatusOutForDelivery},
+		StatusCompleted:      {StatusOutForDelivery, StatusCompleted},
+	}[state]
+	for _, to := range path {
+		require.NoError(t, machine.Move(to, "setup", time.Time{}))
+	}
+	return machine
+}
+
+func TestDeliveryMachineRejectsIllegalTransitions(t *testing.T) {
+	states := []DeliveryState{StatusPreparing, StatusOutForDelivery, StatusCompleted}
+	status := &OrderStatus{Items: []string{"Pizza"}, Address: "123 Main St", Status: StatusPreparing}
+	require.ElementsMatch(t, states, machineAt(t, status, StatusPreparing).States())
+
+	for _, from := range states {
+		for _, to := range states {
+			status := &OrderStatus{Items: []string{"Pizza"}, Address: "123 Main St", Status: StatusPreparing}
+			machine := machineAt(t, status, from)
+			assert.ElementsMatch(t, deliveryMoves[from], machine.Allowed(from), "from %s", from)
+
+			err := machine.Move(to, "test", time.Time{})
+			if slices.Contains(deliveryMoves[from], to) {
+				require.NoError(t, err, "%s -> %s", from, to)
+				assert.Equal(t, to, status.Status)
+				continue
+			}
+			require.ErrorIs(t, err, statemachine.ErrIllegalTransition, "%s -> %s", from, to)
+			assert.Equal(t, from, machine.Current())
+			assert.Equal(t, from, status.Status, "a rejected move should not touch the status")
+		}
+	}
+}
+
+func TestDeliveryMachineGuardsDispatch(t *testing.T) {
+	for _, status := range []*OrderStatus{
+		{Address: "123 Main St", Status: StatusPreparing},
+		{Items: []string{"Pizza"}, Status: StatusPreparing},
+	} {
+		machine := machineAt(t, status, StatusPreparing)
+
+		err := machine.Move(StatusOutForDelivery, "timer", time.Time{})
+
+		require.ErrorIs(t, err, statemachine.ErrGuardRejected)
+		assert.Equal(t, StatusPreparing, status.Status)
+		assert.Empty(t, machine.History())
+		// Picking the order up is still allowed
+		assert.NoError(t, machine.Can(StatusCompleted))
+	}
+}
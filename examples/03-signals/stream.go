@@ -0,0 +1,165 @@
+package signals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+)
+
+// Event stream timing defaults
+const (
+	DefaultPollInterval = time.Millisecond * 500
+	keepAliveInterval   = time.Second * 15
+	idleChecks          = 20 // Empty polls between checks that the workflow is still running
+	maxPollFailures     = 5
+)
+
+// streamStatus serves GET /deliveries/{id}/events as Server-Sent Events
+//
+// It long-polls StatusSinceQuery and sends every new status version as a
+// "status" event whose id is the version. A client that reconnects with the
+// Last-Event-ID header (or ?since=<version>) gets every version after that
+// one, so it never misses a transition even if several happened while it was
+// away. An "end" event follows the final status
+func (g *Gateway) streamStatus(w http.ResponseWriter, req *http.Request) {
+	workflowID := req.PathValue("id")
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, GatewayError{Error: "streaming not supported"})
+		return
+	}
+	since, err := lastSeenVersion(req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, GatewayError{Error: err.Error()})
+		return
+	}
+
+	// The first poll happens before the headers go out, so an unknown delivery is still a 404
+	ctx := req.Context()
+	if _, err := g.describeDelivery(ctx, workflowID); err != nil {
+		writeError(w, err)
+		return
+	}
+	statuses, err := g.statusesSince(ctx, workflowID, since)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	interval := g.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastWrite := time.Now()
+	idle, failures := 0, 0
+	for {
+		for _, status := range statuses {
+			writeEvent(w, "status", strconv.Itoa(status.Version), status)
+			if status.Final() {
+				writeEvent(w, "end", "", map[string]string{"workflow_id": workflowID})
+				flusher.Flush()
+				return
+			}
+		}
+		if len(statuses) > 0 {
+			since = statuses[len(statuses)-1].Version
+			lastWrite, idle = time.Now(), 0
+		} else if time.Since(lastWrite) > keepAliveInterval {
+			// A comment line keeps proxies from closing an idle connection
+			fmt.Fprint(w, ": keep-alive\n\n")
+			lastWrite = time.Now()
+		}
+		flusher.Flush()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// A workflow that was terminated never reaches a final status
+		if len(statuses) == 0 {
+			if idle++; idle >= idleChecks {
+				idle = 0
+				if !g.running(ctx, workflowID) {
+					writeEvent(w, "end", "", map[string]string{"workflow_id": workflowID, "reason": "workflow closed"})
+					flusher.Flush()
+					return
+				}
+			}
+		}
+
+		statuses, err = g.statusesSince(ctx, workflowID, since)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if failures++; failures >= maxPollFailures {
+				writeEvent(w, "error", "", GatewayError{Error: err.Error()})
+				flusher.Flush()
+				return
+			}
+			statuses = nil
+			continue
+		}
+		failures = 0
+	}
+}
+
+// statusesSince runs StatusSinceQuery
+func (g *Gateway) statusesSince(ctx context.Context, workflowID string, since int) ([]OrderStatus, error) {
+	value, err := g.Client.QueryWorkflow(ctx, workflowID, "", StatusSinceQuery, since)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []OrderStatus
+	err = value.Get(&statuses)
+	return statuses, err
+}
+
+// running reports whether the workflow is still running; errors count as running
+func (g *Gateway) running(ctx context.Context, workflowID string) bool {
+	description, err := g.Client.DescribeWorkflowExecution(ctx, workflowID, "")
+	if err != nil {
+		return true
+	}
+	return description.GetWorkflowExecutionInfo().GetStatus() == enumspb.WORKFLOW_EXECUTION_STATUS_RUNNING
+}
+
+// lastSeenVersion reads the Last-Event-ID header browsers send on reconnect, or ?since=
+func lastSeenVersion(req *http.Request) (int, error) {
+	value := req.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = req.URL.Query().Get("since")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid last event ID %q", value)
+	}
+	return version, nil
+}
+
+// writeEvent writes one Server-Sent Event with a JSON data line
+func writeEvent(w http.ResponseWriter, event, id string, data interface{}) {
+	body, _ := json.Marshal(data)
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, body)
+}
@@ -0,0 +1,159 @@
+package signals
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/workflow"
+
+	"temporal-go-examples/shared/errs"
+)
+
+// Update names
+// Unlike the signals, updates tell the caller whether the change was accepted
+// and return the new status
+const (
+	UpdateAddressUpdate = "UpdateAddress"
+	AddItemUpdate       = "AddItem"
+	RemoveItemUpdate    = "RemoveItem"
+)
+
+// setUpdateHandlers registers the delivery's update handlers
+// Validators run before anything is written to history; a rejected update
+// leaves no trace and the caller gets the validator's error. Handlers change
+// the status, record a new version and return it
+func setUpdateHandlers(ctx workflow.Context, status *OrderStatus, record func() OrderStatus) error {
+	logger := workflow.GetLogger(ctx)
+
+	err := workflow.SetUpdateHandlerWithOptions(ctx, UpdateAddressUpdate,
+		func(ctx workflow.Context, address string) (OrderStatus, error) {
+			status.Address = strings.TrimSpace(address)
+			logger.Info("Address updated", "newAddress", status.Address)
+			return record(), nil
+		},
+		workflow.UpdateHandlerOptions{Validator: func(ctx workflow.Context, address string) error {
+			return status.checkAddress(address)
+		}},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = workflow.SetUpdateHandlerWithOptions(ctx, AddItemUpdate,
+		func(ctx workflow.Context, item string) (OrderStatus, error) {
+			status.Items = append(status.Items, strings.TrimSpace(item))
+			logger.Info("Item added to order", "item", item, "totalItems", len(status.Items))
+			return record(), nil
+		},
+		workflow.UpdateHandlerOptions{Validator: func(ctx workflow.Context, item string) error {
+			return status.checkNewItem(item)
+		}},
+	)
+	if err != nil {
+		return err
+	}
+
+	return workflow.SetUpdateHandlerWithOptions(ctx, RemoveItemUpdate,
+		func(ctx workflow.Context, item string) (OrderStatus, error) {
+			// Items are stored trimmed, as AddItem adds them
+			item = strings.TrimSpace(item)
+			i := slices.Index(status.Items, item)
+			if i == -1 {
+				return OrderStatus{}, errs.InvalidDeliveryChange.New(fmt.Sprintf("item %q is not in the order", item))
+			}
+			status.Items = slices.Delete(status.Items, i, i+1)
+			logger.Info("Item removed from order", "item", item, "totalItems", len(status.Items))
+			return record(), nil
+		},
+		workflow.UpdateHandlerOptions{Validator: func(ctx workflow.Context, item string) error {
+			if err := status.checkChangeable(); err != nil {
+				return err
+			}
+			item = strings.TrimSpace(item)
+			if !slices.Contains(status.Items, item) {
+				return errs.InvalidDeliveryChange.New(fmt.Sprintf("item %q is not in the order", item))
+			}
+			if len(status.Items) == 1 {
+				return errs.InvalidDeliveryChange.New("cannot remove the last item; cancel the order instead")
+			}
+			return nil
+		}},
+	)
+}
+
+// checkChangeable rejects changes once the order has been dispatched
+func (s OrderStatus) checkChangeable() error {
+	if s.Status != StatusPreparing {
+		return errs.DeliveryDispatched.New(fmt.Sprintf("order is already %s and can no longer be changed", strings.ToLower(string(s.Status))))
+	}
+	return nil
+}
+
+// checkAddress validates an address change; the update and the signal both use it
+func (s OrderStatus) checkAddress(address string) error {
+	if err := s.checkChangeable(); err != nil {
+		return err
+	}
+	return validateAddress(address)
+}
+
+// checkNewItem validates an added item; the update and the signal both use it
+func (s OrderStatus) checkNewItem(item string) error {
+	if err := s.checkChangeable(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(item) == "" {
+		return errs.InvalidDeliveryChange.New("item cannot be empty")
+	}
+	return nil
+}
+
+// validateAddress accepts addresses that look deliverable: a house number and a street
+func validateAddress(address string) error {
+	address = strings.TrimSpace(address)
+	if len(address) < 5 || len(address) > 200 {
+		return errs.InvalidDeliveryChange.New("address must be between 5 and 200 characters")
+	}
+	if !strings.ContainsFunc(address, unicode.IsDigit) || !strings.ContainsFunc(address, unicode.IsLetter) {
+		return errs.InvalidDeliveryChange.New(fmt.Sprintf("address %q needs a house number and a street", address))
+	}
+	return nil
+}
+
+// UpdateAddress changes a delivery's address and waits for the result
+// It returns the new status, or the validator's error if the change was rejected
+func UpdateAddress(ctx context.Context, c client.Client, workflowID, address string) (OrderStatus, error) {
+	return update(ctx, c, workflowID, UpdateAddressUpdate, address)
+}
+
+// AddItem adds an item to a delivery and waits for the result
+func AddItem(ctx context.Context, c client.Client, workflowID, item string) (OrderStatus, error) {
+	return update(ctx, c, workflowID, AddItemUpdate, item)
+}
+
+// RemoveItem removes an item from a delivery and waits for the result
+func RemoveItem(ctx context.Context, c client.Client, workflowID, item string) (OrderStatus, error) {
+	return update(ctx, c, workflowID, RemoveItemUpdate, item)
+}
+
+// update sends an update and blocks until the workflow has handled it
+// A rejected change comes back as the validator's error: DeliveryDispatched
+// or InvalidDeliveryChange from shared/errs
+func update(ctx context.Context, c DeliveryClient, workflowID, updateName, arg string) (OrderStatus, error) {
+	handle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+		WorkflowID:   workflowID,
+		UpdateName:   updateName,
+		Args:         []interface{}{arg},
+		WaitForStage: client.WorkflowUpdateStageCompleted,
+	})
+	if err != nil {
+		return OrderStatus{}, err
+	}
+	var status OrderStatus
+	err = handle.Get(ctx, &status)
+	return status, err
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	signals "temporal-go-examples/examples/03-signals"
+	"temporal-go-examples/shared"
+)
+
+func main() {
+	gateway := flag.String("gateway", "http://localhost:8092", "Delivery gateway URL")
+	workflowID := flag.String("workflow-id", "", "Delivery workflow to watch")
+	flag.Parse()
+	if *workflowID == "" {
+		log.Fatalln("-workflow-id is required")
+	}
+
+	// Reconnect until the stream ends, resuming after the last version seen
+	lastID := ""
+	for {
+		done, err := watch(*gateway+"/deliveries/"+*workflowID+"/events", &lastID)
+		if done {
+			return
+		}
+		shared.LogError("Stream interrupted (%v); reconnecting after version %q", err, lastID)
+		time.Sleep(time.Second * 2)
+	}
+}
+
+// watch reads one connection's events; it returns true once the stream has ended
+func watch(url string, lastID *string) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if *lastID != "" {
+		req.Header.Set("Last-Event-ID", *lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var body signals.GatewayError
+		json.NewDecoder(resp.Body).Decode(&body)
+		log.Fatalf("Gateway returned %d: %s", resp.StatusCode, body.Error)
+	}
+
+	var event, id, data string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "":
+			// A blank line ends an event
+			switch event {
+			case "status":
+				var status signals.OrderStatus
+				json.Unmarshal([]byte(data), &status)
+				shared.LogInfo("📦 v%d %s, items: %v, address: %s", status.Version, status.Status, status.Items, status.Address)
+				*lastID = id
+			case "end":
+				shared.LogInfo("🏁 Delivery finished")
+				return true, nil
+			case "error":
+				shared.LogError("Gateway error: %s", data)
+			}
+			event, id, data = "", "", ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, err
+	}
+	return false, fmt.Errorf("connection closed")
+}
@@ -18,12 +18,14 @@ func main() {
 	// Create worker
 	w := shared.CreateTemporalWorker(c)
 
-	// Register workflow
+	// Register workflows
+	// DeliveryOrderWorkflow stays registered for deliveries started with its item and address arguments
+	w.RegisterWorkflow(signals.DeliveryWorkflow)
 	w.RegisterWorkflow(signals.DeliveryOrderWorkflow)
 
 	// Start the worker
 	shared.LogInfo("Worker is starting...")
-	shared.LogInfo("Registered workflow: DeliveryOrderWorkflow")
+	shared.LogInfo("Registered workflows: DeliveryWorkflow, DeliveryOrderWorkflow")
 	shared.LogInfo("This workflow supports signals: add-item, update-address, complete-order")
 	shared.LogInfo("This workflow supports queries: get-status")
 	shared.LogInfo("Press Ctrl+C to stop the worker")
@@ -2,45 +2,157 @@ package signals
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"go.temporal.io/sdk/workflow"
 )
 
+// Signal and query names
+const (
+	AddItemSignal       = "add-item"
+	UpdateAddressSignal = "update-address"
+	CompleteOrderSignal = "complete-order"
+	StatusQuery         = "get-status"
+
+	// StatusSinceQuery returns every status version after the one given, oldest first
+	StatusSinceQuery = "get-status-since"
+
+	// DeliveryStatusSignal is sent to the parent workflow, if any, whenever the status changes
+	DeliveryStatusSignal = "delivery-status"
+)
+
 // OrderStatus represents the current state of an order
 type OrderStatus struct {
-	Items   []string `json:"items"`
-	Address string   `json:"address"`
-	Status  string   `json:"status"`
+	OrderID string        `json:"order_id,omitempty"`
+	Items   []string      `json:"items"`
+	Address string        `json:"address"`
+	Status  DeliveryState `json:"status"`
+	// Version goes up by one with every change, so watchers can tell which
+	// changes they have already seen
+	Version int `json:"version"`
+}
+
+// Final reports whether the delivery is over; no later status will follow
+func (s OrderStatus) Final() bool {
+	return s.Status == StatusCompleted
+}
+
+// DeliveryRequest is what a DeliveryWorkflow starts with
+// FulfillmentWorkflow (examples/05-fulfillment) hands one over once an order is paid
+type DeliveryRequest struct {
+	OrderID   string   `json:"order_id,omitempty"`
+	PaymentID string   `json:"payment_id,omitempty"`
+	Items     []string `json:"items"`
+	Address   string   `json:"address"`
 }
 
 // DeliveryOrderWorkflow demonstrates signals and queries
-// This workflow can receive updates while running
+// This workflow can receive updates while running. It keeps the item and
+// address arguments it has always taken, so executions started with them
+// still decode; new deliveries start DeliveryWorkflow with a DeliveryRequest
 func DeliveryOrderWorkflow(ctx workflow.Context, initialItem string, address string) (string, error) {
+	return runDelivery(ctx, DeliveryRequest{Items: []string{initialItem}, Address: address})
+}
+
+// DeliveryWorkflow is DeliveryOrderWorkflow started from a DeliveryRequest,
+// which can carry several items and the order and payment it delivers
+func DeliveryWorkflow(ctx workflow.Context, request DeliveryRequest) (string, error) {
+	return runDelivery(ctx, request)
+}
+
+// runDelivery is the body both delivery workflow types share
+func runDelivery(ctx workflow.Context, request DeliveryRequest) (string, error) {
 	logger := workflow.GetLogger(ctx)
-	logger.Info("DeliveryOrderWorkflow started", "initialItem", initialItem, "address", address)
+	workflowType := workflow.GetInfo(ctx).WorkflowType.Name
+	logger.Info(workflowType+" started", "items", request.Items, "address", request.Address)
 
 	// Initialize order status
 	orderStatus := OrderStatus{
-		Items:   []string{initialItem},
-		Address: address,
-		Status:  "Preparing",
+		OrderID: request.OrderID,
+		Items:   append([]string(nil), request.Items...),
+		Address: request.Address,
+		Status:  StatusPreparing,
+	}
+
+	// Status changes only go through the state machine, which rejects
+	// anything not declared in newDeliveryMachine (see states.go)
+	machine := newDeliveryMachine(&orderStatus, logger)
+	move := func(to DeliveryState, reason string) {
+		if err := machine.Move(to, reason, workflow.Now(ctx)); err != nil {
+			logger.Warn("Status change rejected", "error", err)
+		}
+	}
+
+	// Every change gets the next version, and history keeps each version so a
+	// watcher that falls behind can catch up with StatusSinceQuery
+	var history []OrderStatus
+	record := func() OrderStatus {
+		orderStatus.Version++
+		snapshot := orderStatus
+		snapshot.Items = append([]string(nil), orderStatus.Items...)
+		history = append(history, snapshot)
+		reportStatus(ctx, snapshot)
+		return snapshot
 	}
 
 	// Set up signal channels
-	addItemSignal := workflow.GetSignalChannel(ctx, "add-item")
-	updateAddressSignal := workflow.GetSignalChannel(ctx, "update-address")
-	completeOrderSignal := workflow.GetSignalChannel(ctx, "complete-order")
+	addItemSignal := workflow.GetSignalChannel(ctx, AddItemSignal)
+	updateAddressSignal := workflow.GetSignalChannel(ctx, UpdateAddressSignal)
+	completeOrderSignal := workflow.GetSignalChannel(ctx, CompleteOrderSignal)
 
 	// Set up query handler - clients can query the current status
-	err := workflow.SetQueryHandler(ctx, "get-status", func() (OrderStatus, error) {
+	err := workflow.SetQueryHandler(ctx, StatusQuery, func() (OrderStatus, error) {
 		return orderStatus, nil
 	})
 	if err != nil {
 		return "", err
 	}
+	err = workflow.SetQueryHandler(ctx, StatusSinceQuery, func(sinceVersion int) ([]OrderStatus, error) {
+		if sinceVersion < 0 {
+			sinceVersion = 0
+		}
+		if sinceVersion >= len(history) {
+			return []OrderStatus{}, nil
+		}
+		// Versions start at 1, so version v is history[v-1]
+		return history[sinceVersion:], nil
+	})
+	if err != nil {
+		return "", err
+	}
+	err = workflow.SetQueryHandler(ctx, TransitionsQuery, func() ([]StatusTransition, error) {
+		return machine.History(), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Updates: like the signals, but validated and answered with the new status
+	if err := setUpdateHandlers(ctx, &orderStatus, record); err != nil {
+		return "", err
+	}
 
 	logger.Info("Order initialized", "status", orderStatus)
+	record()
+
+	// A wakeup only gets a new version when it changed something, so a timer
+	// whose move the guard refused doesn't signal the parent every 10 seconds.
+	// Runs started before this recorded every wakeup; GetVersion keeps their
+	// replays doing so
+	recordChangesOnly := workflow.GetVersion(ctx, "record-changes-only", workflow.DefaultVersion, 1) != workflow.DefaultVersion
+	changed := func() bool {
+		last := history[len(history)-1]
+		return last.Status != orderStatus.Status || last.Address != orderStatus.Address ||
+			!slices.Equal(last.Items, orderStatus.Items)
+	}
+
+	// Signals get the same checks as the updates, but can't report a
+	// rejection: a change after dispatch or a malformed one is logged and
+	// dropped. Runs started before this applied every signal; GetVersion keeps
+	// their replays doing so
+	validateSignals := workflow.GetVersion(ctx, "validate-signals", workflow.DefaultVersion, 1) != workflow.DefaultVersion
 
 	// Main workflow loop - wait for signals
 	for {
@@ -50,6 +162,13 @@ func DeliveryOrderWorkflow(ctx workflow.Context, initialItem string, address str
 		selector.AddReceive(addItemSignal, func(c workflow.ReceiveChannel, more bool) {
 			var newItem string
 			c.Receive(ctx, &newItem)
+			if validateSignals {
+				if err := orderStatus.checkNewItem(newItem); err != nil {
+					logger.Warn("Item signal rejected", "item", newItem, "error", err)
+					return
+				}
+				newItem = strings.TrimSpace(newItem)
+			}
 			orderStatus.Items = append(orderStatus.Items, newItem)
 			logger.Info("Item added to order", "item", newItem, "totalItems", len(orderStatus.Items))
 		})
@@ -58,6 +177,13 @@ func DeliveryOrderWorkflow(ctx workflow.Context, initialItem string, address str
 		selector.AddReceive(updateAddressSignal, func(c workflow.ReceiveChannel, more bool) {
 			var newAddress string
 			c.Receive(ctx, &newAddress)
+			if validateSignals {
+				if err := orderStatus.checkAddress(newAddress); err != nil {
+					logger.Warn("Address signal rejected", "address", newAddress, "error", err)
+					return
+				}
+				newAddress = strings.TrimSpace(newAddress)
+			}
 			orderStatus.Address = newAddress
 			logger.Info("Address updated", "newAddress", newAddress)
 		})
@@ -66,32 +192,54 @@ func DeliveryOrderWorkflow(ctx workflow.Context, initialItem string, address str
 		selector.AddReceive(completeOrderSignal, func(c workflow.ReceiveChannel, more bool) {
 			var message string
 			c.Receive(ctx, &message)
-			orderStatus.Status = "Completed"
 			logger.Info("Order completion signal received", "message", message)
+			move(StatusCompleted, CompleteOrderSignal)
 		})
 
 		// Add a timeout to automatically progress the order
 		selector.AddFuture(workflow.NewTimer(ctx, time.Second*10), func(f workflow.Future) {
-			if orderStatus.Status == "Preparing" {
-				orderStatus.Status = "Out for Delivery"
-				logger.Info("Order status updated", "status", orderStatus.Status)
-			} else if orderStatus.Status == "Out for Delivery" {
-				orderStatus.Status = "Completed"
-				logger.Info("Order delivered automatically")
+			switch machine.Current() {
+			case StatusPreparing:
+				move(StatusOutForDelivery, "timer")
+			case StatusOutForDelivery:
+				move(StatusCompleted, "timer")
 			}
 		})
 
 		// Wait for one of the above to happen
 		selector.Select(ctx)
+		if !recordChangesOnly || changed() {
+			record()
+		}
 
 		// Exit if order is completed
-		if orderStatus.Status == "Completed" {
+		if orderStatus.Final() {
 			break
 		}
 	}
 
+	// An update handler accepted before the order completed may still be
+	// reporting its status to the parent; let it finish and reply first
+	if err := workflow.Await(ctx, func() bool { return workflow.AllHandlersFinished(ctx) }); err != nil {
+		return "", err
+	}
+
 	result := fmt.Sprintf("Order completed! Items: %v, Delivered to: %s",
 		orderStatus.Items, orderStatus.Address)
-	logger.Info("DeliveryOrderWorkflow completed", "result", result)
+	logger.Info(workflowType+" completed", "result", result)
 	return result, nil
 }
+
+// reportStatus sends the status to the parent workflow, so a parent such as
+// FulfillmentWorkflow can answer queries about the whole order
+// Workflows cannot query each other; signalling the parent is how it finds out
+func reportStatus(ctx workflow.Context, status OrderStatus) {
+	parent := workflow.GetInfo(ctx).ParentWorkflowExecution
+	if parent == nil {
+		return
+	}
+	err := workflow.SignalExternalWorkflow(ctx, parent.ID, "", DeliveryStatusSignal, status).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Unable to report status to parent workflow", "parentWorkflowID", parent.ID, "error", err)
+	}
+}
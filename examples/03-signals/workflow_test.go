@@ -0,0 +1,217 @@
+package signals
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+
+	"temporal-go-examples/shared/errs"
+)
+
+func newDeliveryEnv() *testsuite.TestWorkflowEnvironment {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(DeliveryWorkflow)
+	env.RegisterWorkflow(DeliveryOrderWorkflow)
+	return env
+}
+
+// queryStatus runs the get-status query; call it from a delayed callback
+func queryStatus(t *testing.T, env *testsuite.TestWorkflowEnvironment) OrderStatus {
+	value, err := env.QueryWorkflow(StatusQuery)
+	require.NoError(t, err)
+	var status OrderStatus
+	require.NoError(t, value.Get(&status))
+	return status
+}
+
+func TestDeliveryDoesNotRecordRefusedMoves(t *testing.T) {
+	env := newDeliveryEnv()
+
+	// Without an address the guard refuses every timer's dispatch
+	var waiting OrderStatus
+	env.RegisterDelayedCallback(func() {
+		waiting = queryStatus(t, env)
+		env.SignalWorkflow(CompleteOrderSignal, "Picked up")
+	}, time.Minute)
+	env.ExecuteWorkflow(DeliveryWorkflow, DeliveryRequest{Items: []string{"Pizza"}})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	assert.Equal(t, StatusPreparing, waiting.Status)
+	assert.Equal(t, 1, waiting.Version, "refused moves should not add versions")
+
+	value, err := env.QueryWorkflow(StatusSinceQuery, 0)
+	require.NoError(t, err)
+	var versions []OrderStatus
+	require.NoError(t, value.Get(&versions))
+	require.Len(t, versions, 2)
+	assert.Equal(t, StatusCompleted, versions[1].Status)
+}
+
+func TestDeliveryOrderWorkflowKeepsItsArguments(t *testing.T) {
+	env := newDeliveryEnv()
+
+	// Executions started before DeliveryWorkflow pass the first item and the address
+	env.ExecuteWorkflow(DeliveryOrderWorkflow, "Pizza", "123 Main St")
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var result string
+	require.NoError(t, env.GetWorkflowResult(&result))
+	assert.Equal(t, "Order completed! Items: [Pizza], Delivered to: 123 Main St", result)
+}
+
+func TestDeliveryRecordsEachChange(t *testing.T) {
+	env := newDeliveryEnv()
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(AddItemSignal, "Coke")
+	}, time.Second)
+	env.ExecuteWorkflow(DeliveryWorkflow, DeliveryRequest{Items: []string{"Pizza"}, Address: "123 Main St"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	value, err := env.QueryWorkflow(StatusSinceQuery, 0)
+	require.NoError(t, err)
+	var versions []OrderStatus
+	require.NoError(t, value.Get(&versions))
+
+	var states []DeliveryState
+	for i, version := range versions {
+		assert.Equal(t, i+1, version.Version)
+		states = append(states, version.Status)
+	}
+	// Started, item added, dispatched by the timer, delivered by the timer
+	assert.Equal(t, []DeliveryState{StatusPreparing, StatusPreparing, StatusOutForDelivery, StatusCompleted}, states)
+	assert.Equal(t, []string{"Pizza", "Coke"}, versions[1].Items)
+}
+
+// updateCallback collects the outcome of an update sent with env.UpdateWorkflow
+type updateCallback struct {
+	status OrderStatus
+	err    error
+}
+
+func (u *updateCallback) Accept()          {}
+func (u *updateCallback) Reject(err error) { u.err = err }
+func (u *updateCallback) Complete(success interface{}, err error) {
+	u.err = err
+	if status, ok := success.(OrderStatus); ok {
+		u.status = status
+	}
+}
+
+func TestDeliveryValidatesSignalsLikeUpdates(t *testing.T) {
+	env := newDeliveryEnv()
+
+	var beforeDispatch, afterDispatch OrderStatus
+	lateUpdate := &updateCallback{}
+	env.RegisterDelayedCallback(func() {
+		// Malformed changes are dropped
+		env.SignalWorkflow(AddItemSignal, "   ")
+		env.SignalWorkflow(UpdateAddressSignal, "Main Street")
+	}, time.Second)
+	env.RegisterDelayedCallback(func() {
+		beforeDispatch = queryStatus(t, env)
+	}, 2*time.Second)
+	env.RegisterDelayedCallback(func() {
+		// The timer dispatched the order at 10s; changes are too late now
+		env.SignalWorkflow(UpdateAddressSignal, "456 Oak Avenue")
+		env.UpdateWorkflow(UpdateAddressUpdate, "late-address", lateUpdate, "456 Oak Avenue")
+	}, 12*time.Second)
+	env.RegisterDelayedCallback(func() {
+		afterDispatch = queryStatus(t, env)
+	}, 13*time.Second)
+	env.ExecuteWorkflow(DeliveryWorkflow, DeliveryRequest{Items: []string{"Pizza"}, Address: "123 Main St"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	assert.Equal(t, []string{"Pizza"}, beforeDispatch.Items)
+	assert.Equal(t, "123 Main St", beforeDispatch.Address)
+	assert.Equal(t, 1, beforeDispatch.Version, "rejected signals should not add versions")
+
+	assert.Equal(t, StatusOutForDelivery, afterDispatch.Status)
+	assert.Equal(t, "123 Main St", afterDispatch.Address)
+	assert.Equal(t, 2, afterDispatch.Version)
+	require.Error(t, lateUpdate.err)
+	assert.True(t, errs.DeliveryDispatched.Is(lateUpdate.err), lateUpdate.err.Error())
+}
+
+func queryTransitions(t *testing.T, env *testsuite.TestWorkflowEnvironment) []StatusTransition {
+	value, err := env.QueryWorkflow(TransitionsQuery)
+	require.NoError(t, err)
+	var transitions []StatusTransition
+	require.NoError(t, value.Get(&transitions))
+	return transitions
+}
+
+func TestDeliveryTransitionsQuery(t *testing.T) {
+	env := newDeliveryEnv()
+	start := env.Now().UTC()
+
+	// The guard refuses the first timer's dispatch until there is an address
+	var waiting []StatusTransition
+	env.RegisterDelayedCallback(func() {
+		waiting = queryTransitions(t, env)
+		env.SignalWorkflow(UpdateAddressSignal, "123 Main St")
+	}, 15*time.Second)
+	env.ExecuteWorkflow(DeliveryWorkflow, DeliveryRequest{Items: []string{"Pizza"}})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	assert.Empty(t, waiting, "refused moves should not be recorded")
+
+	// The signal restarted the timer: dispatched 10s after it, delivered 10s later
+	transitions := queryTransitions(t, env)
+	assert.Equal(t, []StatusTransition{
+		{From: StatusPreparing, To: StatusOutForDelivery, Reason: "timer", At: start.Add(25 * time.Second)},
+		{From: StatusOutForDelivery, To: StatusCompleted, Reason: "timer", At: start.Add(35 * time.Second)},
+	}, transitions)
+}
+
+func TestDeliveryTransitionsQueryAfterPickup(t *testing.T) {
+	env := newDeliveryEnv()
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(CompleteOrderSignal, "Picked up")
+	}, time.Second)
+	env.ExecuteWorkflow(DeliveryWorkflow, DeliveryRequest{Items: []string{"Pizza"}, Address: "123 Main St"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	transitions := queryTransitions(t, env)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, StatusPreparing, transitions[0].From)
+	assert.Equal(t, StatusCompleted, transitions[0].To)
+	assert.Equal(t, CompleteOrderSignal, transitions[0].Reason)
+}
+
+func TestDeliveryRemoveItemTrimsLikeAddItem(t *testing.T) {
+	env := newDeliveryEnv()
+
+	added, removed, unknown := &updateCallback{}, &updateCallback{}, &updateCallback{}
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(AddItemUpdate, "add-coke", added, "  Coke ")
+	}, time.Second)
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(RemoveItemUpdate, "remove-coke", removed, "Coke\t")
+		env.UpdateWorkflow(RemoveItemUpdate, "remove-salad", unknown, "Salad")
+	}, 2*time.Second)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(CompleteOrderSignal, "Picked up")
+	}, 3*time.Second)
+	env.ExecuteWorkflow(DeliveryWorkflow, DeliveryRequest{Items: []string{"Pizza"}, Address: "123 Main St"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.NoError(t, added.err)
+	assert.Equal(t, []string{"Pizza", "Coke"}, added.status.Items)
+	require.NoError(t, removed.err)
+	assert.Equal(t, []string{"Pizza"}, removed.status.Items)
+	require.Error(t, unknown.err)
+	assert.True(t, errs.InvalidDeliveryChange.Is(unknown.err), unknown.err.Error())
+}
@@ -7,9 +7,15 @@ import (
 	"time"
 
 	"go.temporal.io/sdk/activity"
-	"go.temporal.io/sdk/temporal"
+
+	"temporal-go-examples/shared/circuit"
+	"temporal-go-examples/shared/errs"
 )
 
+// CreditBreaker guards the credit service; workers may replace its store with a
+// shared circuit.FileStore so all of them see the same circuit
+var CreditBreaker = circuit.New("CreditAccount", circuit.NewMemoryStore(), circuit.DefaultConfig())
+
 // ValidateAccounts checks if both accounts exist and are valid
 func ValidateAccounts(ctx context.Context, fromAccount, toAccount string) error {
 	logger := activity.GetLogger(ctx)
@@ -20,15 +26,15 @@ func ValidateAccounts(ctx context.Context, fromAccount, toAccount string) error
 
 	// Simulate validation failures
 	if fromAccount == "invalid-account" {
-		return temporal.NewNonRetryableApplicationError("account not found", "InvalidAccount", nil)
+		return errs.InvalidAccount.New("account not found", fromAccount)
 	}
 	if toAccount == "invalid-account" {
-		return temporal.NewNonRetryableApplicationError("account not found", "InvalidAccount", nil)
+		return errs.InvalidAccount.New("account not found", toAccount)
 	}
 
 	// Simulate temporary service issues (will be retried)
 	if rand.Float32() < 0.2 {
-		return fmt.Errorf("account service temporarily unavailable")
+		return errs.ServiceUnavailable.New("account service temporarily unavailable")
 	}
 
 	logger.Info("Account validation successful")
@@ -36,65 +42,139 @@ func ValidateAccounts(ctx context.Context, fromAccount, toAccount string) error
 }
 
 // DebitAccount withdraws money from an account
-func DebitAccount(ctx context.Context, account string, amount float64, reference string) (string, error) {
+func DebitAccount(ctx context.Context, account string, amount float64, currency string, reference string) (string, error) {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Debiting account", "account", account, "amount", amount)
+	logger.Info("Debiting account", "account", account, "amount", amount, "currency", currency)
 
 	// Simulate processing time
 	time.Sleep(time.Millisecond * 200)
 
 	// Simulate insufficient funds (non-retryable)
 	if account == "broke-account" {
-		return "", temporal.NewNonRetryableApplicationError("insufficient funds", "InsufficientFunds", nil)
+		return "", errs.InsufficientFunds.New("insufficient funds", account)
 	}
 
 	// Simulate network issues (retryable)
 	if rand.Float32() < 0.15 {
-		return "", fmt.Errorf("database connection failed")
+		return "", errs.DependencyFailure.New("database connection failed")
 	}
 
 	// Generate transaction ID
-	txnID := fmt.Sprintf("debit_%d", time.Now().Unix())
+	txnID := fmt.Sprintf("debit_%d", time.Now().UnixNano())
+	err := DefaultLedger.post(ctx, Posting{
+		TxnID: txnID, Kind: PostingDebit, Account: account, Amount: amount, Currency: currency, Reference: reference,
+	})
+	if err != nil {
+		return "", err
+	}
 	logger.Info("Debit successful", "txnID", txnID)
 	return txnID, nil
 }
 
 // CreditAccount adds money to an account
-func CreditAccount(ctx context.Context, account string, amount float64, reference string) (string, error) {
+func CreditAccount(ctx context.Context, account string, amount float64, currency string, reference string) (string, error) {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Crediting account", "account", account, "amount", amount)
+	logger.Info("Crediting account", "account", account, "amount", amount, "currency", currency)
 
 	// Simulate processing time
 	time.Sleep(time.Millisecond * 200)
 
 	// Simulate account service issues (retryable)
-	if rand.Float32() < 0.3 {
-		return "", fmt.Errorf("credit service temporarily unavailable")
+	// The breaker stops every workflow hammering the service while it is down
+	err := CreditBreaker.Execute(ctx, func() error {
+		if rand.Float32() < 0.3 {
+			return errs.ServiceUnavailable.New("credit service temporarily unavailable")
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
 	// Generate transaction ID
-	txnID := fmt.Sprintf("credit_%d", time.Now().Unix())
+	txnID := fmt.Sprintf("credit_%d", time.Now().UnixNano())
+	err = DefaultLedger.post(ctx, Posting{
+		TxnID: txnID, Kind: PostingCredit, Account: account, Amount: amount, Currency: currency, Reference: reference,
+	})
+	if err != nil {
+		return "", err
+	}
 	logger.Info("Credit successful", "txnID", txnID)
 	return txnID, nil
 }
 
 // CompensateDebit reverses a debit transaction
-func CompensateDebit(ctx context.Context, account string, amount float64, originalTxnID string) error {
+// The reversal is keyed by the debit and posted to the transfer run that made
+// the debit, even when a fix-up workflow calls this; reversing twice is a no-op
+func CompensateDebit(ctx context.Context, account string, amount float64, currency string, originalTxnID string) error {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Compensating debit", "account", account, "amount", amount, "originalTxn", originalTxnID)
+	logger.Info("Compensating debit", "account", account, "amount", amount, "currency", currency, "originalTxn", originalTxnID)
 
 	// Simulate compensation time
 	time.Sleep(time.Millisecond * 150)
 
 	// Compensation should rarely fail, but simulate occasional issues
 	if rand.Float32() < 0.05 {
-		return fmt.Errorf("compensation service failed - manual intervention required")
+		return errs.DependencyFailure.New("compensation service failed - manual intervention required")
 	}
 
+	reversal := Posting{
+		TxnID:     "reversal_" + originalTxnID,
+		Kind:      PostingReversal,
+		Account:   account,
+		Amount:    amount,
+		Currency:  currency,
+		Reference: originalTxnID,
+	}
+	debit, found, err := DefaultLedger.Posting(originalTxnID)
+	if err != nil {
+		return errs.DependencyFailure.Wrap(err, "ledger unavailable")
+	}
+	if found {
+		reversal.WorkflowID, reversal.RunID = debit.WorkflowID, debit.RunID
+	}
+	if err := DefaultLedger.post(ctx, reversal); err != nil {
+		return err
+	}
 	logger.Info("Compensation successful", "account", account, "reversedTxn", originalTxnID)
 	return nil
 }
 
+// RepostCredit posts a credit the ledger is missing for a completed transfer
+// It uses the transaction ID the transfer reported and belongs to that transfer's
+// run, so the next reconciliation pass finds it and a second fix-up is a no-op
+func RepostCredit(ctx context.Context, d Discrepancy) (string, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Re-posting missing credit", "account", d.Account, "amount", d.Amount, "transfer", d.WorkflowID)
+
+	err := CreditBreaker.Execute(ctx, func() error {
+		if rand.Float32() < 0.3 {
+			return errs.ServiceUnavailable.New("credit service temporarily unavailable")
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	txnID := d.TxnIDs[0]
+	err = DefaultLedger.post(ctx, Posting{
+		TxnID:      txnID,
+		WorkflowID: d.WorkflowID,
+		RunID:      d.RunID,
+		Kind:       PostingCredit,
+		Account:    d.Account,
+		Amount:     d.Amount,
+		Currency:   d.Currency,
+		Reference:  "fixup " + d.WorkflowID,
+	})
+	if err != nil {
+		return "", err
+	}
+	logger.Info("Missing credit re-posted", "txnID", txnID)
+	return txnID, nil
+}
+
 // RiskyTransferActivity demonstrates different types of errors
 func RiskyTransferActivity(ctx context.Context, request TransferRequest) (string, error) {
 	logger := activity.GetLogger(ctx)
@@ -109,24 +189,19 @@ func RiskyTransferActivity(ctx context.Context, request TransferRequest) (string
 	switch {
 	case random < 0.2:
 		// Non-retryable: Invalid account
-		return "", temporal.NewNonRetryableApplicationError(
+		return "", errs.InvalidAccount.New(
 			fmt.Sprintf("account %s does not exist", request.FromAccount),
-			"InvalidAccount",
-			nil,
+			request.FromAccount,
 		)
 	case random < 0.3:
 		// Non-retryable: Insufficient funds
-		return "", temporal.NewNonRetryableApplicationError(
-			"insufficient funds in account",
-			"InsufficientFunds",
-			nil,
-		)
+		return "", errs.InsufficientFunds.New("insufficient funds in account", request.FromAccount)
 	case random < 0.6:
 		// Retryable: Network error
-		return "", fmt.Errorf("network timeout during transfer")
+		return "", errs.ServiceUnavailable.New("network timeout during transfer")
 	case random < 0.7:
 		// Retryable: Database error
-		return "", fmt.Errorf("database connection lost")
+		return "", errs.DependencyFailure.New("database connection lost")
 	default:
 		// Success!
 		result := fmt.Sprintf("Transfer successful: $%.2f from %s to %s",
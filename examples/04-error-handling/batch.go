@@ -0,0 +1,449 @@
+package errors
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"temporal-go-examples/shared/errs"
+	"temporal-go-examples/shared/policies"
+)
+
+const (
+	// BatchProgressQuery returns a BatchProgress for a running batch
+	BatchProgressQuery = "batch-progress"
+
+	// DefaultBatchConcurrency is used when BatchTransferInput.Concurrency is not set
+	DefaultBatchConcurrency = 10
+
+	// batchItemsPerRun caps how many children one run starts before continuing as new
+	// This keeps each run's event history well under Temporal's limits
+	batchItemsPerRun = 500
+
+	// batchUnsuccessfulLimit caps the workflow IDs kept in BatchReport.Unsuccessful
+	// The report is carried through continue-as-new, so it must not grow with the batch
+	batchUnsuccessfulLimit = 100
+
+	// Per-item statuses in the BatchReport
+	BatchItemSucceeded   = "succeeded"
+	BatchItemFailed      = "failed"
+	BatchItemCompensated = "compensated"
+	// BatchItemSkipped means the item's transfer was already started by an
+	// earlier run of the same batch, so it was not started again
+	BatchItemSkipped = "skipped"
+)
+
+// BatchTransferInput is the input of BatchTransferWorkflow
+// Source is a file of requests the workers can read; each run loads only its own
+// page, so large batches never travel in the workflow input. Requests can hold
+// a small batch inline instead. Offset and Report are carried across
+// continue-as-new; callers leave them empty
+type BatchTransferInput struct {
+	BatchID     string            `json:"batch_id"`
+	Source      string            `json:"source,omitempty"` // .csv, .json or .jsonl file, see LoadTransferRequests
+	Requests    []TransferRequest `json:"requests,omitempty"`
+	Concurrency int               `json:"concurrency"`
+	Offset      int               `json:"offset"` // Index of the first request this run handles
+	Report      BatchReport       `json:"report"`
+}
+
+// BatchItemResult is the outcome of one transfer in the batch
+type BatchItemResult struct {
+	Index      int             `json:"index"`
+	WorkflowID string          `json:"workflow_id"`
+	Reference  string          `json:"reference"`
+	Status     string          `json:"status"`
+	Error      string          `json:"error,omitempty"`
+	Result     *TransferResult `json:"result,omitempty"`
+}
+
+// BatchReport summarises a batch
+// It only holds counters and the workflow IDs of the first unsuccessful items,
+// so it stays small however big the batch is; every item's BatchItemResult is
+// written to ResultsDir by BatchStore.SaveBatchResults, read it back with
+// ReadBatchResults
+type BatchReport struct {
+	BatchID     string `json:"batch_id"`
+	Total       int    `json:"total"`
+	Succeeded   int    `json:"succeeded"`
+	Failed      int    `json:"failed"`
+	Compensated int    `json:"compensated"`
+	Skipped     int    `json:"skipped"`
+	// Workflow IDs of failed and compensated items, up to batchUnsuccessfulLimit
+	// of them; when Failed+Compensated is larger, the rest are in ResultsDir
+	Unsuccessful []string `json:"unsuccessful,omitempty"`
+	ResultsDir   string   `json:"results_dir,omitempty"`
+}
+
+// completed is the number of items with an outcome
+func (r BatchReport) completed() int {
+	return r.Succeeded + r.Failed + r.Compensated + r.Skipped
+}
+
+// BatchProgress is returned by the BatchProgressQuery
+type BatchProgress struct {
+	Total       int `json:"total"`
+	Completed   int `json:"completed"`
+	InFlight    int `json:"in_flight"`
+	Succeeded   int `json:"succeeded"`
+	Failed      int `json:"failed"`
+	Compensated int `json:"compensated"`
+	Skipped     int `json:"skipped"`
+	Runs        int `json:"runs"` // Number of continue-as-new runs so far, including this one
+}
+
+// BatchTransferWorkflow runs a MoneyTransferWorkflow child per request with bounded concurrency
+// After batchItemsPerRun children it drains in-flight transfers, saves their
+// results outside the workflow and continues as new with just an offset and
+// counters, so batches of thousands of transfers never outgrow a single history
+func BatchTransferWorkflow(ctx workflow.Context, input BatchTransferInput) (BatchReport, error) {
+	logger := workflow.GetLogger(ctx)
+
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	report := input.Report
+	if input.Offset == 0 {
+		report = BatchReport{
+			BatchID: input.BatchID,
+			Total:   len(input.Requests),
+		}
+	}
+	runs := input.Offset/batchItemsPerRun + 1
+	logger.Info("BatchTransferWorkflow run started", "batchID", input.BatchID, "offset", input.Offset)
+
+	inFlight := 0
+	err := workflow.SetQueryHandler(ctx, BatchProgressQuery, func() (BatchProgress, error) {
+		return BatchProgress{
+			Total:       report.Total,
+			Completed:   report.completed(),
+			InFlight:    inFlight,
+			Succeeded:   report.Succeeded,
+			Failed:      report.Failed,
+			Compensated: report.Compensated,
+			Skipped:     report.Skipped,
+			Runs:        runs,
+		}, nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// Load at most batchItemsPerRun requests for this run
+	var store *BatchStore
+	var requests []TransferRequest
+	if input.Source != "" {
+		var page BatchPage
+		err := policies.ExecuteActivity(ctx, store.LoadBatchPage, input.Source, input.Offset, batchItemsPerRun).Get(ctx, &page)
+		if err != nil {
+			return report, fmt.Errorf("load batch page at %d: %w", input.Offset, err)
+		}
+		requests = page.Requests
+		report.Total = page.Total
+	} else {
+		requests = input.Requests
+		if len(requests) > batchItemsPerRun {
+			requests = requests[:batchItemsPerRun]
+		}
+	}
+	runSize := len(requests)
+
+	// Re-running a batch must not move money twice, so an item whose child ID
+	// was already used is rejected by the server, even if that child has
+	// finished, and reported as skipped. Runs started before this used the
+	// default reuse policy; GetVersion keeps their replays doing so
+	rejectDuplicates := workflow.GetVersion(ctx, "batch-reject-duplicates", workflow.DefaultVersion, 1) == 1
+
+	// Results of this run only; they are saved before continuing as new
+	var items []BatchItemResult
+	selector := workflow.NewSelector(ctx)
+	for i := 0; i < runSize; i++ {
+		// Wait for a free slot before starting the next child
+		for inFlight >= concurrency {
+			selector.Select(ctx)
+		}
+
+		index := input.Offset + i
+		request := requests[i]
+		// Deterministic IDs let a re-run find the transfers it already started
+		childID := fmt.Sprintf("%s-item-%d", input.BatchID, index)
+		childOptions := workflow.ChildWorkflowOptions{WorkflowID: childID}
+		if rejectDuplicates {
+			childOptions.WorkflowIDReusePolicy = enumspb.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE
+		}
+		childCtx := workflow.WithChildOptions(ctx, childOptions)
+		future := workflow.ExecuteChildWorkflow(childCtx, MoneyTransferWorkflow, request)
+		inFlight++
+
+		selector.AddFuture(future, func(f workflow.Future) {
+			inFlight--
+			item := BatchItemResult{
+				Index:      index,
+				WorkflowID: childID,
+				Reference:  request.Reference,
+			}
+			var result TransferResult
+			if err := f.Get(ctx, &result); rejectDuplicates && temporal.IsWorkflowExecutionAlreadyStartedError(err) {
+				// The earlier run's result for this item is in its results file
+				item.Status = BatchItemSkipped
+				item.Error = "transfer already started by an earlier run of this batch"
+			} else if err != nil {
+				item.Status = classifyBatchItemError(err)
+				item.Error = err.Error()
+			} else {
+				item.Status = BatchItemSucceeded
+				item.Result = &result
+			}
+			report.add(item)
+			items = append(items, item)
+		})
+	}
+
+	// Drain the children of this run before finishing or continuing as new
+	for inFlight > 0 {
+		selector.Select(ctx)
+	}
+
+	// Runs started before results were stored outside the workflow skip this
+	if runSize > 0 && workflow.GetVersion(ctx, "batch-results-store", workflow.DefaultVersion, 1) == 1 {
+		err := policies.ExecuteActivity(ctx, store.SaveBatchResults, input.BatchID, input.Offset, items).Get(ctx, &report.ResultsDir)
+		if err != nil {
+			return report, fmt.Errorf("save batch results at %d: %w", input.Offset, err)
+		}
+	}
+
+	if next := input.Offset + runSize; runSize > 0 && next < report.Total {
+		logger.Info("Continuing batch as new", "batchID", input.BatchID, "completed", report.completed())
+		nextInput := BatchTransferInput{
+			BatchID:     input.BatchID,
+			Source:      input.Source,
+			Concurrency: concurrency,
+			Offset:      next,
+			Report:      report,
+		}
+		if input.Source == "" {
+			nextInput.Requests = input.Requests[runSize:]
+		}
+		return report, workflow.NewContinueAsNewError(ctx, BatchTransferWorkflow, nextInput)
+	}
+
+	logger.Info("BatchTransferWorkflow completed", "batchID", input.BatchID,
+		"succeeded", report.Succeeded, "failed", report.Failed, "compensated", report.Compensated, "skipped", report.Skipped)
+	return report, nil
+}
+
+// add updates the counters for an item
+func (r *BatchReport) add(item BatchItemResult) {
+	switch item.Status {
+	case BatchItemSucceeded:
+		r.Succeeded++
+		return
+	case BatchItemSkipped:
+		r.Skipped++
+		return
+	case BatchItemCompensated:
+		r.Compensated++
+	default:
+		r.Failed++
+	}
+	if len(r.Unsuccessful) < batchUnsuccessfulLimit {
+		r.Unsuccessful = append(r.Unsuccessful, item.WorkflowID)
+	}
+}
+
+// classifyBatchItemError maps a child workflow failure to a batch item status
+func classifyBatchItemError(err error) string {
+	if errs.TransferCompensated.Is(err) {
+		return BatchItemCompensated
+	}
+	return BatchItemFailed
+}
+
+// BatchPage is one run's share of a batch file
+type BatchPage struct {
+	Requests []TransferRequest `json:"requests"`
+	Total    int               `json:"total"` // Requests in the whole file
+}
+
+// BatchStore holds the batch activities that read requests and keep results outside the workflow
+// Every worker must see the same files: batch sources and Dir on a shared volume
+type BatchStore struct {
+	Dir string // Where per-item results are written; defaults to the OS temp directory
+}
+
+// LoadBatchPage reads up to limit requests starting at offset from a batch file
+func (s *BatchStore) LoadBatchPage(ctx context.Context, source string, offset, limit int) (BatchPage, error) {
+	requests, err := LoadTransferRequests(source)
+	if err != nil {
+		return BatchPage{}, errs.InvalidBatch.Wrap(err, fmt.Sprintf("unable to read batch %s", source), source)
+	}
+	page := BatchPage{Total: len(requests)}
+	if offset < len(requests) {
+		page.Requests = requests[offset:min(offset+limit, len(requests))]
+	}
+	activity.GetLogger(ctx).Info("Batch page loaded", "source", source, "offset", offset, "count", len(page.Requests), "total", page.Total)
+	return page, nil
+}
+
+// SaveBatchResults writes one run's item results and returns the batch's results directory
+// Each run gets its own file named after its offset, so a retry overwrites
+// instead of appending the same results twice
+func (s *BatchStore) SaveBatchResults(ctx context.Context, batchID string, offset int, items []BatchItemResult) (string, error) {
+	dir := s.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	dir = filepath.Join(dir, batchID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", errs.DependencyFailure.Wrap(err, "create batch results directory")
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			return "", errs.Internal.Wrap(err, "encode batch result")
+		}
+	}
+	path := filepath.Join(dir, fmt.Sprintf("items-%09d.jsonl", offset))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return "", errs.DependencyFailure.Wrap(err, "write batch results")
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", errs.DependencyFailure.Wrap(err, "write batch results")
+	}
+
+	activity.GetLogger(ctx).Info("Batch results saved", "path", path, "items", len(items))
+	return dir, nil
+}
+
+// ReadBatchResults reads every item result saved for a batch, ordered by index
+func ReadBatchResults(dir string) ([]BatchItemResult, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "items-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	var items []BatchItemResult
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open batch results: %w", err)
+		}
+		decoder := json.NewDecoder(f)
+		for {
+			var item BatchItemResult
+			if err := decoder.Decode(&item); err == io.EOF {
+				break
+			} else if err != nil {
+				f.Close()
+				return nil, fmt.Errorf("parse %s: %w", path, err)
+			}
+			items = append(items, item)
+		}
+		f.Close()
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Index < items[j].Index })
+	return items, nil
+}
+
+// LoadTransferRequests reads a batch of transfer requests from a .csv, .json or .jsonl file
+//
+// CSV files need a header row naming the TransferRequest JSON fields, e.g.
+// from_account,to_account,amount,from_currency,to_currency,reference
+// JSON files hold an array of requests; JSONL files hold one request per line
+func LoadTransferRequests(path string) ([]TransferRequest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open batch file: %w", err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return readTransferCSV(f)
+	case ".json":
+		var requests []TransferRequest
+		if err := json.NewDecoder(f).Decode(&requests); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		return requests, nil
+	case ".jsonl":
+		var requests []TransferRequest
+		decoder := json.NewDecoder(f)
+		for {
+			var request TransferRequest
+			if err := decoder.Decode(&request); err == io.EOF {
+				return requests, nil
+			} else if err != nil {
+				return nil, fmt.Errorf("parse %s record %d: %w", path, len(requests)+1, err)
+			}
+			requests = append(requests, request)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported batch file type %q (want .csv, .json or .jsonl)", filepath.Ext(path))
+	}
+}
+
+// readTransferCSV parses CSV rows into transfer requests using the header row
+func readTransferCSV(r io.Reader) ([]TransferRequest, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"from_account", "to_account", "amount"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv header is missing column %q", required)
+		}
+	}
+
+	var requests []TransferRequest
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return requests, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv line %d: %w", line, err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		amount, err := strconv.ParseFloat(field("amount"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("csv line %d: invalid amount %q", line, field("amount"))
+		}
+		requests = append(requests, TransferRequest{
+			FromAccount:  field("from_account"),
+			ToAccount:    field("to_account"),
+			Amount:       amount,
+			FromCurrency: field("from_currency"),
+			ToCurrency:   field("to_currency"),
+			Reference:    field("reference"),
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.temporal.io/sdk/client"
+
+	errors "temporal-go-examples/examples/04-error-handling"
+	"temporal-go-examples/shared"
+)
+
+func main() {
+	file := flag.String("file", "", "CSV, JSON or JSONL file of transfer requests")
+	generate := flag.Int("generate", 0, "Generate this many sample transfers instead of reading a file")
+	concurrency := flag.Int("concurrency", errors.DefaultBatchConcurrency, "Maximum transfers running at once")
+	reportFile := flag.String("report", "", "Write the JSON batch report to this file")
+	flag.Parse()
+
+	// Create Temporal client
+	c, err := shared.CreateTemporalClient()
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	batchID := "batch-" + shared.RandomID()
+
+	// The workflow reads the batch from a file page by page, so the requests
+	// never travel in its input; workers must be able to read the file
+	var source string
+	switch {
+	case *file != "":
+		// Check the file parses before starting anything
+		requests, err := errors.LoadTransferRequests(*file)
+		if err != nil {
+			log.Fatalln("Unable to load batch", err)
+		}
+		if source, err = filepath.Abs(*file); err != nil {
+			log.Fatalln("Unable to resolve batch file", err)
+		}
+		shared.LogInfo("Loaded %d transfers from %s", len(requests), source)
+	case *generate > 0:
+		source = filepath.Join(os.TempDir(), batchID+".jsonl")
+		if err := writeGenerated(source, *generate); err != nil {
+			log.Fatalln("Unable to write generated batch", err)
+		}
+		shared.LogInfo("Generated %d transfers into %s", *generate, source)
+	default:
+		log.Fatalln("Pass -file <path> or -generate <count>")
+	}
+
+	shared.LogInfo("Starting BatchTransferWorkflow %s (concurrency %d)", batchID, *concurrency)
+
+	workflowRun, err := c.ExecuteWorkflow(context.Background(), client.StartWorkflowOptions{
+		ID:        batchID,
+		TaskQueue: shared.TaskQueue,
+	}, errors.BatchTransferWorkflow, errors.BatchTransferInput{
+		BatchID:     batchID,
+		Source:      source,
+		Concurrency: *concurrency,
+	})
+	if err != nil {
+		log.Fatalln("Unable to execute workflow", err)
+	}
+
+	// Report progress until the batch finishes
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second * 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				// Query by workflow ID only so we follow the batch across continue-as-new runs
+				resp, err := c.QueryWorkflow(context.Background(), batchID, "", errors.BatchProgressQuery)
+				if err != nil {
+					continue
+				}
+				var progress errors.BatchProgress
+				if err := resp.Get(&progress); err == nil {
+					shared.LogInfo("📊 Progress: %d/%d done (%d in flight) - ✅ %d ❌ %d ↩️ %d ⏭️ %d (run %d)",
+						progress.Completed, progress.Total, progress.InFlight,
+						progress.Succeeded, progress.Failed, progress.Compensated, progress.Skipped, progress.Runs)
+				}
+			}
+		}
+	}()
+
+	// Following continue-as-new runs is the default for WorkflowRun.Get
+	var report errors.BatchReport
+	err = workflowRun.Get(context.Background(), &report)
+	close(done)
+	if err != nil {
+		log.Fatalln("Batch failed", err)
+	}
+
+	shared.LogInfo("🎉 Batch %s finished: %d succeeded, %d failed, %d compensated, %d skipped (of %d)",
+		report.BatchID, report.Succeeded, report.Failed, report.Compensated, report.Skipped, report.Total)
+
+	// Per-item results live next to the workers, not in the workflow result
+	items, err := errors.ReadBatchResults(report.ResultsDir)
+	if err != nil {
+		shared.LogInfo("Unable to read item results from %s: %v", report.ResultsDir, err)
+		for _, workflowID := range report.Unsuccessful {
+			shared.LogInfo("   %s", workflowID)
+		}
+	}
+	for _, item := range items {
+		if item.Status != errors.BatchItemSucceeded {
+			shared.LogInfo("   #%d %s [%s]: %s", item.Index, item.Reference, item.Status, item.Error)
+		}
+	}
+
+	if *reportFile != "" {
+		data, err := json.MarshalIndent(struct {
+			errors.BatchReport
+			Items []errors.BatchItemResult `json:"items"`
+		}{report, items}, "", "  ")
+		if err != nil {
+			log.Fatalln("Unable to encode report", err)
+		}
+		if err := os.WriteFile(*reportFile, data, 0o644); err != nil {
+			log.Fatalln("Unable to write report", err)
+		}
+		shared.LogInfo("Report written to %s", *reportFile)
+	}
+}
+
+// writeGenerated writes count sample transfers to a JSONL batch file
+func writeGenerated(path string, count int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	for i := 0; i < count; i++ {
+		err := encoder.Encode(errors.TransferRequest{
+			FromAccount: fmt.Sprintf("account-%d", i%50),
+			ToAccount:   fmt.Sprintf("account-%d", 50+i%50),
+			Amount:      float64(10 + i%90),
+			Reference:   fmt.Sprintf("Generated transfer %d", i),
+		})
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
@@ -0,0 +1,136 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	"temporal-go-examples/shared/errs"
+)
+
+// transferRequests builds n requests; every failEvery-th one is compensated
+func transferRequests(n, failEvery int) []TransferRequest {
+	requests := make([]TransferRequest, n)
+	for i := range requests {
+		requests[i] = TransferRequest{FromAccount: "account-123", ToAccount: "account-456", Amount: 10, Reference: fmt.Sprintf("ref-%d", i)}
+		if failEvery > 0 && i%failEvery == 0 {
+			requests[i].Amount = -1
+		}
+	}
+	return requests
+}
+
+// rerunBatch runs the same batch twice, as an operator re-running it would
+func rerunBatch(ctx workflow.Context, input BatchTransferInput) ([]BatchReport, error) {
+	var reports []BatchReport
+	for run := 1; run <= 2; run++ {
+		var report BatchReport
+		childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+			WorkflowID: fmt.Sprintf("%s-run-%d", input.BatchID, run),
+		})
+		if err := workflow.ExecuteChildWorkflow(childCtx, BatchTransferWorkflow, input).Get(ctx, &report); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// newBatchEnv runs BatchTransferWorkflow with a fake MoneyTransferWorkflow that
+// compensates negative amounts; starts counts the transfers that ran
+func newBatchEnv(t *testing.T, resultsDir string, starts *int) *testsuite.TestWorkflowEnvironment {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(BatchTransferWorkflow)
+	env.RegisterWorkflow(MoneyTransferWorkflow)
+	env.RegisterWorkflow(rerunBatch)
+	env.RegisterActivity(&BatchStore{Dir: resultsDir})
+	env.OnWorkflow(MoneyTransferWorkflow, mock.Anything, mock.Anything).Return(
+		func(ctx workflow.Context, request TransferRequest) (TransferResult, error) {
+			*starts++
+			if request.Amount < 0 {
+				return TransferResult{}, errs.TransferCompensated.New("credit failed, debit reversed")
+			}
+			return TransferResult{Summary: "ok " + request.Reference}, nil
+		})
+	return env
+}
+
+func TestBatchContinuesAsNewWithBoundedReport(t *testing.T) {
+	resultsDir := t.TempDir()
+	starts := 0
+	// 700 items, every 3rd compensated: 167 in the first run, more than the report keeps
+	input := BatchTransferInput{BatchID: "batch-can", Requests: transferRequests(700, 3), Concurrency: 50}
+
+	env := newBatchEnv(t, resultsDir, &starts)
+	env.ExecuteWorkflow(BatchTransferWorkflow, input)
+
+	require.True(t, env.IsWorkflowCompleted())
+	var continued *workflow.ContinueAsNewError
+	require.True(t, stderrors.As(env.GetWorkflowError(), &continued), "first run should continue as new: %v", env.GetWorkflowError())
+	var next BatchTransferInput
+	require.NoError(t, converter.GetDefaultDataConverter().FromPayloads(continued.Input, &next))
+
+	assert.Equal(t, batchItemsPerRun, starts)
+	assert.Equal(t, batchItemsPerRun, next.Offset)
+	assert.Len(t, next.Requests, 700-batchItemsPerRun)
+	assert.Equal(t, 700, next.Report.Total)
+	assert.Equal(t, 333, next.Report.Succeeded)
+	assert.Equal(t, 167, next.Report.Compensated)
+	assert.Len(t, next.Report.Unsuccessful, batchUnsuccessfulLimit, "the carried report must stay bounded")
+
+	// The second run picks up at the offset with the carried counters
+	env = newBatchEnv(t, resultsDir, &starts)
+	env.ExecuteWorkflow(BatchTransferWorkflow, next)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report BatchReport
+	require.NoError(t, env.GetWorkflowResult(&report))
+	assert.Equal(t, 700, starts)
+	assert.Equal(t, 700, report.completed())
+	assert.Equal(t, 466, report.Succeeded)
+	assert.Equal(t, 234, report.Compensated)
+	assert.Zero(t, report.Failed)
+	assert.Len(t, report.Unsuccessful, batchUnsuccessfulLimit)
+
+	// Every item's result is on disk, whichever run handled it
+	items, err := ReadBatchResults(report.ResultsDir)
+	require.NoError(t, err)
+	require.Len(t, items, 700)
+	for i, item := range items {
+		assert.Equal(t, i, item.Index)
+		assert.Equal(t, fmt.Sprintf("batch-can-item-%d", i), item.WorkflowID)
+	}
+	assert.Equal(t, BatchItemCompensated, items[699].Status)
+	assert.Equal(t, BatchItemSucceeded, items[698].Status)
+}
+
+func TestBatchRerunSkipsStartedTransfers(t *testing.T) {
+	starts := 0
+	env := newBatchEnv(t, t.TempDir(), &starts)
+	env.ExecuteWorkflow(rerunBatch, BatchTransferInput{BatchID: "batch-rerun", Requests: transferRequests(4, 2)})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var reports []BatchReport
+	require.NoError(t, env.GetWorkflowResult(&reports))
+	require.Len(t, reports, 2)
+	assert.Equal(t, 4, starts, "the re-run must not start any transfer again")
+
+	assert.Equal(t, 2, reports[0].Succeeded)
+	assert.Equal(t, 2, reports[0].Compensated)
+	assert.Equal(t, []string{"batch-rerun-item-0", "batch-rerun-item-2"}, reports[0].Unsuccessful)
+
+	assert.Equal(t, 4, reports[1].Skipped)
+	assert.Equal(t, 4, reports[1].completed())
+	assert.Zero(t, reports[1].Failed, "a duplicate is not a failure")
+	assert.Empty(t, reports[1].Unsuccessful)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"temporal-go-examples/shared"
+	"temporal-go-examples/shared/circuit"
+)
+
+const usage = `Usage: go run circuit/main.go <command> [name]
+
+Commands:
+  status         Show every circuit and its state
+  reset <name>   Close a circuit by hand, e.g. after fixing the downstream
+  open <name>    Force a circuit open for its configured cooldown
+
+Circuits are read from the file in CIRCUIT_STORE_FILE (the same file the workers use).`
+
+func main() {
+	path := os.Getenv("CIRCUIT_STORE_FILE")
+	if path == "" || len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+	store := circuit.NewFileStore(path)
+	command := os.Args[1]
+
+	switch command {
+	case "status":
+		states, err := store.List()
+		if err != nil {
+			log.Fatalln("Unable to read circuits", err)
+		}
+		if len(states) == 0 {
+			shared.LogInfo("No circuits recorded yet in %s", path)
+		}
+		sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+		for _, s := range states {
+			icon := "🟢"
+			switch s.Status {
+			case circuit.Open:
+				icon = "🔴"
+			case circuit.HalfOpen:
+				icon = "🟡"
+			}
+			shared.LogInfo("%s %s: %s (consecutive failures: %d, rejected: %d, updated %s ago)",
+				icon, s.Name, s.Status, s.ConsecutiveFailures, s.Rejected, time.Since(s.UpdatedAt).Round(time.Second))
+			if s.Status == circuit.Open {
+				shared.LogInfo("   reopens for probes in %s", time.Until(s.OpenUntil).Round(time.Second))
+			}
+			if s.LastFailure != "" {
+				shared.LogInfo("   last failure: %s", s.LastFailure)
+			}
+		}
+	case "reset", "open":
+		if len(os.Args) < 3 {
+			fmt.Println(usage)
+			os.Exit(1)
+		}
+		name := os.Args[2]
+		state, err := store.Update(name, func(s *circuit.State) {
+			s.ProbesInFlight = 0
+			if command == "reset" {
+				s.Status = circuit.Closed
+				s.ConsecutiveFailures = 0
+				return
+			}
+			s.Status = circuit.Open
+			s.OpenUntil = time.Now().Add(circuit.DefaultConfig().OpenDuration)
+		})
+		if err != nil {
+			log.Fatalln("Unable to update circuit", err)
+		}
+		shared.LogInfo("✅ Circuit %s is now %s", state.Name, state.Status)
+	default:
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	errors "temporal-go-examples/examples/04-error-handling"
 	"temporal-go-examples/shared"
+	"temporal-go-examples/shared/errs"
 )
 
 func main() {
@@ -19,8 +20,9 @@ func main() {
 
 	// Test different scenarios
 	testScenarios := []struct {
-		name    string
-		request errors.TransferRequest
+		name     string
+		request  errors.TransferRequest
+		approval *errors.ApprovalDecision // Sent as a reviewer signal if set
 	}{
 		{
 			name: "Normal Transfer",
@@ -31,6 +33,17 @@ func main() {
 				Reference:   "Payment for services",
 			},
 		},
+		{
+			name: "Cross-Currency Transfer (USD to EUR)",
+			request: errors.TransferRequest{
+				FromAccount:  "account-123",
+				ToAccount:    "account-789",
+				Amount:       250.00,
+				FromCurrency: "USD",
+				ToCurrency:   "EUR",
+				Reference:    "Invoice 2024-001",
+			},
+		},
 		{
 			name: "Invalid Account (will fail immediately)",
 			request: errors.TransferRequest{
@@ -49,6 +62,20 @@ func main() {
 				Reference:   "Test insufficient funds",
 			},
 		},
+		{
+			name: "Large Transfer (held for manual approval)",
+			request: errors.TransferRequest{
+				FromAccount: "account-123",
+				ToAccount:   "account-456",
+				Amount:      7500.00,
+				Reference:   "Equipment purchase",
+			},
+			approval: &errors.ApprovalDecision{
+				Approved: true,
+				Reviewer: "risk-team",
+				Note:     "Verified with customer by phone",
+			},
+		},
 	}
 
 	// Run the MoneyTransferWorkflow tests
@@ -62,13 +89,25 @@ func main() {
 			continue
 		}
 
+		// Play the reviewer for transfers that need manual approval
+		if scenario.approval != nil {
+			err = c.SignalWorkflow(context.Background(), workflowRun.GetID(), "", errors.ApprovalSignal, *scenario.approval)
+			if err != nil {
+				shared.LogError("Failed to send approval: %v", err)
+			} else {
+				shared.LogInfo("📝 Reviewer %s sent approval", scenario.approval.Reviewer)
+			}
+		}
+
 		// Wait for result
-		var result string
+		var result errors.TransferResult
 		err = workflowRun.Get(context.Background(), &result)
 		if err != nil {
-			shared.LogError("Workflow failed: %v", err)
+			// Classify maps the failure back to its category, wherever it came from
+			class := errs.Classify(err)
+			shared.LogError("Workflow failed [%s/%s]: %v", class.Category, class.Type, err)
 		} else {
-			shared.LogInfo("✅ Workflow succeeded: %s", result)
+			shared.LogInfo("✅ Workflow succeeded: %s", result.Summary)
 		}
 
 		time.Sleep(time.Second) // Give some time between tests
@@ -97,7 +136,16 @@ func main() {
 		var result string
 		err = workflowRun.Get(context.Background(), &result)
 		if err != nil {
-			shared.LogError("Risky transfer failed: %v", err)
+			// The workflow attaches a structured errs.Failure to its error
+			var failure errs.Failure
+			if errs.Details(err, &failure) {
+				shared.LogError("Risky transfer failed: %s", failure.Message)
+				shared.LogError("   kind=%s type=%s category=%s retryable=%v attempts=%d retryState=%s",
+					failure.Kind, failure.Type, failure.Category, failure.Retryable, failure.Attempts, failure.RetryState)
+				shared.LogError("   last failure cause: %s", failure.Cause)
+			} else {
+				shared.LogError("Risky transfer failed: %v", err)
+			}
 		} else {
 			shared.LogInfo("✅ Risky transfer succeeded: %s", result)
 		}
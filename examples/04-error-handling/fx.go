@@ -0,0 +1,128 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+
+	"temporal-go-examples/shared/errs"
+)
+
+const (
+	// DefaultCurrency is used when a TransferRequest leaves a currency empty
+	DefaultCurrency = "USD"
+
+	// FXQuoteTTL is how long a quoted rate stays locked
+	FXQuoteTTL = time.Second * 30
+
+	// maxRequotes is how often a transfer re-locks an expired rate before giving up
+	maxRequotes = 3
+
+	// fxSpreadBps is the spread (in basis points) charged on cross-currency transfers
+	fxSpreadBps = 25.0
+)
+
+// fxRateTable is a local fake of a rate provider: units of each currency per 1 USD
+var fxRateTable = map[string]float64{
+	"USD": 1.0,
+	"EUR": 0.92,
+	"GBP": 0.79,
+	"JPY": 149.50,
+	"INR": 83.20,
+}
+
+// FXQuote is a locked exchange rate between two currencies
+type FXQuote struct {
+	QuoteID   string    `json:"quote_id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	MidRate   float64   `json:"mid_rate"`   // Market rate before spread
+	Rate      float64   `json:"rate"`       // Rate applied to the transfer (mid rate minus spread)
+	SpreadBps float64   `json:"spread_bps"` // Spread charged, in basis points
+	LockedAt  time.Time `json:"locked_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Convert applies the quoted rate to an amount in the source currency
+func (q FXQuote) Convert(amount float64) float64 {
+	return math.Round(amount*q.Rate*100) / 100
+}
+
+// ExpiredAt reports whether the quote is no longer locked at the given time
+func (q FXQuote) ExpiredAt(t time.Time) bool {
+	return !t.Before(q.ExpiresAt)
+}
+
+// toBaseCurrency converts an amount to DefaultCurrency at the mid rate
+// Risk limits use it so transfers in different currencies add up
+func toBaseCurrency(amount float64, currency string) (float64, error) {
+	currency = normalizeCurrency(currency)
+	rate, ok := fxRateTable[currency]
+	if !ok {
+		return 0, errs.UnsupportedCurrency.New(fmt.Sprintf("currency %s is not supported", currency), currency)
+	}
+	return amount * fxRateTable[DefaultCurrency] / rate, nil
+}
+
+// normalizeCurrency upper-cases a currency code and applies the default
+func normalizeCurrency(currency string) string {
+	if currency == "" {
+		return DefaultCurrency
+	}
+	return strings.ToUpper(currency)
+}
+
+// GetFXQuote locks an exchange rate from one currency to another for FXQuoteTTL
+// Same-currency quotes are returned with a rate of 1 and no spread
+func GetFXQuote(ctx context.Context, from, to string) (FXQuote, error) {
+	logger := activity.GetLogger(ctx)
+	from, to = normalizeCurrency(from), normalizeCurrency(to)
+	logger.Info("Requesting FX quote", "from", from, "to", to)
+
+	// Simulate rate provider latency
+	time.Sleep(time.Millisecond * 50)
+
+	fromRate, ok := fxRateTable[from]
+	if !ok {
+		return FXQuote{}, errs.UnsupportedCurrency.New(fmt.Sprintf("currency %s is not supported", from), from)
+	}
+	toRate, ok := fxRateTable[to]
+	if !ok {
+		return FXQuote{}, errs.UnsupportedCurrency.New(fmt.Sprintf("currency %s is not supported", to), to)
+	}
+
+	now := time.Now()
+	quote := FXQuote{
+		QuoteID:   fmt.Sprintf("fxq_%d", now.UnixNano()),
+		From:      from,
+		To:        to,
+		MidRate:   toRate / fromRate,
+		LockedAt:  now,
+		ExpiresAt: now.Add(FXQuoteTTL),
+	}
+	quote.Rate = quote.MidRate
+	if from != to {
+		quote.SpreadBps = fxSpreadBps
+		quote.Rate = quote.MidRate * (1 - fxSpreadBps/10000)
+	}
+
+	logger.Info("FX quote locked", "quoteID", quote.QuoteID, "rate", quote.Rate, "expiresAt", quote.ExpiresAt)
+	return quote, nil
+}
+
+// CreditAtQuote credits the destination with an amount converted at a locked quote
+// Every attempt checks the lock first, so a retry that starts after the quote
+// expired fails with FXQuoteExpired instead of crediting at a stale rate
+func CreditAtQuote(ctx context.Context, account string, amount float64, quote FXQuote, reference string) (string, error) {
+	if quote.ExpiredAt(time.Now()) {
+		return "", errs.FXQuoteExpired.New(
+			fmt.Sprintf("fx quote %s expired at %s", quote.QuoteID, quote.ExpiresAt.Format(time.RFC3339)),
+			quote.QuoteID,
+		)
+	}
+	return CreditAccount(ctx, account, quote.Convert(amount), quote.To, reference)
+}
@@ -0,0 +1,106 @@
+package errors
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+
+	"temporal-go-examples/shared/errs"
+	"temporal-go-examples/shared/filestore"
+)
+
+// Ledger posting kinds
+const (
+	PostingDebit    = "debit"
+	PostingCredit   = "credit"
+	PostingReversal = "reversal"
+)
+
+// Posting is one journal entry written by a transfer activity
+// Postings belong to the transfer run they settle, even when a fix-up posts them
+type Posting struct {
+	TxnID      string    `json:"txn_id"`
+	WorkflowID string    `json:"workflow_id"`
+	RunID      string    `json:"run_id"`
+	Kind       string    `json:"kind"`
+	Account    string    `json:"account"`
+	Amount     float64   `json:"amount"`
+	Currency   string    `json:"currency"`
+	Reference  string    `json:"reference"` // For reversals, the TxnID of the reversed debit
+	PostedAt   time.Time `json:"posted_at"`
+}
+
+// Ledger is a journal standing in for the bank's ledger database
+// Postings are kept in a JSON file keyed by TxnID, so they survive worker
+// restarts and every worker on the host sees the same journal. Reconciliation
+// would report every transfer as broken if the journal forgot its postings
+type Ledger struct {
+	file filestore.Map[Posting]
+}
+
+// NewLedger creates a ledger backed by the file at path
+func NewLedger(path string) *Ledger {
+	return &Ledger{file: filestore.Map[Posting]{Path: path}}
+}
+
+// LedgerFromEnv uses LEDGER_FILE, or a file in the temp directory
+func LedgerFromEnv() *Ledger {
+	path := os.Getenv("LEDGER_FILE")
+	if path == "" {
+		path = filepath.Join(os.TempDir(), "temporal-ledger.json")
+	}
+	return NewLedger(path)
+}
+
+// DefaultLedger is the journal the transfer activities post to
+var DefaultLedger = LedgerFromEnv()
+
+// post records a posting; postings without a run belong to the calling activity's run
+// The first posting under a TxnID wins, so posting the same entry again is a no-op
+func (l *Ledger) post(ctx context.Context, posting Posting) error {
+	if posting.RunID == "" {
+		info := activity.GetInfo(ctx)
+		posting.WorkflowID = info.WorkflowExecution.ID
+		posting.RunID = info.WorkflowExecution.RunID
+	}
+	posting.PostedAt = time.Now()
+	_, err := l.file.Update(posting.TxnID, func(stored *Posting) {
+		if stored.TxnID == "" {
+			*stored = posting
+		}
+	})
+	if err != nil {
+		return errs.DependencyFailure.Wrap(err, "ledger unavailable")
+	}
+	return nil
+}
+
+// Posting returns the posting with a transaction ID
+func (l *Ledger) Posting(txnID string) (Posting, bool, error) {
+	postings, err := l.file.Read()
+	if err != nil {
+		return Posting{}, false, err
+	}
+	posting, ok := postings[txnID]
+	return posting, ok, nil
+}
+
+// PostingsForRuns returns the postings made for the given workflow runs, oldest first
+func (l *Ledger) PostingsForRuns(runIDs map[string]bool) ([]Posting, error) {
+	postings, err := l.file.Read()
+	if err != nil {
+		return nil, err
+	}
+	var result []Posting
+	for _, p := range postings {
+		if runIDs[p.RunID] {
+			result = append(result, p)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].PostedAt.Before(result[j].PostedAt) })
+	return result, nil
+}
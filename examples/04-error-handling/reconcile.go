@@ -0,0 +1,489 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"temporal-go-examples/shared"
+	"temporal-go-examples/shared/errs"
+	"temporal-go-examples/shared/policies"
+)
+
+// Discrepancy kinds found by ReconciliationWorkflow
+const (
+	DiscrepancyOrphanDebit      = "orphan-debit"      // Money left the source but the transfer failed without reversing it
+	DiscrepancyMissingCredit    = "missing-credit"    // The transfer completed but the destination was never credited
+	DiscrepancyDoublePosting    = "double-posting"    // The same leg was posted more than once
+	DiscrepancyUnexpectedCredit = "unexpected-credit" // The destination was credited by a transfer that did not complete
+)
+
+const (
+	// DefaultVisibilityLag is used when ReconciliationInput.VisibilityLag is not set
+	DefaultVisibilityLag = 2 * time.Minute
+
+	// closedTransfersPageSize caps the runs, and so the results, one ListClosedTransfersPage call returns
+	closedTransfersPageSize = 100
+)
+
+// TransferOutcome is a closed MoneyTransferWorkflow as seen through visibility
+type TransferOutcome struct {
+	WorkflowID string          `json:"workflow_id"`
+	RunID      string          `json:"run_id"`
+	Status     string          `json:"status"`
+	ErrorType  string          `json:"error_type,omitempty"`
+	Result     *TransferResult `json:"result,omitempty"`
+	ClosedAt   time.Time       `json:"closed_at"`
+}
+
+// Discrepancy is one mismatch between a transfer outcome and the ledger
+type Discrepancy struct {
+	Kind       string   `json:"kind"`
+	Leg        string   `json:"leg"` // Posting kind involved: debit or credit
+	WorkflowID string   `json:"workflow_id"`
+	RunID      string   `json:"run_id"`
+	Account    string   `json:"account"`
+	Amount     float64  `json:"amount"`
+	Currency   string   `json:"currency"`
+	TxnIDs     []string `json:"txn_ids"`
+	Detail     string   `json:"detail"`
+}
+
+// ReconciliationReport is the result of one reconciliation pass
+type ReconciliationReport struct {
+	WindowStart      time.Time     `json:"window_start"`
+	WindowEnd        time.Time     `json:"window_end"`
+	TransfersChecked int           `json:"transfers_checked"`
+	PostingsChecked  int           `json:"postings_checked"`
+	Discrepancies    []Discrepancy `json:"discrepancies"`
+	FixupsStarted    int           `json:"fixups_started"`
+	ReportPath       string        `json:"report_path"`
+}
+
+// ReconciliationInput configures ReconciliationWorkflow
+type ReconciliationInput struct {
+	Since    time.Time     `json:"since"`    // Start of the first window; zero means Lookback before now
+	Lookback time.Duration `json:"lookback"` // Used when Since is zero (default 1h)
+	Interval time.Duration `json:"interval"` // Time between passes; zero runs a single pass
+	AutoFix  bool          `json:"auto_fix"` // Start a DiscrepancyFixupWorkflow per fixable discrepancy
+	// VisibilityLag is how long visibility may take to list a closed run; each
+	// window ends this long before the pass starts (default DefaultVisibilityLag)
+	VisibilityLag time.Duration `json:"visibility_lag"`
+}
+
+// ReconciliationWorkflow compares closed MoneyTransferWorkflow runs against the ledger
+// Each pass covers the transfers closed since the previous pass, exports a report
+// and optionally starts fix-up child workflows. With an Interval it sleeps and
+// continues as new, so it can run indefinitely
+func ReconciliationWorkflow(ctx workflow.Context, input ReconciliationInput) (ReconciliationReport, error) {
+	logger := workflow.GetLogger(ctx)
+
+	// Visibility is eventually consistent: a transfer that closed a moment ago
+	// may not be listed yet. The window ends VisibilityLag in the past so the
+	// index can catch up, and the next window starts where this one ended, so
+	// nothing falls between passes. Transfers are then listed a page at a time,
+	// keeping every activity result small. Runs started before this ended the
+	// window at now and listed everything at once; GetVersion keeps their
+	// replays doing so
+	paged := workflow.GetVersion(ctx, "reconcile-paged-lagged", workflow.DefaultVersion, 1) == 1
+
+	windowEnd := workflow.Now(ctx)
+	if paged {
+		lag := input.VisibilityLag
+		if lag <= 0 {
+			lag = DefaultVisibilityLag
+		}
+		windowEnd = windowEnd.Add(-lag)
+	}
+	windowStart := input.Since
+	if windowStart.IsZero() {
+		lookback := input.Lookback
+		if lookback <= 0 {
+			lookback = time.Hour
+		}
+		windowStart = windowEnd.Add(-lookback)
+	}
+	if windowEnd.Before(windowStart) {
+		// The lag grew since the last pass; check nothing twice
+		windowEnd = windowStart
+	}
+	logger.Info("Reconciliation pass started", "windowStart", windowStart, "windowEnd", windowEnd)
+
+	report := ReconciliationReport{WindowStart: windowStart, WindowEnd: windowEnd}
+	var reconciler *Reconciler
+	if paged {
+		// Steps 1-3 a page at a time: list, load postings, compare
+		var pageToken []byte
+		for {
+			var page TransferPage
+			err := policies.ExecuteActivity(ctx, reconciler.ListClosedTransfersPage, windowStart, windowEnd, pageToken).Get(ctx, &page)
+			if err != nil {
+				return ReconciliationReport{}, fmt.Errorf("list closed transfers: %w", err)
+			}
+			postings, err := ledgerPostings(ctx, page.Outcomes)
+			if err != nil {
+				return ReconciliationReport{}, err
+			}
+			report.TransfersChecked += len(page.Outcomes)
+			report.PostingsChecked += len(postings)
+			report.Discrepancies = append(report.Discrepancies, findDiscrepancies(page.Outcomes, postings)...)
+
+			if len(page.NextPageToken) == 0 {
+				break
+			}
+			pageToken = page.NextPageToken
+		}
+		sortDiscrepancies(report.Discrepancies)
+	} else {
+		// Step 1: Find the transfers that closed in the window
+		var outcomes []TransferOutcome
+		err := policies.ExecuteActivity(ctx, reconciler.ListClosedTransfers, windowStart, windowEnd).Get(ctx, &outcomes)
+		if err != nil {
+			return ReconciliationReport{}, fmt.Errorf("list closed transfers: %w", err)
+		}
+
+		// Step 2: Load their ledger postings
+		postings, err := ledgerPostings(ctx, outcomes)
+		if err != nil {
+			return ReconciliationReport{}, err
+		}
+
+		// Step 3: Compare - this is plain deterministic code, so it runs in the workflow
+		report.TransfersChecked = len(outcomes)
+		report.PostingsChecked = len(postings)
+		report.Discrepancies = findDiscrepancies(outcomes, postings)
+	}
+	logger.Info("Reconciliation compared", "transfers", report.TransfersChecked, "discrepancies", len(report.Discrepancies))
+
+	// Step 4: Start fix-ups; abandoned children outlive this run's continue-as-new
+	// The ID names the discrepancy and a closed fix-up's ID is never reused, so
+	// overlapping passes that find the same discrepancy can't repair it twice
+	if input.AutoFix {
+		for _, d := range report.Discrepancies {
+			if !fixable(d) {
+				continue
+			}
+			childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+				WorkflowID:            fmt.Sprintf("fixup-%s-%s-%s", d.Kind, d.RunID, d.TxnIDs[len(d.TxnIDs)-1]),
+				WorkflowIDReusePolicy: enumspb.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE,
+				ParentClosePolicy:     enumspb.PARENT_CLOSE_POLICY_ABANDON,
+			})
+			child := workflow.ExecuteChildWorkflow(childCtx, DiscrepancyFixupWorkflow, d)
+			if err := child.GetChildWorkflowExecution().Get(ctx, nil); err != nil {
+				if temporal.IsWorkflowExecutionAlreadyStartedError(err) {
+					logger.Info("Fix-up already started by an earlier pass", "kind", d.Kind, "workflowID", d.WorkflowID)
+				} else {
+					logger.Error("Unable to start fix-up", "kind", d.Kind, "workflowID", d.WorkflowID, "error", err)
+				}
+				continue
+			}
+			report.FixupsStarted++
+		}
+	}
+
+	// Step 5: Export the report
+	err := policies.ExecuteActivity(ctx, reconciler.ExportReconciliationReport, report).Get(ctx, &report.ReportPath)
+	if err != nil {
+		return report, fmt.Errorf("export report: %w", err)
+	}
+	logger.Info("Reconciliation pass completed", "report", report.ReportPath, "fixups", report.FixupsStarted)
+
+	if input.Interval <= 0 {
+		return report, nil
+	}
+	if err := workflow.Sleep(ctx, input.Interval); err != nil {
+		return report, err
+	}
+	input.Since = windowEnd
+	return report, workflow.NewContinueAsNewError(ctx, ReconciliationWorkflow, input)
+}
+
+// ledgerPostings loads the ledger postings of the given transfer runs
+func ledgerPostings(ctx workflow.Context, outcomes []TransferOutcome) ([]Posting, error) {
+	runIDs := make([]string, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		runIDs = append(runIDs, outcome.RunID)
+	}
+	var reconciler *Reconciler
+	var postings []Posting
+	if err := policies.ExecuteActivity(ctx, reconciler.LedgerPostings, runIDs).Get(ctx, &postings); err != nil {
+		return nil, fmt.Errorf("load ledger postings: %w", err)
+	}
+	return postings, nil
+}
+
+// findDiscrepancies classifies ledger postings against transfer outcomes
+func findDiscrepancies(outcomes []TransferOutcome, postings []Posting) []Discrepancy {
+	byRun := make(map[string][]Posting)
+	for _, p := range postings {
+		byRun[p.RunID] = append(byRun[p.RunID], p)
+	}
+
+	var found []Discrepancy
+	for _, outcome := range outcomes {
+		// Reversals name the debit they reverse, so only unreversed debits count
+		reversed := make(map[string]bool)
+		for _, p := range byRun[outcome.RunID] {
+			if p.Kind == PostingReversal {
+				reversed[p.Reference] = true
+			}
+		}
+		var debits, credits []Posting
+		for _, p := range byRun[outcome.RunID] {
+			switch {
+			case p.Kind == PostingDebit && !reversed[p.TxnID]:
+				debits = append(debits, p)
+			case p.Kind == PostingCredit:
+				credits = append(credits, p)
+			}
+		}
+		report := func(kind string, postings []Posting, detail string) {
+			d := Discrepancy{Kind: kind, WorkflowID: outcome.WorkflowID, RunID: outcome.RunID, Detail: detail}
+			for _, p := range postings {
+				d.TxnIDs = append(d.TxnIDs, p.TxnID)
+				d.Leg, d.Account, d.Amount, d.Currency = p.Kind, p.Account, p.Amount, p.Currency
+			}
+			found = append(found, d)
+		}
+
+		if outcome.Status == enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED.String() {
+			// A completed transfer has exactly one debit and one credit
+			if len(debits) > 1 {
+				report(DiscrepancyDoublePosting, debits, fmt.Sprintf("%d debits posted for one transfer", len(debits)))
+			}
+			if len(credits) > 1 {
+				report(DiscrepancyDoublePosting, credits, fmt.Sprintf("%d credits posted for one transfer", len(credits)))
+			}
+			if len(credits) == 0 && outcome.Result != nil {
+				d := Discrepancy{
+					Kind:       DiscrepancyMissingCredit,
+					Leg:        PostingCredit,
+					WorkflowID: outcome.WorkflowID,
+					RunID:      outcome.RunID,
+					Account:    outcome.Result.ToAccount,
+					Amount:     outcome.Result.CreditedAmount,
+					Currency:   outcome.Result.ToCurrency,
+					TxnIDs:     []string{outcome.Result.CreditTxnID},
+					Detail:     "workflow reported a credit that is not in the ledger",
+				}
+				found = append(found, d)
+			}
+			continue
+		}
+
+		// The transfer did not complete: every debit must have been reversed and nothing credited
+		if len(debits) > 0 {
+			report(DiscrepancyOrphanDebit, debits,
+				fmt.Sprintf("transfer %s with %d unreversed debit(s)", outcome.Status, len(debits)))
+		}
+		if len(credits) > 0 {
+			report(DiscrepancyUnexpectedCredit, credits, fmt.Sprintf("transfer %s but the destination was credited", outcome.Status))
+		}
+	}
+
+	sortDiscrepancies(found)
+	return found
+}
+
+// sortDiscrepancies orders discrepancies by workflow ID, then kind
+// Stable order makes reports easy to diff between runs
+func sortDiscrepancies(found []Discrepancy) {
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].WorkflowID != found[j].WorkflowID {
+			return found[i].WorkflowID < found[j].WorkflowID
+		}
+		return found[i].Kind < found[j].Kind
+	})
+}
+
+// fixable reports whether DiscrepancyFixupWorkflow can repair a discrepancy
+// Unexpected credits and duplicate credits need a human to claw money back
+func fixable(d Discrepancy) bool {
+	if len(d.TxnIDs) == 0 {
+		return false
+	}
+	switch d.Kind {
+	case DiscrepancyOrphanDebit, DiscrepancyMissingCredit:
+		return true
+	case DiscrepancyDoublePosting:
+		return d.Leg == PostingDebit && len(d.TxnIDs) > 1
+	}
+	return false
+}
+
+// DiscrepancyFixupWorkflow repairs one discrepancy found by ReconciliationWorkflow
+// Orphan and duplicate debits are reversed; missing credits are re-posted
+func DiscrepancyFixupWorkflow(ctx workflow.Context, d Discrepancy) (string, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("DiscrepancyFixupWorkflow started", "kind", d.Kind, "workflowID", d.WorkflowID)
+
+	// Fix-ups must eventually succeed, so they retry without an attempt limit
+	// instead of using the catalog's policies
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute * 2,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+		},
+	})
+
+	switch d.Kind {
+	case DiscrepancyOrphanDebit:
+		for _, txnID := range d.TxnIDs {
+			if err := workflow.ExecuteActivity(ctx, CompensateDebit, d.Account, d.Amount, d.Currency, txnID).Get(ctx, nil); err != nil {
+				return "", fmt.Errorf("reverse orphan debit %s: %w", txnID, err)
+			}
+		}
+		return fmt.Sprintf("Reversed %d orphan debit(s) on %s", len(d.TxnIDs), d.Account), nil
+	case DiscrepancyDoublePosting:
+		// Keep the first debit, reverse the duplicates
+		for _, txnID := range d.TxnIDs[1:] {
+			if err := workflow.ExecuteActivity(ctx, CompensateDebit, d.Account, d.Amount, d.Currency, txnID).Get(ctx, nil); err != nil {
+				return "", fmt.Errorf("reverse duplicate debit %s: %w", txnID, err)
+			}
+		}
+		return fmt.Sprintf("Reversed %d duplicate debit(s) on %s", len(d.TxnIDs)-1, d.Account), nil
+	case DiscrepancyMissingCredit:
+		// RepostCredit posts under the transfer's own run and credit ID, so a
+		// later pass sees the credit; fix-ups started before it used CreditAccount
+		var txnID string
+		var credit workflow.Future
+		if workflow.GetVersion(ctx, "repost-credit", workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+			credit = workflow.ExecuteActivity(ctx, CreditAccount, d.Account, d.Amount, d.Currency, "fixup "+d.WorkflowID)
+		} else {
+			credit = workflow.ExecuteActivity(ctx, RepostCredit, d)
+		}
+		if err := credit.Get(ctx, &txnID); err != nil {
+			return "", fmt.Errorf("re-post missing credit: %w", err)
+		}
+		return fmt.Sprintf("Re-posted missing credit to %s (%s)", d.Account, txnID), nil
+	}
+	return "", errs.ManualReviewRequired.New(fmt.Sprintf("discrepancy kind %s needs manual review", d.Kind), d)
+}
+
+// Reconciler holds the dependencies of the reconciliation activities
+type Reconciler struct {
+	Client    client.Client
+	Ledger    *Ledger
+	ReportDir string // Where reports are written; defaults to the OS temp directory
+}
+
+// TransferPage is one page of closed transfers from ListClosedTransfersPage
+type TransferPage struct {
+	Outcomes      []TransferOutcome `json:"outcomes"`
+	NextPageToken []byte            `json:"next_page_token,omitempty"` // Empty on the last page
+}
+
+// ListClosedTransfersPage lists one page of MoneyTransferWorkflow runs that
+// closed in [start, end) through visibility, loading each completed run's
+// result and each failed run's error type
+// Pass the previous page's NextPageToken to get the next page; at most
+// closedTransfersPageSize runs come back per call
+func (r *Reconciler) ListClosedTransfersPage(ctx context.Context, start, end time.Time, pageToken []byte) (TransferPage, error) {
+	resp, err := r.Client.ListWorkflow(ctx, &workflowservice.ListWorkflowExecutionsRequest{
+		Namespace:     shared.Namespace,
+		PageSize:      closedTransfersPageSize,
+		Query:         closedTransfersQuery(start, end),
+		NextPageToken: pageToken,
+	})
+	if err != nil {
+		return TransferPage{}, err
+	}
+
+	page := TransferPage{NextPageToken: resp.GetNextPageToken()}
+	for _, info := range resp.GetExecutions() {
+		outcome := TransferOutcome{
+			WorkflowID: info.GetExecution().GetWorkflowId(),
+			RunID:      info.GetExecution().GetRunId(),
+			Status:     info.GetStatus().String(),
+			ClosedAt:   info.GetCloseTime().AsTime(),
+		}
+
+		// Get on a closed run returns immediately with its result or failure
+		var result TransferResult
+		err := r.Client.GetWorkflow(ctx, outcome.WorkflowID, outcome.RunID).Get(ctx, &result)
+		if err == nil {
+			outcome.Result = &result
+		} else {
+			outcome.ErrorType = errs.Classify(err).Type
+		}
+		page.Outcomes = append(page.Outcomes, outcome)
+		activity.RecordHeartbeat(ctx, len(page.Outcomes))
+	}
+
+	activity.GetLogger(ctx).Info("Closed transfers listed", "count", len(page.Outcomes), "more", len(page.NextPageToken) > 0)
+	return page, nil
+}
+
+// ListClosedTransfers lists every MoneyTransferWorkflow run that closed in [start, end)
+// Only reconciliation passes started before ListClosedTransfersPage call it;
+// a busy window can outgrow the activity result size limit
+func (r *Reconciler) ListClosedTransfers(ctx context.Context, start, end time.Time) ([]TransferOutcome, error) {
+	var outcomes []TransferOutcome
+	var pageToken []byte
+	for {
+		page, err := r.ListClosedTransfersPage(ctx, start, end, pageToken)
+		if err != nil {
+			return nil, err
+		}
+		outcomes = append(outcomes, page.Outcomes...)
+		if len(page.NextPageToken) == 0 {
+			return outcomes, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// closedTransfersQuery is the visibility query for transfers closed in [start, end)
+func closedTransfersQuery(start, end time.Time) string {
+	return fmt.Sprintf("WorkflowType = 'MoneyTransferWorkflow' AND ExecutionStatus != 'Running' AND CloseTime >= '%s' AND CloseTime < '%s'",
+		start.UTC().Format(time.RFC3339Nano), end.UTC().Format(time.RFC3339Nano))
+}
+
+// LedgerPostings returns the ledger postings made by the given workflow runs
+func (r *Reconciler) LedgerPostings(ctx context.Context, runIDs []string) ([]Posting, error) {
+	wanted := make(map[string]bool, len(runIDs))
+	for _, id := range runIDs {
+		wanted[id] = true
+	}
+	postings, err := r.Ledger.PostingsForRuns(wanted)
+	if err != nil {
+		return nil, errs.DependencyFailure.Wrap(err, "ledger unavailable")
+	}
+	return postings, nil
+}
+
+// ExportReconciliationReport writes the report as JSON and returns its path
+func (r *Reconciler) ExportReconciliationReport(ctx context.Context, report ReconciliationReport) (string, error) {
+	dir := r.ReportDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("reconciliation-%s.json", report.WindowEnd.UTC().Format("20060102T150405Z")))
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", errs.Internal.Wrap(err, "encode reconciliation report")
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+
+	activity.GetLogger(ctx).Info("Reconciliation report exported", "path", path, "discrepancies", len(report.Discrepancies))
+	return path, nil
+}
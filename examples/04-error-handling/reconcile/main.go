@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"go.temporal.io/sdk/client"
+
+	errors "temporal-go-examples/examples/04-error-handling"
+	"temporal-go-examples/shared"
+)
+
+func main() {
+	lookback := flag.Duration("lookback", time.Hour, "Reconcile transfers closed within this long")
+	interval := flag.Duration("interval", 0, "Keep reconciling on this interval (0 runs a single pass)")
+	autoFix := flag.Bool("autofix", false, "Start fix-up workflows for fixable discrepancies")
+	lag := flag.Duration("lag", errors.DefaultVisibilityLag, "How long visibility may take to list a closed transfer; windows end this long ago")
+	flag.Parse()
+
+	// Create Temporal client
+	c, err := shared.CreateTemporalClient()
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	input := errors.ReconciliationInput{
+		Lookback:      *lookback,
+		Interval:      *interval,
+		AutoFix:       *autoFix,
+		VisibilityLag: *lag,
+	}
+
+	// A fixed ID means at most one periodic reconciler runs at a time
+	workflowID := "reconciliation"
+	if *interval == 0 {
+		workflowID = "reconciliation-" + shared.RandomID()
+	}
+	workflowRun, err := c.ExecuteWorkflow(context.Background(), client.StartWorkflowOptions{
+		ID:        workflowID,
+		TaskQueue: shared.TaskQueue,
+	}, errors.ReconciliationWorkflow, input)
+	if err != nil {
+		log.Fatalln("Unable to execute workflow", err)
+	}
+	shared.LogInfo("ReconciliationWorkflow started! WorkflowID: %s", workflowRun.GetID())
+
+	if *interval > 0 {
+		shared.LogInfo("Reconciling every %s - reports are written by the worker", *interval)
+		return
+	}
+
+	var report errors.ReconciliationReport
+	if err := workflowRun.Get(context.Background(), &report); err != nil {
+		log.Fatalln("Reconciliation failed", err)
+	}
+
+	shared.LogInfo("🔎 Checked %d transfers and %d postings between %s and %s",
+		report.TransfersChecked, report.PostingsChecked,
+		report.WindowStart.Format(time.RFC3339), report.WindowEnd.Format(time.RFC3339))
+	if len(report.Discrepancies) == 0 {
+		shared.LogInfo("✅ Ledger matches transfer outcomes")
+	}
+	for _, d := range report.Discrepancies {
+		shared.LogError("%s on %s: %.2f %s (%s) - %s", d.Kind, d.Account, d.Amount, d.Currency, d.WorkflowID, d.Detail)
+	}
+	shared.LogInfo("Fix-ups started: %d", report.FixupsStarted)
+	shared.LogInfo("Report: %s", report.ReportPath)
+}
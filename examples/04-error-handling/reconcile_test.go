@@ -0,0 +1,200 @@
+package errors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/testsuite"
+)
+
+var (
+	completed = enumspb.WORKFLOW_EXECUTION_STATUS_COMPLETED.String()
+	failed    = enumspb.WORKFLOW_EXECUTION_STATUS_FAILED.String()
+)
+
+func posting(run, txnID, kind, reference string) Posting {
+	account := "account-123"
+	if kind == PostingCredit {
+		account = "account-456"
+	}
+	return Posting{TxnID: txnID, WorkflowID: "wf-" + run, RunID: run, Kind: kind, Account: account, Amount: 100, Currency: "USD", Reference: reference}
+}
+
+func outcome(run, status string) TransferOutcome {
+	o := TransferOutcome{WorkflowID: "wf-" + run, RunID: run, Status: status}
+	if status == completed {
+		o.Result = &TransferResult{ToAccount: "account-456", CreditTxnID: "credit-" + run, CreditedAmount: 100, ToCurrency: "USD"}
+	}
+	return o
+}
+
+func TestFindDiscrepancies(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		outcome  TransferOutcome
+		postings []Posting
+		want     []Discrepancy
+	}{
+		{
+			name:    "completed transfer with one debit and one credit",
+			outcome: outcome("ok", completed),
+			postings: []Posting{
+				posting("ok", "debit-ok", PostingDebit, ""),
+				posting("ok", "credit-ok", PostingCredit, ""),
+			},
+		},
+		{
+			name:    "completed transfer debited twice",
+			outcome: outcome("dd", completed),
+			postings: []Posting{
+				posting("dd", "debit-1", PostingDebit, ""),
+				posting("dd", "debit-2", PostingDebit, ""),
+				posting("dd", "credit-dd", PostingCredit, ""),
+			},
+			want: []Discrepancy{{Kind: DiscrepancyDoublePosting, Leg: PostingDebit, Account: "account-123", TxnIDs: []string{"debit-1", "debit-2"}}},
+		},
+		{
+			name:    "completed transfer credited twice",
+			outcome: outcome("dc", completed),
+			postings: []Posting{
+				posting("dc", "debit-dc", PostingDebit, ""),
+				posting("dc", "credit-1", PostingCredit, ""),
+				posting("dc", "credit-2", PostingCredit, ""),
+			},
+			want: []Discrepancy{{Kind: DiscrepancyDoublePosting, Leg: PostingCredit, Account: "account-456", TxnIDs: []string{"credit-1", "credit-2"}}},
+		},
+		{
+			name:     "completed transfer never credited",
+			outcome:  outcome("mc", completed),
+			postings: []Posting{posting("mc", "debit-mc", PostingDebit, "")},
+			want:     []Discrepancy{{Kind: DiscrepancyMissingCredit, Leg: PostingCredit, Account: "account-456", TxnIDs: []string{"credit-mc"}}},
+		},
+		{
+			name:     "failed transfer left its debit",
+			outcome:  outcome("od", failed),
+			postings: []Posting{posting("od", "debit-od", PostingDebit, "")},
+			want:     []Discrepancy{{Kind: DiscrepancyOrphanDebit, Leg: PostingDebit, Account: "account-123", TxnIDs: []string{"debit-od"}}},
+		},
+		{
+			name:    "failed transfer reversed its debit",
+			outcome: outcome("rv", failed),
+			postings: []Posting{
+				posting("rv", "debit-rv", PostingDebit, ""),
+				posting("rv", "reversal-rv", PostingReversal, "debit-rv"),
+			},
+		},
+		{
+			name:    "failed transfer credited the destination",
+			outcome: outcome("uc", failed),
+			postings: []Posting{
+				posting("uc", "debit-uc", PostingDebit, ""),
+				posting("uc", "reversal-uc", PostingReversal, "debit-uc"),
+				posting("uc", "credit-uc", PostingCredit, ""),
+			},
+			want: []Discrepancy{{Kind: DiscrepancyUnexpectedCredit, Leg: PostingCredit, Account: "account-456", TxnIDs: []string{"credit-uc"}}},
+		},
+		{
+			name:    "postings of other runs are ignored",
+			outcome: outcome("mine", failed),
+			postings: []Posting{
+				posting("other", "debit-other", PostingDebit, ""),
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			found := findDiscrepancies([]TransferOutcome{test.outcome}, test.postings)
+
+			require.Len(t, found, len(test.want))
+			for i, want := range test.want {
+				assert.Equal(t, want.Kind, found[i].Kind)
+				assert.Equal(t, want.Leg, found[i].Leg)
+				assert.Equal(t, want.Account, found[i].Account)
+				assert.Equal(t, want.TxnIDs, found[i].TxnIDs)
+				assert.Equal(t, test.outcome.WorkflowID, found[i].WorkflowID)
+				assert.Equal(t, test.outcome.RunID, found[i].RunID)
+				assert.Equal(t, 100.0, found[i].Amount)
+			}
+		})
+	}
+}
+
+func TestFixableDiscrepancies(t *testing.T) {
+	assert.True(t, fixable(Discrepancy{Kind: DiscrepancyOrphanDebit, TxnIDs: []string{"d"}}))
+	assert.True(t, fixable(Discrepancy{Kind: DiscrepancyMissingCredit, TxnIDs: []string{"c"}}))
+	assert.True(t, fixable(Discrepancy{Kind: DiscrepancyDoublePosting, Leg: PostingDebit, TxnIDs: []string{"d1", "d2"}}))
+	assert.False(t, fixable(Discrepancy{Kind: DiscrepancyDoublePosting, Leg: PostingCredit, TxnIDs: []string{"c1", "c2"}}))
+	assert.False(t, fixable(Discrepancy{Kind: DiscrepancyUnexpectedCredit, TxnIDs: []string{"c"}}))
+	assert.False(t, fixable(Discrepancy{Kind: DiscrepancyOrphanDebit}))
+}
+
+func TestReconciliationPagesAndLagsTheWindow(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	start := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	env.SetStartTime(start)
+	env.RegisterWorkflow(ReconciliationWorkflow)
+	reconciler := &Reconciler{}
+	env.RegisterActivity(reconciler)
+
+	// Two pages; each is compared with only its own postings
+	pages := map[string]TransferPage{
+		"": {
+			Outcomes:      []TransferOutcome{outcome("b", failed), outcome("a", completed)},
+			NextPageToken: []byte("page-2"),
+		},
+		"page-2": {Outcomes: []TransferOutcome{outcome("c", failed)}},
+	}
+	var windowEnds []time.Time
+	env.OnActivity(reconciler.ListClosedTransfersPage, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, from, to time.Time, pageToken []byte) (TransferPage, error) {
+			windowEnds = append(windowEnds, to)
+			return pages[string(pageToken)], nil
+		})
+	ledger := map[string][]Posting{
+		"a": {posting("a", "debit-a", PostingDebit, ""), posting("a", "credit-a", PostingCredit, "")},
+		"b": {posting("b", "debit-b", PostingDebit, "")},
+		"c": {posting("c", "credit-c", PostingCredit, "")},
+	}
+	var postingCalls [][]string
+	env.OnActivity(reconciler.LedgerPostings, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, runIDs []string) ([]Posting, error) {
+			postingCalls = append(postingCalls, runIDs)
+			var postings []Posting
+			for _, runID := range runIDs {
+				postings = append(postings, ledger[runID]...)
+			}
+			return postings, nil
+		})
+	env.OnActivity(reconciler.ExportReconciliationReport, mock.Anything, mock.Anything).Return("report.json", nil)
+
+	env.ExecuteWorkflow(ReconciliationWorkflow, ReconciliationInput{Lookback: time.Hour, VisibilityLag: 5 * time.Minute})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var report ReconciliationReport
+	require.NoError(t, env.GetWorkflowResult(&report))
+
+	// The window ends the lag before the pass, so late-indexed transfers fall in the next one
+	wantEnd := start.Add(-5 * time.Minute)
+	assert.True(t, wantEnd.Equal(report.WindowEnd), "window end %s", report.WindowEnd)
+	assert.True(t, wantEnd.Add(-time.Hour).Equal(report.WindowStart), "window start %s", report.WindowStart)
+	require.Len(t, windowEnds, 2)
+	for _, end := range windowEnds {
+		assert.True(t, wantEnd.Equal(end), "listed up to %s", end)
+	}
+
+	assert.Equal(t, [][]string{{"b", "a"}, {"c"}}, postingCalls)
+	assert.Equal(t, 3, report.TransfersChecked)
+	assert.Equal(t, 4, report.PostingsChecked)
+	require.Len(t, report.Discrepancies, 2)
+	assert.Equal(t, DiscrepancyOrphanDebit, report.Discrepancies[0].Kind)
+	assert.Equal(t, "wf-b", report.Discrepancies[0].WorkflowID)
+	assert.Equal(t, DiscrepancyUnexpectedCredit, report.Discrepancies[1].Kind)
+	assert.Equal(t, "wf-c", report.Discrepancies[1].WorkflowID)
+	assert.Equal(t, "report.json", report.ReportPath)
+}
@@ -0,0 +1,243 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// ApprovalSignal is the signal a reviewer sends to approve or reject a held transfer
+	ApprovalSignal = "approve-transfer"
+
+	// Risk outcomes recorded in the RiskDecision
+	RiskOutcomeAutoApproved     = "auto-approved"
+	RiskOutcomeManuallyApproved = "manually-approved"
+	RiskOutcomeRejected         = "rejected"
+	RiskOutcomeManuallyRejected = "manually-rejected"
+	RiskOutcomeApprovalTimeout  = "auto-rejected-timeout"
+)
+
+// RiskRules configures the pre-transfer risk stage
+// Amounts are in DefaultCurrency; transfers are converted at the mid rate before comparing
+type RiskRules struct {
+	DailyLimit             float64  `json:"daily_limit"`              // Max total debited per account per day
+	VelocityLimit          int      `json:"velocity_limit"`           // Max transfers per account per velocity window
+	VelocityWindowSeconds  int      `json:"velocity_window_seconds"`  // Length of the velocity window
+	LargeAmount            float64  `json:"large_amount"`             // Single transfers above this add to the score
+	BlockedAccounts        []string `json:"blocked_accounts"`         // Accounts that can never send or receive
+	ReviewThreshold        int      `json:"review_threshold"`         // Scores above this need manual approval
+	ApprovalTimeoutSeconds int      `json:"approval_timeout_seconds"` // How long to wait for a reviewer
+}
+
+// DefaultRiskRules returns the rules used when no config file is provided
+func DefaultRiskRules() RiskRules {
+	return RiskRules{
+		DailyLimit:             10000,
+		VelocityLimit:          5,
+		VelocityWindowSeconds:  60,
+		LargeAmount:            5000,
+		BlockedAccounts:        []string{"blocked-account"},
+		ReviewThreshold:        50,
+		ApprovalTimeoutSeconds: 300,
+	}
+}
+
+// LoadRiskRules reads rules from a JSON file
+// Fields missing from the file keep their default values
+func LoadRiskRules(path string) (RiskRules, error) {
+	rules := DefaultRiskRules()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, fmt.Errorf("read risk rules: %w", err)
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return rules, fmt.Errorf("parse risk rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// RiskAssessment is the score produced by the rules engine
+type RiskAssessment struct {
+	Score            int           `json:"score"`
+	Reasons          []string      `json:"reasons"`
+	Blocked          bool          `json:"blocked"`           // Hard rejection, no review possible
+	RequiresApproval bool          `json:"requires_approval"` // Score is above the review threshold
+	ApprovalTimeout  time.Duration `json:"approval_timeout"`
+}
+
+// ApprovalDecision is the payload of the ApprovalSignal
+type ApprovalDecision struct {
+	Approved bool   `json:"approved"`
+	Reviewer string `json:"reviewer"`
+	Note     string `json:"note"`
+}
+
+// RiskDecision records how the risk stage ended
+type RiskDecision struct {
+	Score    int      `json:"score"`
+	Reasons  []string `json:"reasons"`
+	Outcome  string   `json:"outcome"`
+	Approved bool     `json:"approved"`
+	Reviewer string   `json:"reviewer,omitempty"`
+	Note     string   `json:"note,omitempty"`
+}
+
+// riskEntry is one transfer counted against an account's limits
+type riskEntry struct {
+	at     time.Time
+	amount float64 // In DefaultCurrency
+}
+
+// RiskChecker scores transfers against RiskRules
+// It is registered as an activity struct so the worker can hand it loaded rules
+type RiskChecker struct {
+	Rules RiskRules
+
+	mu      sync.Mutex
+	history map[string]map[string]riskEntry // account -> workflow ID -> entry
+}
+
+// NewRiskChecker creates a RiskChecker with the given rules
+func NewRiskChecker(rules RiskRules) *RiskChecker {
+	return &RiskChecker{
+		Rules:   rules,
+		history: make(map[string]map[string]riskEntry),
+	}
+}
+
+// AssessTransferRisk scores a transfer against blocklists, amount thresholds,
+// and the daily and velocity limits of the source account
+// It only reads the history; RecordTransfer adds the transfer once money moved
+func (r *RiskChecker) AssessTransferRisk(ctx context.Context, request TransferRequest) (RiskAssessment, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Assessing transfer risk", "from", request.FromAccount, "amount", request.Amount, "currency", request.FromCurrency)
+
+	amount, err := toBaseCurrency(request.Amount, request.FromCurrency)
+	if err != nil {
+		return RiskAssessment{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	assessment := RiskAssessment{
+		ApprovalTimeout: time.Duration(r.Rules.ApprovalTimeoutSeconds) * time.Second,
+	}
+	flag := func(score int, reason string) {
+		assessment.Score += score
+		assessment.Reasons = append(assessment.Reasons, reason)
+	}
+
+	for _, blocked := range r.Rules.BlockedAccounts {
+		if request.FromAccount == blocked || request.ToAccount == blocked {
+			assessment.Blocked = true
+			flag(100, fmt.Sprintf("account %s is blocklisted", blocked))
+		}
+	}
+
+	if r.Rules.LargeAmount > 0 && amount > r.Rules.LargeAmount {
+		flag(40, fmt.Sprintf("amount %.2f %s exceeds large-amount threshold %.2f", amount, DefaultCurrency, r.Rules.LargeAmount))
+	}
+
+	// Count earlier transfers from this account; entries are keyed by workflow ID
+	// so a retried activity does not count the same transfer twice
+	now := time.Now()
+	workflowID := activity.GetInfo(ctx).WorkflowExecution.ID
+	window := time.Duration(r.Rules.VelocityWindowSeconds) * time.Second
+	dailyTotal, recent := amount, 1
+	for id, entry := range r.history[request.FromAccount] {
+		if id == workflowID {
+			continue
+		}
+		if now.Sub(entry.at) < 24*time.Hour {
+			dailyTotal += entry.amount
+		}
+		if now.Sub(entry.at) < window {
+			recent++
+		}
+	}
+	if r.Rules.DailyLimit > 0 && dailyTotal > r.Rules.DailyLimit {
+		flag(60, fmt.Sprintf("daily total %.2f %s exceeds limit %.2f", dailyTotal, DefaultCurrency, r.Rules.DailyLimit))
+	}
+	if r.Rules.VelocityLimit > 0 && recent > r.Rules.VelocityLimit {
+		flag(30, fmt.Sprintf("%d transfers in %s exceeds velocity limit %d", recent, window, r.Rules.VelocityLimit))
+	}
+
+	assessment.RequiresApproval = !assessment.Blocked && assessment.Score > r.Rules.ReviewThreshold
+
+	logger.Info("Risk assessment complete", "score", assessment.Score, "reasons", assessment.Reasons)
+	return assessment, nil
+}
+
+// RecordTransfer counts a debited transfer against the source account's limits
+// Transfers the reviewer rejected, or that failed before the debit, never count
+// Entries are keyed by workflow ID, so a retried activity records it only once
+func (r *RiskChecker) RecordTransfer(ctx context.Context, request TransferRequest) error {
+	amount, err := toBaseCurrency(request.Amount, request.FromCurrency)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.history[request.FromAccount] == nil {
+		r.history[request.FromAccount] = make(map[string]riskEntry)
+	}
+	workflowID := activity.GetInfo(ctx).WorkflowExecution.ID
+	r.history[request.FromAccount][workflowID] = riskEntry{at: time.Now(), amount: amount}
+	return nil
+}
+
+// awaitRiskDecision turns an assessment into a decision, holding the workflow
+// for a reviewer's ApprovalSignal when the score needs manual approval
+func awaitRiskDecision(ctx workflow.Context, assessment RiskAssessment) RiskDecision {
+	logger := workflow.GetLogger(ctx)
+	decision := RiskDecision{
+		Score:   assessment.Score,
+		Reasons: assessment.Reasons,
+	}
+
+	switch {
+	case assessment.Blocked:
+		decision.Outcome = RiskOutcomeRejected
+		return decision
+	case !assessment.RequiresApproval:
+		decision.Outcome = RiskOutcomeAutoApproved
+		decision.Approved = true
+		return decision
+	}
+
+	logger.Info("Transfer held for manual approval", "score", assessment.Score, "timeout", assessment.ApprovalTimeout)
+
+	// Cancel the timer once a reviewer answers so it doesn't linger in history
+	timerCtx, cancelTimer := workflow.WithCancel(ctx)
+	defer cancelTimer()
+
+	selector := workflow.NewSelector(ctx)
+	selector.AddReceive(workflow.GetSignalChannel(ctx, ApprovalSignal), func(c workflow.ReceiveChannel, more bool) {
+		var approval ApprovalDecision
+		c.Receive(ctx, &approval)
+		decision.Approved = approval.Approved
+		decision.Reviewer = approval.Reviewer
+		decision.Note = approval.Note
+		decision.Outcome = RiskOutcomeManuallyRejected
+		if approval.Approved {
+			decision.Outcome = RiskOutcomeManuallyApproved
+		}
+		logger.Info("Reviewer decision received", "approved", approval.Approved, "reviewer", approval.Reviewer)
+	})
+	selector.AddFuture(workflow.NewTimer(timerCtx, assessment.ApprovalTimeout), func(f workflow.Future) {
+		decision.Outcome = RiskOutcomeApprovalTimeout
+		logger.Warn("No reviewer decision before timeout, rejecting transfer")
+	})
+	selector.Select(ctx)
+
+	return decision
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+
+	errors "temporal-go-examples/examples/04-error-handling"
+	"temporal-go-examples/shared"
+)
+
+const usage = `Usage: go run schedule/main.go <command> [flags]
+
+Commands:
+  create    Create a recurring MoneyTransferWorkflow schedule
+  update    Change the timing, policies or transfer of a schedule; only the flags given change
+  pause     Stop a schedule from starting new transfers
+  unpause   Resume a paused schedule
+  trigger   Start a transfer right now
+  backfill  Run the transfers a schedule would have made in a past time range
+  describe  Show a schedule and its recent runs
+  delete    Remove a schedule
+
+Run "go run schedule/main.go <command> -h" for command flags.`
+
+// defaultCron is used by create when neither -cron nor -every is given: 09:00 on the 1st of every month
+const defaultCron = "0 9 1 * *"
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	id := flags.String("id", "rent-payment", "Schedule ID")
+	note := flags.String("note", "", "Note recorded with the change")
+	overlapName := flags.String("overlap", "", "Overlap policy: skip, buffer-one, buffer-all, cancel-other, terminate-other, allow-all")
+
+	// Timing and transfer flags are only used by create and update
+	cron := flags.String("cron", "", "Cron expression (default without -every: 09:00 on the 1st of every month)")
+	every := flags.Duration("every", 0, "Fixed interval instead of or in addition to -cron, e.g. 24h")
+	timeZone := flags.String("tz", "", "IANA time zone for -cron, e.g. Europe/London")
+	catchup := flags.Duration("catchup", time.Hour*24, "How far back missed runs are caught up after downtime")
+	jitter := flags.Duration("jitter", 0, "Random delay added to each run")
+	from := flags.String("from", "account-123", "Source account")
+	to := flags.String("to", "landlord-456", "Destination account")
+	amount := flags.Float64("amount", 1500, "Amount in the source currency")
+	fromCurrency := flags.String("from-currency", "", "Source currency (default USD)")
+	toCurrency := flags.String("to-currency", "", "Destination currency (default USD)")
+	reference := flags.String("reference", "Monthly rent", "Transfer reference")
+
+	// Backfill range
+	start := flags.String("start", "", "Backfill start time (RFC3339)")
+	end := flags.String("end", "", "Backfill end time (RFC3339, default now)")
+
+	if err := flags.Parse(args); err != nil {
+		log.Fatalln(err)
+	}
+
+	overlap, err := shared.ParseOverlapPolicy(*overlapName)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// Create Temporal client
+	c, err := shared.CreateTemporalClient()
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	cfg := shared.ScheduleConfig{
+		ID:            *id,
+		Interval:      *every,
+		TimeZone:      *timeZone,
+		Overlap:       overlap,
+		CatchupWindow: *catchup,
+		Jitter:        *jitter,
+		Note:          *note,
+	}
+	if *cron != "" {
+		cfg.CronExpressions = []string{*cron}
+	} else if *every == 0 {
+		// An -every schedule runs on its interval alone; only a schedule with
+		// no timing at all gets the monthly default. update ignores this unless
+		// -cron is given, which then removes the cron expressions
+		cfg.CronExpressions = []string{defaultCron}
+	}
+	request := errors.TransferRequest{
+		FromAccount:  *from,
+		ToAccount:    *to,
+		Amount:       *amount,
+		FromCurrency: *fromCurrency,
+		ToCurrency:   *toCurrency,
+		Reference:    *reference,
+	}
+
+	switch command {
+	case "create":
+		_, err = shared.CreateSchedule(ctx, c, cfg, errors.MoneyTransferWorkflow, request)
+	case "update":
+		err = shared.UpdateSchedule(ctx, c, *id, changedFlags(flags, cfg, overlap, request))
+	case "pause":
+		err = shared.PauseSchedule(ctx, c, *id, *note)
+	case "unpause":
+		err = shared.UnpauseSchedule(ctx, c, *id, *note)
+	case "trigger":
+		err = shared.TriggerSchedule(ctx, c, *id, overlap)
+	case "backfill":
+		var startTime, endTime time.Time
+		startTime, endTime, err = parseRange(*start, *end)
+		if err == nil {
+			err = shared.BackfillSchedule(ctx, c, *id, startTime, endTime, overlap)
+		}
+	case "describe":
+		err = describe(ctx, c, *id)
+	case "delete":
+		err = shared.DeleteSchedule(ctx, c, *id)
+	default:
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatalf("Schedule %s failed: %v", command, err)
+	}
+	shared.LogInfo("✅ Schedule %s: %s done", *id, command)
+}
+
+// changedFlags turns the flags given on the command line into schedule changes
+// Flags left out keep the schedule's current value instead of their defaults
+func changedFlags(flags *flag.FlagSet, cfg shared.ScheduleConfig, overlap enumspb.ScheduleOverlapPolicy, request errors.TransferRequest) shared.ScheduleChanges {
+	var changes shared.ScheduleChanges
+	transferFlags := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "cron":
+			changes.CronExpressions = append([]string{}, cfg.CronExpressions...)
+		case "every":
+			changes.Interval = &cfg.Interval
+		case "tz":
+			changes.TimeZone = &cfg.TimeZone
+		case "overlap":
+			changes.Overlap = &overlap
+		case "catchup":
+			changes.CatchupWindow = &cfg.CatchupWindow
+		case "jitter":
+			changes.Jitter = &cfg.Jitter
+		case "note":
+			changes.Note = &cfg.Note
+		case "from", "to", "amount", "from-currency", "to-currency", "reference":
+			transferFlags[f.Name] = true
+		}
+	})
+	if len(transferFlags) == 0 {
+		return changes
+	}
+
+	// Start from the scheduled transfer and only replace the fields given
+	changes.Args = func(current []interface{}) ([]interface{}, error) {
+		var scheduled errors.TransferRequest
+		if len(current) > 0 {
+			if err := shared.DecodeScheduleArg(current[0], &scheduled); err != nil {
+				return nil, fmt.Errorf("decode scheduled transfer: %w", err)
+			}
+		}
+		for name := range transferFlags {
+			switch name {
+			case "from":
+				scheduled.FromAccount = request.FromAccount
+			case "to":
+				scheduled.ToAccount = request.ToAccount
+			case "amount":
+				scheduled.Amount = request.Amount
+			case "from-currency":
+				scheduled.FromCurrency = request.FromCurrency
+			case "to-currency":
+				scheduled.ToCurrency = request.ToCurrency
+			case "reference":
+				scheduled.Reference = request.Reference
+			}
+		}
+		return []interface{}{scheduled}, nil
+	}
+	return changes
+}
+
+// parseRange parses the backfill time range; the end defaults to now
+func parseRange(start, end string) (time.Time, time.Time, error) {
+	if start == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("backfill needs -start")
+	}
+	startTime, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid -start: %w", err)
+	}
+	endTime := time.Now()
+	if end != "" {
+		if endTime, err = time.Parse(time.RFC3339, end); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -end: %w", err)
+		}
+	}
+	return startTime, endTime, nil
+}
+
+// describe prints the schedule's state, next runs and recent runs
+func describe(ctx context.Context, c client.Client, id string) error {
+	description, err := shared.DescribeSchedule(ctx, c, id)
+	if err != nil {
+		return err
+	}
+
+	if state := description.Schedule.State; state != nil {
+		shared.LogInfo("📅 Schedule %s (paused: %v, note: %q)", id, state.Paused, state.Note)
+	}
+	if spec := description.Schedule.Spec; spec != nil {
+		shared.LogInfo("   Cron: %v, Jitter: %s, Time zone: %q", spec.CronExpressions, spec.Jitter, spec.TimeZoneName)
+	}
+	if policy := description.Schedule.Policy; policy != nil {
+		shared.LogInfo("   Overlap: %s, Catch-up window: %s", policy.Overlap, policy.CatchupWindow)
+	}
+	for _, next := range description.Info.NextActionTimes {
+		shared.LogInfo("   ⏭️  Next run: %s", next.Format(time.RFC3339))
+	}
+	for _, action := range description.Info.RecentActions {
+		if action.StartWorkflowResult != nil {
+			shared.LogInfo("   ✅ Ran at %s: %s", action.ActualTime.Format(time.RFC3339), action.StartWorkflowResult.WorkflowID)
+		}
+	}
+	return nil
+}
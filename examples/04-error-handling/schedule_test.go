@@ -0,0 +1,70 @@
+package errors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+// scheduledTransfers starts MoneyTransferWorkflow on a cron spec, the way a
+// schedule created by schedule/main.go does
+func scheduledTransfers(ctx workflow.Context, cron string, request TransferRequest) error {
+	ctx = workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+		WorkflowID:   "rent-payment-run",
+		CronSchedule: cron,
+	})
+	return workflow.ExecuteChildWorkflow(ctx, MoneyTransferWorkflow, request).Get(ctx, nil)
+}
+
+func TestRentScheduleRunsOnTheFirstOfEveryMonth(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetStartTime(time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC))
+
+	env.RegisterWorkflow(scheduledTransfers)
+	env.RegisterWorkflow(MoneyTransferWorkflow)
+	env.RegisterActivity(NewRiskChecker(DefaultRiskRules()))
+	env.RegisterActivity(ValidateAccounts)
+	env.RegisterActivity(GetFXQuote)
+	env.RegisterActivity(DebitAccount)
+	env.RegisterActivity(CreditAtQuote)
+
+	env.OnActivity(ValidateAccounts, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(GetFXQuote, mock.Anything, mock.Anything, mock.Anything).Return(FXQuote{
+		QuoteID: "fxq", From: "USD", To: "USD", MidRate: 1, Rate: 1,
+		ExpiresAt: time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}, nil)
+	env.OnActivity(CreditAtQuote, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("credit", nil)
+
+	// Each debit is one scheduled run; the test clock says when it happened
+	var runs []time.Time
+	env.OnActivity(DebitAccount, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, account string, amount float64, currency, reference string) (string, error) {
+			runs = append(runs, env.Now().UTC())
+			return "debit", nil
+		})
+
+	// Time skipping jumps straight from one run to the next
+	env.RegisterDelayedCallback(env.CancelWorkflow, 95*24*time.Hour)
+	env.ExecuteWorkflow(scheduledTransfers, "0 9 1 * *", TransferRequest{
+		FromAccount: "account-123",
+		ToAccount:   "landlord-456",
+		Amount:      1500,
+		Reference:   "Monthly rent",
+	})
+
+	// Unlike the server, the test environment starts the first cron run
+	// right away; every run after it must follow the timetable
+	require.True(t, env.IsWorkflowCompleted())
+	require.GreaterOrEqual(t, len(runs), 4)
+	for i, month := range []time.Month{time.February, time.March, time.April} {
+		want := time.Date(2025, month, 1, 9, 0, 0, 0, time.UTC)
+		assert.WithinDuration(t, want, runs[i+1], time.Minute, "run %d", i+1)
+	}
+}
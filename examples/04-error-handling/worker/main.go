@@ -2,9 +2,12 @@ package main
 
 import (
 	"log"
+	"os"
 
 	errors "temporal-go-examples/examples/04-error-handling"
 	"temporal-go-examples/shared"
+	"temporal-go-examples/shared/circuit"
+	"temporal-go-examples/shared/policies"
 )
 
 func main() {
@@ -15,22 +18,59 @@ func main() {
 	}
 	defer c.Close()
 
+	// Load the activity policy catalog - set ACTIVITY_POLICIES_FILE to tune
+	// retries and timeouts; the file is re-read whenever it changes
+	if err := policies.LoadFromEnv(); err != nil {
+		log.Fatalln("Unable to load activity policies", err)
+	}
+
 	// Create worker
 	w := shared.CreateTemporalWorker(c)
 
+	// Load risk rules - set RISK_RULES_FILE to tune limits without code changes
+	riskRules := errors.DefaultRiskRules()
+	if path := os.Getenv("RISK_RULES_FILE"); path != "" {
+		riskRules, err = errors.LoadRiskRules(path)
+		if err != nil {
+			log.Fatalln("Unable to load risk rules", err)
+		}
+		shared.LogInfo("Loaded risk rules from %s", path)
+	}
+
+	// Share the credit circuit breaker between workers through a file
+	if path := os.Getenv("CIRCUIT_STORE_FILE"); path != "" {
+		errors.CreditBreaker.Store = circuit.NewFileStore(path)
+		shared.LogInfo("Circuit breaker state is shared through %s", path)
+	}
+
 	// Register workflows and activities
 	w.RegisterWorkflow(errors.MoneyTransferWorkflow)
 	w.RegisterWorkflow(errors.RetryableTransferWorkflow)
+	w.RegisterWorkflow(errors.BatchTransferWorkflow)
+	w.RegisterWorkflow(errors.ReconciliationWorkflow)
+	w.RegisterWorkflow(errors.DiscrepancyFixupWorkflow)
 	w.RegisterActivity(errors.ValidateAccounts)
 	w.RegisterActivity(errors.DebitAccount)
 	w.RegisterActivity(errors.CreditAccount)
+	w.RegisterActivity(errors.CreditAtQuote)
 	w.RegisterActivity(errors.CompensateDebit)
+	w.RegisterActivity(errors.RepostCredit)
+	w.RegisterActivity(errors.GetFXQuote)
+	w.RegisterActivity(errors.NewRiskChecker(riskRules))
+	w.RegisterActivity(&errors.Reconciler{
+		Client:    c,
+		Ledger:    errors.DefaultLedger,
+		ReportDir: os.Getenv("RECONCILIATION_REPORT_DIR"),
+	})
+	w.RegisterActivity(&errors.BatchStore{Dir: os.Getenv("BATCH_RESULTS_DIR")})
 	w.RegisterActivity(errors.RiskyTransferActivity)
 
 	// Start the worker
 	shared.LogInfo("Worker is starting...")
-	shared.LogInfo("Registered workflows: MoneyTransferWorkflow, RetryableTransferWorkflow")
-	shared.LogInfo("Registered activities: ValidateAccounts, DebitAccount, CreditAccount, CompensateDebit, RiskyTransferActivity")
+	shared.LogInfo("Registered workflows: MoneyTransferWorkflow, RetryableTransferWorkflow, BatchTransferWorkflow, ReconciliationWorkflow, DiscrepancyFixupWorkflow")
+	shared.LogInfo("Registered activities: ValidateAccounts, DebitAccount, CreditAccount, CreditAtQuote, CompensateDebit, GetFXQuote, AssessTransferRisk, RecordTransfer, RiskyTransferActivity")
+	shared.LogInfo("Registered batch activities: LoadBatchPage, SaveBatchResults")
+	shared.LogInfo("Registered reconciliation activities: ListClosedTransfers, LedgerPostings, ExportReconciliationReport, RepostCredit")
 	shared.LogInfo("This example demonstrates error handling and compensation patterns")
 	shared.LogInfo("Press Ctrl+C to stop the worker")
 	shared.StartWorker(w)
@@ -2,120 +2,250 @@ package errors
 
 import (
 	"fmt"
-	"time"
 
+	enumspb "go.temporal.io/api/enums/v1"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
+
+	"temporal-go-examples/shared/errs"
+	"temporal-go-examples/shared/policies"
 )
 
 // TransferRequest represents a money transfer request
+// Amount is expressed in FromCurrency; empty currencies default to DefaultCurrency
 type TransferRequest struct {
-	FromAccount string  `json:"from_account"`
-	ToAccount   string  `json:"to_account"`
-	Amount      float64 `json:"amount"`
-	Reference   string  `json:"reference"`
+	FromAccount  string  `json:"from_account"`
+	ToAccount    string  `json:"to_account"`
+	Amount       float64 `json:"amount"`
+	FromCurrency string  `json:"from_currency"`
+	ToCurrency   string  `json:"to_currency"`
+	Reference    string  `json:"reference"`
+}
+
+// TransferResult describes a completed money transfer
+type TransferResult struct {
+	FromAccount    string       `json:"from_account"`
+	ToAccount      string       `json:"to_account"`
+	DebitTxnID     string       `json:"debit_txn_id"`
+	CreditTxnID    string       `json:"credit_txn_id"`
+	DebitedAmount  float64      `json:"debited_amount"`
+	FromCurrency   string       `json:"from_currency"`
+	CreditedAmount float64      `json:"credited_amount"`
+	ToCurrency     string       `json:"to_currency"`
+	FXQuoteID      string       `json:"fx_quote_id"`
+	FXRate         float64      `json:"fx_rate"`       // Rate applied to the credit
+	FXSpreadBps    float64      `json:"fx_spread_bps"` // Spread charged on the applied rate
+	Requotes       int          `json:"requotes"`      // Times the rate was re-locked after expiring
+	Risk           RiskDecision `json:"risk"`
+	Summary        string       `json:"summary"`
 }
 
 // MoneyTransferWorkflow demonstrates error handling and compensation
-func MoneyTransferWorkflow(ctx workflow.Context, request TransferRequest) (string, error) {
+func MoneyTransferWorkflow(ctx workflow.Context, request TransferRequest) (TransferResult, error) {
 	logger := workflow.GetLogger(ctx)
 	logger.Info("MoneyTransferWorkflow started", "request", request)
 
-	// Configure retry policy for activities
-	retryPolicy := &temporal.RetryPolicy{
-		InitialInterval:    time.Second,      // Wait 1 second before first retry
-		BackoffCoefficient: 2.0,              // Double the wait time each retry
-		MaximumInterval:    time.Second * 30, // Don't wait more than 30 seconds
-		MaximumAttempts:    3,                // Try maximum 3 times
-	}
-
-	// Configure activity options
-	activityOptions := workflow.ActivityOptions{
-		StartToCloseTimeout: time.Minute * 2,
-		RetryPolicy:         retryPolicy,
-	}
-	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+	request.FromCurrency = normalizeCurrency(request.FromCurrency)
+	request.ToCurrency = normalizeCurrency(request.ToCurrency)
 
 	// Step 1: Validate accounts
 	logger.Info("Validating accounts")
-	err := workflow.ExecuteActivity(ctx, ValidateAccounts, request.FromAccount, request.ToAccount).Get(ctx, nil)
+	err := policies.ExecuteActivity(ctx, ValidateAccounts, request.FromAccount, request.ToAccount).Get(ctx, nil)
 	if err != nil {
 		logger.Error("Account validation failed", "error", err)
-		return "", fmt.Errorf("account validation failed: %w", err)
+		return TransferResult{}, fmt.Errorf("account validation failed: %w", err)
 	}
 
-	// Step 2: Debit source account
+	// Step 2: Score the transfer and, if needed, wait for a reviewer
+	logger.Info("Assessing transfer risk")
+	var riskChecker *RiskChecker
+	var assessment RiskAssessment
+	err = policies.ExecuteActivity(ctx, riskChecker.AssessTransferRisk, request).Get(ctx, &assessment)
+	if err != nil {
+		logger.Error("Risk assessment failed", "error", err)
+		return TransferResult{}, fmt.Errorf("risk assessment failed: %w", err)
+	}
+	riskDecision := awaitRiskDecision(ctx, assessment)
+	if !riskDecision.Approved {
+		logger.Error("Transfer rejected by risk check", "outcome", riskDecision.Outcome)
+		// The decision travels to the client as the error's details
+		return TransferResult{}, errs.TransferRejected.New(
+			fmt.Sprintf("transfer rejected by risk check: %s", riskDecision.Outcome),
+			riskDecision,
+		)
+	}
+
+	// Step 3: Lock an FX rate before any money moves
+	logger.Info("Locking FX quote", "from", request.FromCurrency, "to", request.ToCurrency)
+	var originalQuote FXQuote
+	err = policies.ExecuteActivity(ctx, GetFXQuote, request.FromCurrency, request.ToCurrency).Get(ctx, &originalQuote)
+	if err != nil {
+		logger.Error("FX quote failed", "error", err)
+		return TransferResult{}, fmt.Errorf("fx quote failed: %w", err)
+	}
+
+	// Step 4: Debit source account
 	logger.Info("Debiting source account", "account", request.FromAccount, "amount", request.Amount)
 	var debitTxnID string
-	err = workflow.ExecuteActivity(ctx, DebitAccount, request.FromAccount, request.Amount, request.Reference).Get(ctx, &debitTxnID)
+	err = policies.ExecuteActivity(ctx, DebitAccount, request.FromAccount, request.Amount, request.FromCurrency, request.Reference).Get(ctx, &debitTxnID)
 	if err != nil {
 		logger.Error("Debit failed", "error", err)
-		return "", fmt.Errorf("debit failed: %w", err)
+		return TransferResult{}, fmt.Errorf("debit failed: %w", err)
 	}
 
-	// Step 3: Credit destination account
-	logger.Info("Crediting destination account", "account", request.ToAccount, "amount", request.Amount)
-	var creditTxnID string
-	err = workflow.ExecuteActivity(ctx, CreditAccount, request.ToAccount, request.Amount, request.Reference).Get(ctx, &creditTxnID)
-	if err != nil {
-		logger.Error("Credit failed, starting compensation", "error", err)
+	// Only debited transfers count against the daily and velocity limits
+	if workflow.GetVersion(ctx, "record-risk-after-debit", workflow.DefaultVersion, 1) == 1 {
+		if err := policies.ExecuteActivity(ctx, riskChecker.RecordTransfer, request).Get(ctx, nil); err != nil {
+			// The money already moved; a missed entry only loosens the limits
+			logger.Warn("Unable to record transfer for risk limits", "error", err)
+		}
+	}
 
-		// Compensation: Reverse the debit
-		logger.Info("Compensating: reversing debit", "debitTxnID", debitTxnID)
-		compensateErr := workflow.ExecuteActivity(ctx, CompensateDebit, request.FromAccount, request.Amount, debitTxnID).Get(ctx, nil)
-		if compensateErr != nil {
-			logger.Error("CRITICAL: Compensation failed", "error", compensateErr)
-			return "", fmt.Errorf("transfer failed and compensation failed: credit_error=%v, compensation_error=%v", err, compensateErr)
+	// Step 5: Re-quote if the lock expired while the debit was retrying
+	// workflow.Now is deterministic, so replays make the same decision
+	quote := originalQuote
+	requotes := 0
+	if quote.ExpiredAt(workflow.Now(ctx)) {
+		logger.Info("FX quote expired before credit, re-quoting", "quoteID", quote.QuoteID)
+		err = policies.ExecuteActivity(ctx, GetFXQuote, request.FromCurrency, request.ToCurrency).Get(ctx, &quote)
+		if err != nil {
+			logger.Error("FX re-quote failed, starting compensation", "error", err)
+			return TransferResult{}, compensateDebit(ctx, request, debitTxnID, err)
 		}
+		requotes++
+	}
 
-		logger.Info("Compensation successful")
-		return "", fmt.Errorf("transfer failed but system is consistent: %w", err)
+	// Step 6: Credit destination account
+	creditAmount := quote.Convert(request.Amount)
+	logger.Info("Crediting destination account", "account", request.ToAccount, "amount", creditAmount, "currency", request.ToCurrency)
+	var creditTxnID string
+	if workflow.GetVersion(ctx, "credit-at-quote", workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		// Transfers started before CreditAtQuote existed keep crediting a fixed amount
+		err = policies.ExecuteActivity(ctx, CreditAccount, request.ToAccount, creditAmount, request.ToCurrency, request.Reference).Get(ctx, &creditTxnID)
+	} else {
+		// CreditAtQuote checks the lock on every attempt, so credit retries that
+		// outlive the quote come back as FXQuoteExpired; lock a fresh rate and try again
+		for {
+			err = policies.ExecuteActivity(ctx, CreditAtQuote, request.ToAccount, request.Amount, quote, request.Reference).Get(ctx, &creditTxnID)
+			if err == nil || !errs.FXQuoteExpired.Is(err) || requotes >= maxRequotes {
+				break
+			}
+			logger.Info("FX quote expired during credit, re-quoting", "quoteID", quote.QuoteID)
+			if err = policies.ExecuteActivity(ctx, GetFXQuote, request.FromCurrency, request.ToCurrency).Get(ctx, &quote); err != nil {
+				break
+			}
+			requotes++
+		}
+		creditAmount = quote.Convert(request.Amount)
+	}
+	if err != nil {
+		logger.Error("Credit failed, starting compensation", "error", err)
+		return TransferResult{}, compensateDebit(ctx, request, debitTxnID, err)
 	}
 
 	// Success!
-	result := fmt.Sprintf("Transfer successful: $%.2f from %s to %s (Debit: %s, Credit: %s)",
-		request.Amount, request.FromAccount, request.ToAccount, debitTxnID, creditTxnID)
-	logger.Info("MoneyTransferWorkflow completed successfully", "result", result)
+	result := TransferResult{
+		FromAccount:    request.FromAccount,
+		ToAccount:      request.ToAccount,
+		DebitTxnID:     debitTxnID,
+		CreditTxnID:    creditTxnID,
+		DebitedAmount:  request.Amount,
+		FromCurrency:   request.FromCurrency,
+		CreditedAmount: creditAmount,
+		ToCurrency:     request.ToCurrency,
+		FXQuoteID:      quote.QuoteID,
+		FXRate:         quote.Rate,
+		FXSpreadBps:    quote.SpreadBps,
+		Requotes:       requotes,
+		Risk:           riskDecision,
+	}
+	result.Summary = fmt.Sprintf("Transfer successful: %.2f %s from %s to %.2f %s at %s (rate %.6f, spread %.0f bps, Debit: %s, Credit: %s)",
+		result.DebitedAmount, result.FromCurrency, request.FromAccount,
+		result.CreditedAmount, result.ToCurrency, request.ToAccount,
+		result.FXRate, result.FXSpreadBps, debitTxnID, creditTxnID)
+	logger.Info("MoneyTransferWorkflow completed successfully", "result", result.Summary)
 	return result, nil
 }
 
-// RetryableTransferWorkflow demonstrates handling retryable vs non-retryable errors
-func RetryableTransferWorkflow(ctx workflow.Context, request TransferRequest) (string, error) {
+// compensateDebit reverses a debit after a later step failed
+// The reversal returns the debited amount in the source currency, so no rate is
+// involved and the customer gets back exactly what was debited
+func compensateDebit(ctx workflow.Context, request TransferRequest, debitTxnID string, cause error) error {
 	logger := workflow.GetLogger(ctx)
-	logger.Info("RetryableTransferWorkflow started", "request", request)
 
-	// Special retry policy for different types of errors
-	retryPolicy := &temporal.RetryPolicy{
-		InitialInterval:    time.Second,
-		BackoffCoefficient: 2.0,
-		MaximumInterval:    time.Second * 30,
-		MaximumAttempts:    5,
-		// Don't retry application errors (business logic errors)
-		NonRetryableErrorTypes: []string{
-			"InvalidAccount",
-			"InsufficientFunds",
-		},
+	// Compensation: Reverse the debit
+	logger.Info("Compensating: reversing debit", "debitTxnID", debitTxnID)
+	compensateErr := policies.ExecuteActivity(ctx, CompensateDebit, request.FromAccount, request.Amount, request.FromCurrency, debitTxnID).Get(ctx, nil)
+	if compensateErr != nil {
+		logger.Error("CRITICAL: Compensation failed", "error", compensateErr)
+		return errs.CompensationFailed.Wrap(
+			cause,
+			fmt.Sprintf("transfer failed and compensation failed: credit_error=%v, compensation_error=%v", cause, compensateErr),
+			debitTxnID,
+		)
 	}
 
-	activityOptions := workflow.ActivityOptions{
-		StartToCloseTimeout: time.Minute * 2,
-		RetryPolicy:         retryPolicy,
+	logger.Info("Compensation successful")
+	// A typed error lets callers such as BatchTransferWorkflow tell "compensated" apart from "failed"
+	return errs.TransferCompensated.Wrap(cause, fmt.Sprintf("transfer failed but system is consistent: %v", cause), debitTxnID)
+}
+
+// knownAttempts returns how many attempts an activity made, or zero if that
+// isn't known. The error doesn't carry the count; only a retry state saying the
+// attempts ran out pins it down, to every attempt the policy allowed. Timeouts,
+// non-retryable errors and other stops can end at any attempt, so they report
+// zero rather than a guess
+func knownAttempts(failure errs.Failure, policy policies.Policy) int32 {
+	if failure.RetryState != enumspb.RETRY_STATE_MAXIMUM_ATTEMPTS_REACHED.String() {
+		return 0
 	}
-	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+	return policy.MaximumAttempts
+}
+
+// RetryableTransferWorkflow demonstrates handling retryable vs non-retryable errors
+func RetryableTransferWorkflow(ctx workflow.Context, request TransferRequest) (string, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("RetryableTransferWorkflow started", "request", request)
+
+	// The catalog maps RiskyTransferActivity to the "default" policy with an
+	// override of 5 attempts. Every catalog policy lists the non-retryable kinds
+	// from shared/errs in NonRetryableErrorTypes, which protects against an
+	// activity that builds one by hand and forgets the flag
 
 	// Try the risky transfer operation
 	var result string
-	err := workflow.ExecuteActivity(ctx, RiskyTransferActivity, request).Get(ctx, &result)
+	future, policy := policies.ExecuteActivityWithPolicy(ctx, RiskyTransferActivity, request)
+	err := future.Get(ctx, &result)
 	if err != nil {
-		// Check if it's a non-retryable error
-		var appErr *temporal.ApplicationError
-		if temporal.IsApplicationError(err) {
-			appErr = err.(*temporal.ApplicationError)
-			logger.Error("Non-retryable error occurred", "type", appErr.Type(), "message", appErr.Error())
+		// The error from Get is an ActivityError wrapping the real cause, so a type
+		// assertion would fail; errs.Describe walks the chain with errors.As instead
+		failure := errs.Describe(err)
+		if failure.Kind == errs.FailureCanceled {
+			// Let cancellation propagate so the workflow is reported as canceled
+			return "", err
+		}
+
+		failure.Attempts = knownAttempts(failure, policy)
+
+		if failure.Retryable {
+			logger.Error("Retryable error persisted after all attempts",
+				"type", failure.Type, "attempts", failure.Attempts, "retryState", failure.RetryState)
 		} else {
-			logger.Error("Retryable error occurred", "error", err)
+			logger.Error("Non-retryable error occurred",
+				"kind", failure.Kind, "type", failure.Type, "category", failure.Category, "message", failure.Message)
 		}
-		return "", err
+
+		// Keep the original type and retryability so errs.Classify still works
+		// on the client, and attach the structured failure as details
+		return "", temporal.NewApplicationErrorWithOptions(
+			fmt.Sprintf("risky transfer failed: %s", failure.Message),
+			failure.Type,
+			temporal.ApplicationErrorOptions{
+				NonRetryable: !failure.Retryable,
+				Cause:        err,
+				Details:      []interface{}{failure},
+			},
+		)
 	}
 
 	logger.Info("RetryableTransferWorkflow completed successfully", "result", result)
@@ -0,0 +1,80 @@
+package errors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/testsuite"
+
+	"temporal-go-examples/shared/errs"
+	"temporal-go-examples/shared/policies"
+)
+
+// riskyTransferFailure runs RetryableTransferWorkflow against an activity that
+// always fails with err, and returns the failure attached to the workflow error
+func riskyTransferFailure(t *testing.T, err error) (errs.Failure, int) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(RetryableTransferWorkflow)
+	env.RegisterActivity(RiskyTransferActivity)
+	calls := 0
+	env.OnActivity(RiskyTransferActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, request TransferRequest) (string, error) {
+			calls++
+			return "", err
+		})
+
+	env.ExecuteWorkflow(RetryableTransferWorkflow, TransferRequest{FromAccount: "account-123", ToAccount: "account-456", Amount: 10})
+
+	require.True(t, env.IsWorkflowCompleted())
+	workflowErr := env.GetWorkflowError()
+	require.Error(t, workflowErr)
+	var failure errs.Failure
+	require.True(t, errs.Details(workflowErr, &failure), "the failure should be attached: %v", workflowErr)
+	return failure, calls
+}
+
+func TestRetryableTransferRetriesTransientErrors(t *testing.T) {
+	failure, calls := riskyTransferFailure(t, errs.ServiceUnavailable.New("network timeout during transfer"))
+
+	// The catalog allows RiskyTransferActivity 5 attempts
+	assert.Equal(t, 5, calls)
+	assert.Equal(t, "ServiceUnavailable", failure.Type)
+	assert.True(t, failure.Retryable)
+}
+
+func TestKnownAttempts(t *testing.T) {
+	policy := policies.Policy{MaximumAttempts: 5}
+	for _, test := range []struct {
+		retryState enumspb.RetryState
+		want       int32
+	}{
+		{enumspb.RETRY_STATE_MAXIMUM_ATTEMPTS_REACHED, 5},
+		{enumspb.RETRY_STATE_NON_RETRYABLE_FAILURE, 0},
+		{enumspb.RETRY_STATE_TIMEOUT, 0},
+		{enumspb.RETRY_STATE_CANCEL_REQUESTED, 0},
+		{enumspb.RETRY_STATE_UNSPECIFIED, 0},
+	} {
+		failure := errs.Failure{Kind: errs.FailureApplication, Retryable: true, RetryState: test.retryState.String()}
+		assert.Equal(t, test.want, knownAttempts(failure, policy), "%s", test.retryState)
+	}
+
+	// An unlimited policy has no count to report
+	exhausted := errs.Failure{RetryState: enumspb.RETRY_STATE_MAXIMUM_ATTEMPTS_REACHED.String()}
+	assert.Zero(t, knownAttempts(exhausted, policies.Policy{}))
+}
+
+func TestRetryableTransferLeavesUnknownAttemptsZero(t *testing.T) {
+	failure, calls := riskyTransferFailure(t, errs.InvalidAccount.New("account does not exist"))
+
+	// A non-retryable error stops after the first attempt; nothing says how
+	// many the policy allowed were used, so none are reported
+	assert.Equal(t, 1, calls)
+	assert.Zero(t, failure.Attempts)
+	assert.Equal(t, "InvalidAccount", failure.Type)
+	assert.False(t, failure.Retryable)
+}
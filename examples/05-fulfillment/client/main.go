@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.temporal.io/sdk/client"
+
+	activities "temporal-go-examples/examples/02-activities"
+	signals "temporal-go-examples/examples/03-signals"
+	fulfillment "temporal-go-examples/examples/05-fulfillment"
+	"temporal-go-examples/shared"
+)
+
+func main() {
+	// Create Temporal client
+	c, err := shared.CreateTemporalClient()
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	order := activities.Order{
+		ID:              "order-" + shared.RandomID(),
+		UserID:          "user-67890",
+		Email:           "customer@example.com",
+		Amount:          24.99,
+		Product:         "MUG",
+		ShippingAddress: "123 Main St",
+	}
+
+	shared.LogInfo("Starting FulfillmentWorkflow for order %s...", order.ID)
+	workflowRun, err := shared.ExecuteWorkflow(c, fulfillment.FulfillmentWorkflow, order)
+	if err != nil {
+		log.Fatalln("Unable to execute workflow", err)
+	}
+	workflowID := workflowRun.GetID()
+	shared.LogInfo("Workflow started! WorkflowID: %s", workflowID)
+
+	// Customers only ever talk to the fulfillment; it forwards to the delivery
+	// An address change during payment goes into the handoff instead
+	time.Sleep(time.Second)
+	if err := c.SignalWorkflow(ctx, workflowID, "", signals.UpdateAddressSignal, "456 Oak Avenue"); err != nil {
+		log.Fatalln("Unable to signal workflow", err)
+	}
+	shared.LogInfo("✅ Updated address: 456 Oak Avenue")
+
+	// Watch both halves through the one query until the order is out for delivery
+	for {
+		time.Sleep(time.Second * 2)
+		status := queryStatus(c, workflowID)
+		if status.Delivery != nil {
+			shared.LogInfo("📊 Stage: %s, delivery: %s, items: %v, address: %s",
+				status.Stage, status.Delivery.Status, status.Delivery.Items, status.Delivery.Address)
+		} else {
+			shared.LogInfo("📊 Stage: %s", status.Stage)
+		}
+		if status.Stage != fulfillment.StagePayment {
+			break
+		}
+	}
+
+	if err := c.SignalWorkflow(ctx, workflowID, "", signals.AddItemSignal, "STICKER-PACK"); err != nil {
+		log.Fatalln("Unable to signal workflow", err)
+	}
+	shared.LogInfo("✅ Added item: STICKER-PACK")
+	if err := c.SignalWorkflow(ctx, workflowID, "", signals.CompleteOrderSignal, "Customer confirmed delivery"); err != nil {
+		log.Fatalln("Unable to signal workflow", err)
+	}
+	shared.LogInfo("✅ Sent completion signal")
+
+	var result fulfillment.FulfillmentStatus
+	if err := workflowRun.Get(ctx, &result); err != nil {
+		log.Fatalln("Workflow failed", err)
+	}
+	shared.LogInfo("🎉 Order: %s", result.Order.Message)
+	shared.LogInfo("🎉 Delivery: %s", result.DeliveryResult)
+}
+
+// queryStatus asks the fulfillment for the status of both halves
+func queryStatus(c client.Client, workflowID string) fulfillment.FulfillmentStatus {
+	resp, err := c.QueryWorkflow(context.Background(), workflowID, "", fulfillment.StatusQuery)
+	if err != nil {
+		log.Fatalln("Unable to query workflow", err)
+	}
+	var status fulfillment.FulfillmentStatus
+	if err := resp.Get(&status); err != nil {
+		log.Fatalln("Unable to decode query result", err)
+	}
+	return status
+}
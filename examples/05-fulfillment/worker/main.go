@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+
+	activities "temporal-go-examples/examples/02-activities"
+	signals "temporal-go-examples/examples/03-signals"
+	fulfillment "temporal-go-examples/examples/05-fulfillment"
+	"temporal-go-examples/shared"
+	"temporal-go-examples/shared/policies"
+	"temporal-go-examples/shared/ratelimit"
+)
+
+func main() {
+	// Create Temporal client
+	c, err := shared.CreateTemporalClient()
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	// The order half runs the 02-activities activities, with their policies and rate limits
+	if err := policies.LoadFromEnv(); err != nil {
+		log.Fatalln("Unable to load activity policies", err)
+	}
+	if activities.Limiter, err = ratelimit.FromEnv(); err != nil {
+		log.Fatalln("Unable to load rate limits", err)
+	}
+
+	// Create worker
+	w := shared.CreateTemporalWorker(c)
+
+	// Register the parent workflow and everything its children need
+	// Child workflows run on the parent's task queue, so this worker runs them too
+	w.RegisterWorkflow(fulfillment.FulfillmentWorkflow)
+	w.RegisterWorkflow(activities.OrderProcessingWorkflow)
+	w.RegisterWorkflow(activities.NotificationOutboxWorkflow)
+	w.RegisterWorkflow(signals.DeliveryWorkflow)
+	w.RegisterActivity(activities.ValidateOrder)
+	w.RegisterActivity(activities.ProcessPayment)
+	w.RegisterActivity(activities.PriceOrder)
+	w.RegisterActivity(activities.ReserveInventory)
+	w.RegisterActivity(activities.CommitReservation)
+	w.RegisterActivity(activities.ReleaseReservation)
+	w.RegisterActivity(activities.VoidPayment)
+	w.RegisterActivity(activities.RefundPayment)
+	w.RegisterActivity(activities.SendConfirmationEmail)
+	w.RegisterActivity(activities.SendConfirmationWebhook)
+	w.RegisterActivity(activities.WriteConfirmationFile)
+
+	// Start the worker
+	shared.LogInfo("Worker is starting...")
+	shared.LogInfo("Registered workflows: FulfillmentWorkflow, OrderProcessingWorkflow, NotificationOutboxWorkflow, DeliveryWorkflow")
+	shared.LogInfo("Payments complete through the 02-activities webhook receiver; run it too")
+	shared.LogInfo("Press Ctrl+C to stop the worker")
+	shared.StartWorker(w)
+}
@@ -0,0 +1,186 @@
+package fulfillment
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/workflow"
+
+	activities "temporal-go-examples/examples/02-activities"
+	signals "temporal-go-examples/examples/03-signals"
+)
+
+// StatusQuery returns the FulfillmentStatus
+const StatusQuery = "get-status"
+
+// Fulfillment stages
+const (
+	StagePayment   = "payment"   // OrderProcessingWorkflow is running
+	StageDelivery  = "delivery"  // DeliveryWorkflow is running
+	StageCompleted = "completed" // Paid and delivered
+	StageCanceled  = "canceled"  // The order was canceled before it was paid
+	StageFailed    = "failed"    // The order or the delivery failed
+)
+
+// FulfillmentStatus covers both halves of an order in one place
+type FulfillmentStatus struct {
+	OrderID            string                  `json:"order_id"`
+	Stage              string                  `json:"stage"`
+	OrderWorkflowID    string                  `json:"order_workflow_id"`
+	DeliveryWorkflowID string                  `json:"delivery_workflow_id,omitempty"`
+	Order              *activities.OrderResult `json:"order,omitempty"`    // Set once the order workflow has finished
+	Delivery           *signals.OrderStatus    `json:"delivery,omitempty"` // Latest status the delivery reported
+	DeliveryResult     string                  `json:"delivery_result,omitempty"`
+	Error              string                  `json:"error,omitempty"`
+}
+
+// FulfillmentWorkflow takes an order from payment to the customer's door
+// It runs OrderProcessingWorkflow (examples/02-activities) as a child, then
+// hands the paid order to DeliveryWorkflow (examples/03-signals) as a
+// second child. Customer signals sent to the fulfillment are forwarded to the
+// delivery; signals that arrive while payment is still running are folded
+// into the handoff instead
+func FulfillmentWorkflow(ctx workflow.Context, order activities.Order) (FulfillmentStatus, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("FulfillmentWorkflow started", "orderID", order.ID)
+
+	workflowID := workflow.GetInfo(ctx).WorkflowExecution.ID
+	status := FulfillmentStatus{
+		OrderID:         order.ID,
+		Stage:           StagePayment,
+		OrderWorkflowID: workflowID + "-order",
+	}
+	err := workflow.SetQueryHandler(ctx, StatusQuery, func() (FulfillmentStatus, error) {
+		return status, nil
+	})
+	if err != nil {
+		return status, err
+	}
+
+	// The handoff collects what the customer changes while payment runs
+	handoff := signals.DeliveryRequest{
+		OrderID: order.ID,
+		Items:   deliveryItems(order),
+		Address: order.ShippingAddress,
+	}
+	var completeMessage *string // complete-order received before the delivery started
+
+	addItem := workflow.GetSignalChannel(ctx, signals.AddItemSignal)
+	updateAddress := workflow.GetSignalChannel(ctx, signals.UpdateAddressSignal)
+	completeOrder := workflow.GetSignalChannel(ctx, signals.CompleteOrderSignal)
+	deliveryStatus := workflow.GetSignalChannel(ctx, signals.DeliveryStatusSignal)
+
+	// Step 1: Take payment in the order workflow
+	orderCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{WorkflowID: status.OrderWorkflowID})
+	orderFuture := workflow.ExecuteChildWorkflow(orderCtx, activities.OrderProcessingWorkflow, order)
+	var delivery workflow.ChildWorkflowFuture
+
+	for status.Stage == StagePayment || status.Stage == StageDelivery {
+		selector := workflow.NewSelector(ctx)
+
+		selector.AddReceive(addItem, func(c workflow.ReceiveChannel, more bool) {
+			var item string
+			c.Receive(ctx, &item)
+			if delivery == nil {
+				handoff.Items = append(handoff.Items, item)
+				return
+			}
+			forward(ctx, delivery, signals.AddItemSignal, item)
+		})
+		selector.AddReceive(updateAddress, func(c workflow.ReceiveChannel, more bool) {
+			var address string
+			c.Receive(ctx, &address)
+			if delivery == nil {
+				handoff.Address = address
+				return
+			}
+			forward(ctx, delivery, signals.UpdateAddressSignal, address)
+		})
+		selector.AddReceive(completeOrder, func(c workflow.ReceiveChannel, more bool) {
+			var message string
+			c.Receive(ctx, &message)
+			if delivery == nil {
+				completeMessage = &message
+				return
+			}
+			forward(ctx, delivery, signals.CompleteOrderSignal, message)
+		})
+		selector.AddReceive(deliveryStatus, func(c workflow.ReceiveChannel, more bool) {
+			var reported signals.OrderStatus
+			c.Receive(ctx, &reported)
+			status.Delivery = &reported
+		})
+
+		if status.Stage == StagePayment {
+			selector.AddFuture(orderFuture, func(f workflow.Future) {
+				var result activities.OrderResult
+				if err := f.Get(ctx, &result); err != nil {
+					logger.Error("Order failed", "error", err)
+					status.Stage = StageFailed
+					status.Error = err.Error()
+					return
+				}
+				status.Order = &result
+				if result.Status == activities.OrderCanceled {
+					logger.Info("Order canceled, nothing to deliver", "reason", result.Reason)
+					status.Stage = StageCanceled
+					return
+				}
+
+				// Step 2: Hand the paid order to delivery
+				handoff.PaymentID = result.PaymentID
+				status.DeliveryWorkflowID = workflowID + "-delivery"
+				deliveryCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{WorkflowID: status.DeliveryWorkflowID})
+				delivery = workflow.ExecuteChildWorkflow(deliveryCtx, signals.DeliveryWorkflow, handoff)
+				if err := delivery.GetChildWorkflowExecution().Get(ctx, nil); err != nil {
+					logger.Error("Unable to start delivery", "error", err)
+					status.Stage = StageFailed
+					status.Error = err.Error()
+					return
+				}
+				status.Stage = StageDelivery
+				logger.Info("Order paid, delivery started", "deliveryWorkflowID", status.DeliveryWorkflowID, "items", handoff.Items)
+
+				if completeMessage != nil {
+					forward(ctx, delivery, signals.CompleteOrderSignal, *completeMessage)
+				}
+			})
+		} else {
+			selector.AddFuture(delivery, func(f workflow.Future) {
+				if err := f.Get(ctx, &status.DeliveryResult); err != nil {
+					logger.Error("Delivery failed", "error", err)
+					status.Stage = StageFailed
+					status.Error = err.Error()
+					return
+				}
+				status.Stage = StageCompleted
+			})
+		}
+
+		selector.Select(ctx)
+	}
+
+	if status.Stage == StageFailed {
+		return status, fmt.Errorf("fulfillment of order %s failed: %s", order.ID, status.Error)
+	}
+	logger.Info("FulfillmentWorkflow completed", "orderID", order.ID, "stage", status.Stage)
+	return status, nil
+}
+
+// forward passes a customer signal on to the delivery workflow
+func forward(ctx workflow.Context, delivery workflow.ChildWorkflowFuture, signalName string, arg interface{}) {
+	if err := delivery.SignalChildWorkflow(ctx, signalName, arg).Get(ctx, nil); err != nil {
+		workflow.GetLogger(ctx).Error("Unable to forward signal to delivery", "signal", signalName, "error", err)
+	}
+}
+
+// deliveryItems describes an order's items the way DeliveryWorkflow lists them
+func deliveryItems(order activities.Order) []string {
+	if len(order.Items) == 0 {
+		return []string{order.Product}
+	}
+	items := make([]string, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, fmt.Sprintf("%d x %s", item.Quantity, item.SKU))
+	}
+	return items
+}
@@ -0,0 +1,125 @@
+package fulfillment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+
+	activities "temporal-go-examples/examples/02-activities"
+	signals "temporal-go-examples/examples/03-signals"
+)
+
+// testWorkflowID is the workflow ID the test environment gives the fulfillment
+const testWorkflowID = "default-test-workflow-id"
+
+var testOrder = activities.Order{
+	ID:              "order-1",
+	UserID:          "user-1",
+	Email:           "customer@example.com",
+	Amount:          24.99,
+	Product:         "MUG",
+	ShippingAddress: "123 Main St",
+}
+
+// newFulfillmentEnv runs the real delivery child and mocks the order child,
+// which returns result once paidAfter has passed
+func newFulfillmentEnv(paidAfter time.Duration, result activities.OrderResult) *testsuite.TestWorkflowEnvironment {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(FulfillmentWorkflow)
+	env.RegisterWorkflow(activities.OrderProcessingWorkflow)
+	env.RegisterWorkflow(signals.DeliveryWorkflow)
+	env.OnWorkflow(activities.OrderProcessingWorkflow, mock.Anything, mock.Anything).After(paidAfter).Return(result, nil)
+	return env
+}
+
+var paid = activities.OrderResult{OrderID: "order-1", Status: activities.OrderCompleted, PaymentID: "pay_1"}
+
+// queryFulfillment runs the get-status query; call it from a delayed callback
+func queryFulfillment(t *testing.T, env *testsuite.TestWorkflowEnvironment) FulfillmentStatus {
+	value, err := env.QueryWorkflow(StatusQuery)
+	require.NoError(t, err)
+	var status FulfillmentStatus
+	require.NoError(t, value.Get(&status))
+	return status
+}
+
+func TestSignalsDuringPaymentGoIntoTheHandoff(t *testing.T) {
+	env := newFulfillmentEnv(time.Minute, paid)
+	var duringPayment FulfillmentStatus
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(signals.UpdateAddressSignal, "456 Oak Avenue")
+		env.SignalWorkflow(signals.AddItemSignal, "STICKER-PACK")
+		env.SignalWorkflow(signals.CompleteOrderSignal, "Left at the door")
+	}, 10*time.Second)
+	env.RegisterDelayedCallback(func() {
+		duringPayment = queryFulfillment(t, env)
+	}, 20*time.Second)
+
+	env.ExecuteWorkflow(FulfillmentWorkflow, testOrder)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	assert.Equal(t, StagePayment, duringPayment.Stage)
+	assert.Empty(t, duringPayment.DeliveryWorkflowID, "no delivery before the order is paid")
+	assert.Nil(t, duringPayment.Delivery)
+
+	var status FulfillmentStatus
+	require.NoError(t, env.GetWorkflowResult(&status))
+	assert.Equal(t, StageCompleted, status.Stage)
+	// The delivery started with the changes, and the held completion ended it
+	assert.Equal(t, "Order completed! Items: [MUG STICKER-PACK], Delivered to: 456 Oak Avenue", status.DeliveryResult)
+}
+
+func TestSignalsAfterPaymentAreForwarded(t *testing.T) {
+	env := newFulfillmentEnv(time.Second, paid)
+	var duringDelivery FulfillmentStatus
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(signals.AddItemSignal, "STICKER-PACK")
+	}, 3*time.Second)
+	env.RegisterDelayedCallback(func() {
+		duringDelivery = queryFulfillment(t, env)
+		env.SignalWorkflow(signals.CompleteOrderSignal, "Left at the door")
+	}, 5*time.Second)
+
+	env.ExecuteWorkflow(FulfillmentWorkflow, testOrder)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	// One query covers both halves: the order's result and the delivery's
+	// latest reported status, which already has the forwarded item
+	assert.Equal(t, StageDelivery, duringDelivery.Stage)
+	assert.Equal(t, testWorkflowID+"-order", duringDelivery.OrderWorkflowID)
+	assert.Equal(t, testWorkflowID+"-delivery", duringDelivery.DeliveryWorkflowID)
+	require.NotNil(t, duringDelivery.Order)
+	assert.Equal(t, "pay_1", duringDelivery.Order.PaymentID)
+	require.NotNil(t, duringDelivery.Delivery)
+	assert.Equal(t, []string{"MUG", "STICKER-PACK"}, duringDelivery.Delivery.Items)
+	assert.Equal(t, signals.StatusPreparing, duringDelivery.Delivery.Status)
+
+	var status FulfillmentStatus
+	require.NoError(t, env.GetWorkflowResult(&status))
+	assert.Equal(t, StageCompleted, status.Stage)
+	require.NotNil(t, status.Delivery)
+	assert.True(t, status.Delivery.Final(), "the delivery reports its final status before it returns")
+	assert.Equal(t, "Order completed! Items: [MUG STICKER-PACK], Delivered to: 123 Main St", status.DeliveryResult)
+}
+
+func TestCanceledOrderIsNeverDelivered(t *testing.T) {
+	canceled := activities.OrderResult{OrderID: "order-1", Status: activities.OrderCanceled, Reason: "changed my mind"}
+	env := newFulfillmentEnv(time.Second, canceled)
+
+	env.ExecuteWorkflow(FulfillmentWorkflow, testOrder)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var status FulfillmentStatus
+	require.NoError(t, env.GetWorkflowResult(&status))
+	assert.Equal(t, StageCanceled, status.Stage)
+	assert.Empty(t, status.DeliveryWorkflowID)
+	assert.Nil(t, status.Delivery)
+}
@@ -1,7 +1,7 @@
 #!/bin/bash
 
 if [ -z "$1" ]; then
-    echo "Usage: ./run-example.sh <example-name> [worker|client]"
+    echo "Usage: ./run-example.sh <example-name> [worker|client|<command>] [args...]"
     echo ""
     echo "Available examples:"
     ls -1 examples/ | grep -E '^[0-9]' | sed 's/^/  - /'
@@ -14,6 +14,7 @@ fi
 
 EXAMPLE=$1
 TYPE=${2:-worker}
+shift $(( $# < 2 ? $# : 2 ))
 
 if [ ! -d "examples/$EXAMPLE" ]; then
     echo "❌ Example '$EXAMPLE' not found!"
@@ -23,8 +24,8 @@ if [ ! -d "examples/$EXAMPLE" ]; then
     exit 1
 fi
 
-if [[ "$TYPE" != "worker" && "$TYPE" != "client" ]]; then
-    echo "❌ Type must be 'worker' or 'client'"
+if [ ! -d "examples/$EXAMPLE/$TYPE" ]; then
+    echo "❌ Type must be 'worker', 'client' or another command in examples/$EXAMPLE/"
     exit 1
 fi
 
@@ -48,6 +49,6 @@ if [ ! -f "$TYPE/main.go" ]; then
     exit 1
 fi
 
-echo "▶️  Executing: go run $TYPE/main.go"
+echo "▶️  Executing: go run $TYPE/main.go $*"
 echo "----------------------------------------"
-go run $TYPE/main.go
+go run $TYPE/main.go "$@"
@@ -0,0 +1,190 @@
+// Package circuit is a circuit breaker for activities that call flaky downstreams
+//
+// Without a breaker every workflow retries a failing dependency on its own, so
+// an outage turns into a retry storm. A Breaker counts failures per activity type
+// in a Store shared by all workers. Once too many fail in a row it opens and
+// activities fail fast with a retryable CircuitOpen error whose NextRetryDelay
+// tells Temporal to come back when the cooldown ends. After the cooldown a few
+// half-open probes are let through; a success closes the circuit again.
+package circuit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+
+	"temporal-go-examples/shared/errs"
+)
+
+// Circuit statuses
+const (
+	Closed   = "closed"
+	Open     = "open"
+	HalfOpen = "half-open"
+)
+
+// Config tunes when a breaker opens and how it recovers
+type Config struct {
+	FailureThreshold int           // Consecutive failures that open the circuit
+	OpenDuration     time.Duration // Cooldown before half-open probes are allowed
+	HalfOpenProbes   int           // Probes allowed in flight while half-open
+	ProbeTimeout     time.Duration // A probe that hasn't reported back by then is forgotten
+}
+
+// DefaultConfig returns settings suitable for the examples
+func DefaultConfig() Config {
+	return Config{
+		FailureThreshold: 5,
+		OpenDuration:     time.Second * 30,
+		HalfOpenProbes:   1,
+		ProbeTimeout:     time.Minute,
+	}
+}
+
+// State is the persisted state of one circuit
+type State struct {
+	Name                string    `json:"name"`
+	Status              string    `json:"status"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	OpenUntil           time.Time `json:"open_until"`
+	ProbesInFlight      int       `json:"probes_in_flight"`
+	ProbeStartedAt      time.Time `json:"probe_started_at"`
+	LastFailure         string    `json:"last_failure"`
+	Rejected            int64     `json:"rejected"` // Calls failed fast while open
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// Breaker guards calls to one downstream
+type Breaker struct {
+	Name   string
+	Store  Store
+	Config Config
+}
+
+// New creates a breaker; name is usually the activity type it protects
+func New(name string, store Store, config Config) *Breaker {
+	return &Breaker{Name: name, Store: store, Config: config}
+}
+
+// Execute runs fn if the circuit allows it and records the outcome
+// Only retryable failures (transient, dependency or unclassified) count against
+// the circuit; a business "no" says nothing about the downstream's health
+func (b *Breaker) Execute(ctx context.Context, fn func() error) error {
+	allowed, state, err := b.allow()
+	if err != nil {
+		return errs.DependencyFailure.Wrap(err, "circuit breaker store unavailable")
+	}
+	b.report(ctx, state, !allowed)
+	if !allowed {
+		retryIn := b.retryDelay(state)
+		return errs.CircuitOpen.WithOptions(
+			fmt.Sprintf("circuit %s is open, retry in %s", b.Name, retryIn.Round(time.Second)),
+			temporal.ApplicationErrorOptions{
+				NextRetryDelay: retryIn,
+				Details:        []interface{}{state},
+			},
+		)
+	}
+
+	callErr := fn()
+	counts := callErr != nil
+	if counts {
+		category := errs.Classify(callErr).Category
+		counts = category == errs.Transient || category == errs.Dependency || category == errs.Unknown
+	}
+	state, err = b.record(counts, callErr)
+	if err == nil {
+		b.report(ctx, state, false)
+	}
+	return callErr
+}
+
+// allow decides whether a call may proceed, moving open circuits to half-open after the cooldown
+func (b *Breaker) allow() (bool, State, error) {
+	allowed := false
+	state, err := b.Store.Update(b.Name, func(s *State) {
+		now := time.Now()
+		switch s.Status {
+		case Open:
+			if now.Before(s.OpenUntil) {
+				s.Rejected++
+				return
+			}
+			s.Status = HalfOpen
+			s.ProbesInFlight = 0
+			fallthrough
+		case HalfOpen:
+			if s.ProbesInFlight > 0 && now.Sub(s.ProbeStartedAt) > b.Config.ProbeTimeout {
+				s.ProbesInFlight = 0
+			}
+			if s.ProbesInFlight >= b.Config.HalfOpenProbes {
+				s.Rejected++
+				return
+			}
+			s.ProbesInFlight++
+			s.ProbeStartedAt = now
+		}
+		allowed = true
+	})
+	return allowed, state, err
+}
+
+// retryDelay is how long a rejected caller should wait before trying again
+// An open circuit rejects until its cooldown ends. A half-open one is waiting on
+// its probes: they close it again or reopen it for another cooldown, so callers
+// wait one cooldown rather than spend an attempt every second until they settle
+func (b *Breaker) retryDelay(state State) time.Duration {
+	retryIn := time.Until(state.OpenUntil)
+	if state.Status == HalfOpen {
+		retryIn = b.Config.OpenDuration
+	}
+	return max(retryIn, time.Second)
+}
+
+// record updates the circuit with the outcome of a call
+func (b *Breaker) record(failed bool, callErr error) (State, error) {
+	return b.Store.Update(b.Name, func(s *State) {
+		if !failed {
+			// Any success - including a half-open probe - closes the circuit
+			s.Status = Closed
+			s.ConsecutiveFailures = 0
+			s.ProbesInFlight = 0
+			return
+		}
+
+		s.ConsecutiveFailures++
+		s.LastFailure = callErr.Error()
+		if s.Status == HalfOpen || s.ConsecutiveFailures >= b.Config.FailureThreshold {
+			s.Status = Open
+			s.OpenUntil = time.Now().Add(b.Config.OpenDuration)
+			s.ProbesInFlight = 0
+		}
+	})
+}
+
+// report publishes the circuit state through the activity's metrics handler
+func (b *Breaker) report(ctx context.Context, state State, rejected bool) {
+	if !activity.IsActivity(ctx) {
+		return
+	}
+	handler := activity.GetMetricsHandler(ctx).WithTags(map[string]string{"circuit": b.Name})
+	handler.Gauge("circuit_breaker_state").Update(StatusValue(state.Status))
+	handler.Gauge("circuit_breaker_consecutive_failures").Update(float64(state.ConsecutiveFailures))
+	if rejected {
+		handler.Counter("circuit_breaker_rejected").Inc(1)
+	}
+}
+
+// StatusValue maps a status to a gauge value: 0 closed, 1 half-open, 2 open
+func StatusValue(status string) float64 {
+	switch status {
+	case Open:
+		return 2
+	case HalfOpen:
+		return 1
+	}
+	return 0
+}
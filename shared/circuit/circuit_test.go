@@ -0,0 +1,162 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+
+	"temporal-go-examples/shared/errs"
+)
+
+var testConfig = Config{
+	FailureThreshold: 3,
+	OpenDuration:     time.Minute,
+	HalfOpenProbes:   1,
+	ProbeTimeout:     10 * time.Minute,
+}
+
+func newTestBreaker() (*Breaker, *MemoryStore) {
+	store := NewMemoryStore()
+	return New("CreditAccount", store, testConfig), store
+}
+
+func fail(err error) func() error {
+	return func() error { return err }
+}
+
+func succeed() error { return nil }
+
+// endCooldown moves the circuit's cooldown into the past, as if it had elapsed
+func endCooldown(t *testing.T, store *MemoryStore) {
+	_, err := store.Update("CreditAccount", func(s *State) { s.OpenUntil = time.Now().Add(-time.Second) })
+	require.NoError(t, err)
+}
+
+func stateOf(t *testing.T, store *MemoryStore) State {
+	state, err := store.Update("CreditAccount", func(*State) {})
+	require.NoError(t, err)
+	return state
+}
+
+// retryDelayOf returns the NextRetryDelay of a CircuitOpen rejection
+func retryDelayOf(t *testing.T, err error) time.Duration {
+	require.True(t, errs.CircuitOpen.Is(err), "expected a CircuitOpen rejection, got %v", err)
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	return appErr.NextRetryDelay()
+}
+
+func TestBreakerOpensAfterConsecutiveRetryableFailures(t *testing.T) {
+	breaker, store := newTestBreaker()
+	ctx := context.Background()
+	outage := errs.ServiceUnavailable.New("ledger down")
+
+	require.ErrorIs(t, breaker.Execute(ctx, fail(outage)), outage)
+	require.ErrorIs(t, breaker.Execute(ctx, fail(outage)), outage)
+	// A success resets the count
+	require.NoError(t, breaker.Execute(ctx, succeed))
+	assert.Zero(t, stateOf(t, store).ConsecutiveFailures)
+
+	// Business errors say nothing about the downstream's health
+	for range 5 {
+		breaker.Execute(ctx, fail(errs.InsufficientFunds.New("balance too low")))
+	}
+	assert.Equal(t, Closed, stateOf(t, store).Status)
+
+	for range testConfig.FailureThreshold {
+		breaker.Execute(ctx, fail(outage))
+	}
+	state := stateOf(t, store)
+	assert.Equal(t, Open, state.Status)
+	assert.Equal(t, 3, state.ConsecutiveFailures)
+	assert.Contains(t, state.LastFailure, "ledger down")
+}
+
+func TestOpenBreakerFailsFastUntilCooldownEnds(t *testing.T) {
+	breaker, store := newTestBreaker()
+	ctx := context.Background()
+	for range testConfig.FailureThreshold {
+		breaker.Execute(ctx, fail(errs.ServiceUnavailable.New("ledger down")))
+	}
+
+	called := false
+	err := breaker.Execute(ctx, func() error {
+		called = true
+		return nil
+	})
+	assert.False(t, called, "an open circuit must not call the downstream")
+	delay := retryDelayOf(t, err)
+	assert.Greater(t, delay, testConfig.OpenDuration-5*time.Second)
+	assert.LessOrEqual(t, delay, testConfig.OpenDuration)
+	assert.Equal(t, int64(1), stateOf(t, store).Rejected)
+}
+
+func TestHalfOpenProbeClosesBreaker(t *testing.T) {
+	breaker, store := newTestBreaker()
+	ctx := context.Background()
+	for range testConfig.FailureThreshold {
+		breaker.Execute(ctx, fail(errs.ServiceUnavailable.New("ledger down")))
+	}
+	endCooldown(t, store)
+
+	var probeState State
+	var concurrentErr error
+	err := breaker.Execute(ctx, func() error {
+		probeState = stateOf(t, store)
+		// Another caller arrives while the single probe is in flight
+		concurrentErr = breaker.Execute(ctx, succeed)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, HalfOpen, probeState.Status)
+	assert.Equal(t, 1, probeState.ProbesInFlight)
+
+	// The cooldown has already passed; the rejected caller waits a full one
+	// instead of burning an attempt every second while the probe runs
+	assert.Equal(t, testConfig.OpenDuration, retryDelayOf(t, concurrentErr))
+
+	state := stateOf(t, store)
+	assert.Equal(t, Closed, state.Status)
+	assert.Zero(t, state.ConsecutiveFailures)
+	assert.Zero(t, state.ProbesInFlight)
+	require.NoError(t, breaker.Execute(ctx, succeed))
+}
+
+func TestFailedProbeReopensBreaker(t *testing.T) {
+	breaker, store := newTestBreaker()
+	ctx := context.Background()
+	for range testConfig.FailureThreshold {
+		breaker.Execute(ctx, fail(errs.ServiceUnavailable.New("ledger down")))
+	}
+	endCooldown(t, store)
+
+	before := time.Now()
+	breaker.Execute(ctx, fail(errs.ServiceUnavailable.New("still down")))
+
+	// One failed probe is enough, whatever the threshold
+	state := stateOf(t, store)
+	assert.Equal(t, Open, state.Status)
+	assert.Zero(t, state.ProbesInFlight)
+	assert.False(t, state.OpenUntil.Before(before.Add(testConfig.OpenDuration)), "a new cooldown starts")
+	retryDelayOf(t, breaker.Execute(ctx, succeed))
+}
+
+func TestStuckProbeIsForgotten(t *testing.T) {
+	breaker, store := newTestBreaker()
+	ctx := context.Background()
+	_, err := store.Update("CreditAccount", func(s *State) {
+		s.Status = HalfOpen
+		s.ProbesInFlight = 1
+		s.ProbeStartedAt = time.Now().Add(-testConfig.ProbeTimeout - time.Second)
+	})
+	require.NoError(t, err)
+
+	// The worker running the probe died; after the probe timeout a new one goes through
+	require.NoError(t, breaker.Execute(ctx, succeed))
+	assert.Equal(t, Closed, stateOf(t, store).Status)
+}
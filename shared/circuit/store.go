@@ -0,0 +1,90 @@
+package circuit
+
+import (
+	"sync"
+	"time"
+
+	"temporal-go-examples/shared/filestore"
+)
+
+// Store persists circuit states
+// Update must apply fn atomically: no other update of the same store may interleave
+type Store interface {
+	Update(name string, fn func(*State)) (State, error)
+	List() ([]State, error)
+}
+
+// normalize fills in defaults for a state seen for the first time
+func normalize(name string, s State) State {
+	s.Name = name
+	if s.Status == "" {
+		s.Status = Closed
+	}
+	return s
+}
+
+// MemoryStore keeps circuit states in process memory; each worker has its own circuits
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]State
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]State)}
+}
+
+// Update applies fn to a circuit's state
+func (m *MemoryStore) Update(name string, fn func(*State)) (State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state := normalize(name, m.states[name])
+	fn(&state)
+	state.UpdatedAt = time.Now()
+	m.states[name] = state
+	return state, nil
+}
+
+// List returns every known circuit
+func (m *MemoryStore) List() ([]State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	states := make([]State, 0, len(m.states))
+	for _, s := range m.states {
+		states = append(states, s)
+	}
+	return states, nil
+}
+
+// FileStore keeps circuit states in a JSON file so every worker on a host (or on
+// a shared volume) sees the same circuits. A lock file serialises updates
+type FileStore struct {
+	file filestore.Map[State]
+}
+
+// NewFileStore creates a store backed by the file at path
+func NewFileStore(path string) *FileStore {
+	return &FileStore{file: filestore.Map[State]{Path: path}}
+}
+
+// Update applies fn to a circuit's state under the file lock
+func (f *FileStore) Update(name string, fn func(*State)) (State, error) {
+	return f.file.Update(name, func(s *State) {
+		*s = normalize(name, *s)
+		fn(s)
+		s.UpdatedAt = time.Now()
+	})
+}
+
+// List returns every known circuit
+func (f *FileStore) List() ([]State, error) {
+	states, err := f.file.Read()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]State, 0, len(states))
+	for _, s := range states {
+		result = append(result, s)
+	}
+	return result, nil
+}
@@ -0,0 +1,166 @@
+// Package errs is the error taxonomy shared by all activities and workflows
+//
+// Every error returned by an activity should be built from a Kind. A Kind pairs
+// the ApplicationError type string (what Temporal sees and what retry policies
+// match on) with a Category, and the Category decides whether Temporal retries it.
+// That way nothing becomes retryable by accident the way a bare fmt.Errorf does.
+package errs
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// Category groups error kinds by how they should be handled
+type Category string
+
+const (
+	// Validation errors mean the input is malformed; retrying cannot help
+	Validation Category = "validation"
+	// Business errors mean a business rule said no, e.g. insufficient funds
+	Business Category = "business"
+	// Transient errors are short-lived hiccups such as timeouts; retry soon
+	Transient Category = "transient"
+	// Dependency errors mean a downstream system is failing; retry with backoff
+	Dependency Category = "dependency"
+	// Fatal errors need a human: bugs, corrupt data, failed compensations
+	Fatal Category = "fatal"
+	// Unknown is returned when an error can't be classified
+	Unknown Category = "unknown"
+)
+
+// Retryable reports the default retryability of the category
+func (c Category) Retryable() bool {
+	return c == Transient || c == Dependency
+}
+
+// Kind is a named, categorised error type
+type Kind struct {
+	Type     string
+	Category Category
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Category{}
+)
+
+// Define registers a new error kind; define kinds at package level so every
+// worker and client process knows the same taxonomy
+func Define(errType string, category Category) Kind {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[errType] = category
+	return Kind{Type: errType, Category: category}
+}
+
+// New builds an ApplicationError of this kind
+// Details are attached as payloads the caller can decode with Details
+func (k Kind) New(message string, details ...interface{}) error {
+	return k.Wrap(nil, message, details...)
+}
+
+// Wrap builds an ApplicationError of this kind with an underlying cause
+func (k Kind) Wrap(cause error, message string, details ...interface{}) error {
+	return k.WithOptions(message, temporal.ApplicationErrorOptions{Cause: cause, Details: details})
+}
+
+// WithOptions builds an ApplicationError of this kind from explicit options
+// NonRetryable and Category are always taken from the kind
+func (k Kind) WithOptions(message string, options temporal.ApplicationErrorOptions) error {
+	options.NonRetryable = !k.Category.Retryable()
+	if k.Category == Validation || k.Category == Business {
+		// Expected outcomes: keep them out of error logs and alerting metrics
+		options.Category = temporal.ApplicationErrorCategoryBenign
+	}
+	return temporal.NewApplicationErrorWithOptions(message, k.Type, options)
+}
+
+// Is reports whether err, or any error it wraps, is of this kind
+func (k Kind) Is(err error) bool {
+	var appErr *temporal.ApplicationError
+	return errors.As(err, &appErr) && appErr.Type() == k.Type
+}
+
+// CategoryOf returns the category registered for an error type
+func CategoryOf(errType string) Category {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if category, ok := registry[errType]; ok {
+		return category
+	}
+	return Unknown
+}
+
+// NonRetryableTypes lists every registered type whose category is not retryable,
+// sorted so it is stable across processes; use it for RetryPolicy.NonRetryableErrorTypes
+func NonRetryableTypes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	var types []string
+	for errType, category := range registry {
+		if !category.Retryable() {
+			types = append(types, errType)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Classification describes an error as seen by a workflow or client
+type Classification struct {
+	Category  Category
+	Type      string
+	Message   string
+	Retryable bool
+}
+
+// Classify finds the ApplicationError in an error chain (for example inside an
+// ActivityError or WorkflowExecutionError) and maps it back to its category
+// Timeouts are treated as transient and panics as fatal; anything else is Unknown
+func Classify(err error) Classification {
+	if err == nil {
+		return Classification{}
+	}
+
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) {
+		category := CategoryOf(appErr.Type())
+		retryable := !appErr.NonRetryable()
+		if category == Unknown && retryable {
+			// Unregistered retryable errors, e.g. a plain fmt.Errorf from an activity
+			category = Transient
+		} else if category == Unknown {
+			category = Fatal
+		}
+		return Classification{
+			Category:  category,
+			Type:      appErr.Type(),
+			Message:   appErr.Message(),
+			Retryable: retryable,
+		}
+	}
+
+	var timeoutErr *temporal.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return Classification{Category: Transient, Type: "Timeout", Message: timeoutErr.Message(), Retryable: true}
+	}
+	var panicErr *temporal.PanicError
+	if errors.As(err, &panicErr) {
+		return Classification{Category: Fatal, Type: "Panic", Message: panicErr.Error()}
+	}
+	return Classification{Category: Unknown, Message: err.Error()}
+}
+
+// Details decodes the payloads attached to the ApplicationError in an error chain
+// It returns false if there is no ApplicationError or it carries no details
+func Details(err error, d ...interface{}) bool {
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || !appErr.HasDetails() {
+		return false
+	}
+	return appErr.Details(d...) == nil
+}
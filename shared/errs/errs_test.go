@@ -0,0 +1,121 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+// roundTrip sends an error through the failure converter, as it travels
+// from an activity or workflow to its caller
+func roundTrip(err error) error {
+	fc := temporal.GetDefaultFailureConverter()
+	return fc.FailureToError(fc.ErrorToFailure(err))
+}
+
+func TestKindsGetRetryabilityFromCategory(t *testing.T) {
+	for _, test := range []struct {
+		kind      Kind
+		retryable bool
+	}{
+		{InvalidAccount, false},
+		{InsufficientFunds, false},
+		{ServiceUnavailable, true},
+		{DependencyFailure, true},
+		{CompensationFailed, false},
+	} {
+		t.Run(test.kind.Type, func(t *testing.T) {
+			var appErr *temporal.ApplicationError
+			require.ErrorAs(t, test.kind.New("boom"), &appErr)
+			assert.Equal(t, test.kind.Type, appErr.Type())
+			assert.Equal(t, !test.retryable, appErr.NonRetryable())
+			assert.Equal(t, test.retryable, test.kind.Category.Retryable())
+		})
+	}
+}
+
+func TestExpectedOutcomesAreBenign(t *testing.T) {
+	var appErr *temporal.ApplicationError
+	require.ErrorAs(t, PaymentDeclined.New("card declined"), &appErr)
+	assert.Equal(t, temporal.ApplicationErrorCategoryBenign, appErr.Category())
+
+	require.ErrorAs(t, Internal.New("bug"), &appErr)
+	assert.NotEqual(t, temporal.ApplicationErrorCategoryBenign, appErr.Category())
+}
+
+func TestKindIsSurvivesRoundTrip(t *testing.T) {
+	err := roundTrip(InsufficientFunds.New("balance too low", 42.5))
+
+	assert.True(t, InsufficientFunds.Is(err))
+	assert.False(t, PaymentDeclined.Is(err))
+	var amount float64
+	require.True(t, Details(err, &amount))
+	assert.Equal(t, 42.5, amount)
+}
+
+func TestKindIsFindsWrappedErrors(t *testing.T) {
+	cause := ServiceUnavailable.New("gateway timed out")
+	err := TransferCompensated.Wrap(cause, "transfer failed but system is consistent", "debit-1")
+
+	// The outer kind is what Is and Classify see, through any number of layers
+	wrapped := fmt.Errorf("credit step: %w", roundTrip(err))
+	assert.True(t, TransferCompensated.Is(wrapped))
+	class := Classify(wrapped)
+	assert.Equal(t, Business, class.Category)
+	assert.False(t, class.Retryable)
+
+	// The cause keeps its own kind once unwrapped
+	var appErr *temporal.ApplicationError
+	require.ErrorAs(t, wrapped, &appErr)
+	assert.True(t, ServiceUnavailable.Is(appErr.Unwrap()))
+}
+
+func TestClassify(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		err       error
+		category  Category
+		errType   string
+		retryable bool
+	}{
+		{"validation", InvalidOrder.New("no items"), Validation, "InvalidOrder", false},
+		{"business", OutOfStock.New("sold out"), Business, "OutOfStock", false},
+		{"transient", RateLimited.New("slow down"), Transient, "RateLimited", true},
+		{"dependency", DependencyFailure.New("db down"), Dependency, "DependencyFailure", true},
+		{"fatal", ManualReviewRequired.New("help"), Fatal, "ManualReviewRequired", false},
+		{"unregistered retryable", temporal.NewApplicationError("flaky", "SomethingElse"), Transient, "SomethingElse", true},
+		{"unregistered non-retryable", temporal.NewNonRetryableApplicationError("broken", "SomethingElse", nil), Fatal, "SomethingElse", false},
+		{"timeout", temporal.NewTimeoutError(0, nil), Transient, "Timeout", true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, err := range []error{test.err, roundTrip(test.err)} {
+				class := Classify(err)
+				assert.Equal(t, test.category, class.Category)
+				assert.Equal(t, test.errType, class.Type)
+				assert.Equal(t, test.retryable, class.Retryable)
+			}
+		})
+	}
+	assert.Equal(t, Classification{}, Classify(nil))
+	assert.Equal(t, Classification{Category: Unknown, Message: "no idea"}, Classify(errors.New("no idea")))
+}
+
+func TestNonRetryableTypes(t *testing.T) {
+	types := NonRetryableTypes()
+
+	assert.Contains(t, types, "InvalidAccount")
+	assert.Contains(t, types, "CompensationFailed")
+	assert.NotContains(t, types, "ServiceUnavailable")
+	assert.NotContains(t, types, "DependencyFailure")
+	assert.IsIncreasing(t, types)
+}
+
+func TestDetailsWithoutApplicationError(t *testing.T) {
+	var v string
+	assert.False(t, Details(errors.New("plain"), &v))
+	assert.False(t, Details(Internal.New("no details"), &v))
+}
@@ -0,0 +1,89 @@
+package errs
+
+import (
+	"errors"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// Failure kinds reported by Describe
+const (
+	FailureApplication = "application"
+	FailureTimeout     = "timeout"
+	FailureCanceled    = "canceled"
+	FailurePanic       = "panic"
+	FailureUnknown     = "unknown"
+)
+
+// Failure is a structured, serialisable description of an error chain
+// Workflows attach it to the errors they return so clients get more than a string
+type Failure struct {
+	Kind         string   `json:"kind"`
+	Type         string   `json:"type,omitempty"`
+	Category     Category `json:"category"`
+	Message      string   `json:"message"`
+	Retryable    bool     `json:"retryable"`
+	ActivityType string   `json:"activity_type,omitempty"`
+	RetryState   string   `json:"retry_state,omitempty"` // Why Temporal stopped retrying, e.g. MaximumAttemptsReached
+	TimeoutType  string   `json:"timeout_type,omitempty"`
+	Attempts     int32    `json:"attempts,omitempty"` // Filled in by callers that know the attempt count
+	Cause        string   `json:"cause,omitempty"`    // Message of the innermost error in the chain
+}
+
+// Describe walks an error chain with errors.As instead of type assertions, so it
+// works no matter how many ActivityError, ChildWorkflowExecutionError or
+// WorkflowExecutionError layers wrap the interesting error
+func Describe(err error) Failure {
+	if err == nil {
+		return Failure{}
+	}
+
+	class := Classify(err)
+	failure := Failure{
+		Kind:      FailureUnknown,
+		Type:      class.Type,
+		Category:  class.Category,
+		Message:   class.Message,
+		Retryable: class.Retryable,
+		Cause:     rootCause(err).Error(),
+	}
+
+	var activityErr *temporal.ActivityError
+	if errors.As(err, &activityErr) {
+		if activityType := activityErr.ActivityType(); activityType != nil {
+			failure.ActivityType = activityType.GetName()
+		}
+		failure.RetryState = activityErr.RetryState().String()
+	}
+
+	var canceledErr *temporal.CanceledError
+	var panicErr *temporal.PanicError
+	var timeoutErr *temporal.TimeoutError
+	var appErr *temporal.ApplicationError
+	switch {
+	case errors.As(err, &canceledErr):
+		failure.Kind = FailureCanceled
+		failure.Message = canceledErr.Error()
+	case errors.As(err, &panicErr):
+		failure.Kind = FailurePanic
+		failure.Category = Fatal
+		failure.Message = panicErr.Error()
+	case errors.As(err, &timeoutErr):
+		failure.Kind = FailureTimeout
+		failure.TimeoutType = timeoutErr.TimeoutType().String()
+	case errors.As(err, &appErr):
+		failure.Kind = FailureApplication
+	}
+	return failure
+}
+
+// rootCause returns the innermost error of a chain
+func rootCause(err error) error {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+}
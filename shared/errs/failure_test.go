@@ -0,0 +1,102 @@
+package errs
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	failurepb "go.temporal.io/api/failure/v1"
+	"go.temporal.io/sdk/temporal"
+)
+
+// activityFailure is the error a workflow gets from an activity that failed
+// with cause, built from the failure the server sends back
+func activityFailure(retryState enumspb.RetryState, cause *failurepb.Failure) error {
+	fc := temporal.GetDefaultFailureConverter()
+	return fc.FailureToError(&failurepb.Failure{
+		Message: "activity error",
+		Cause:   cause,
+		FailureInfo: &failurepb.Failure_ActivityFailureInfo{ActivityFailureInfo: &failurepb.ActivityFailureInfo{
+			ActivityType: &commonpb.ActivityType{Name: "DebitAccount"},
+			ActivityId:   "5",
+			RetryState:   retryState,
+		}},
+	})
+}
+
+func failureOf(err error) *failurepb.Failure {
+	return temporal.GetDefaultFailureConverter().ErrorToFailure(err)
+}
+
+func TestDescribeActivityError(t *testing.T) {
+	err := activityFailure(enumspb.RETRY_STATE_MAXIMUM_ATTEMPTS_REACHED, failureOf(ServiceUnavailable.New("gateway down")))
+
+	failure := Describe(fmt.Errorf("debit: %w", err))
+	assert.Equal(t, FailureApplication, failure.Kind)
+	assert.Equal(t, "ServiceUnavailable", failure.Type)
+	assert.Equal(t, Transient, failure.Category)
+	assert.True(t, failure.Retryable)
+	assert.Equal(t, "DebitAccount", failure.ActivityType)
+	assert.Equal(t, enumspb.RETRY_STATE_MAXIMUM_ATTEMPTS_REACHED.String(), failure.RetryState)
+	assert.Contains(t, failure.Cause, "gateway down")
+	assert.Zero(t, failure.Attempts, "Describe can't know the attempt count")
+
+	// A non-retryable kind stops at once
+	failure = Describe(activityFailure(enumspb.RETRY_STATE_NON_RETRYABLE_FAILURE, failureOf(InvalidAccount.New("no such account"))))
+	assert.Equal(t, FailureApplication, failure.Kind)
+	assert.Equal(t, "InvalidAccount", failure.Type)
+	assert.False(t, failure.Retryable)
+	assert.Equal(t, enumspb.RETRY_STATE_NON_RETRYABLE_FAILURE.String(), failure.RetryState)
+}
+
+func TestDescribeTimeoutError(t *testing.T) {
+	lastErr := ServiceUnavailable.New("still waiting")
+	err := activityFailure(enumspb.RETRY_STATE_TIMEOUT, failureOf(temporal.NewTimeoutError(enumspb.TIMEOUT_TYPE_SCHEDULE_TO_CLOSE, lastErr)))
+
+	failure := Describe(err)
+	assert.Equal(t, FailureTimeout, failure.Kind)
+	assert.Equal(t, enumspb.TIMEOUT_TYPE_SCHEDULE_TO_CLOSE.String(), failure.TimeoutType)
+	assert.Equal(t, enumspb.RETRY_STATE_TIMEOUT.String(), failure.RetryState)
+	assert.Equal(t, "DebitAccount", failure.ActivityType)
+	assert.Contains(t, failure.Cause, "still waiting", "the cause is the last attempt's error")
+
+	// Without an activity around it, e.g. a heartbeat timeout seen in the activity
+	failure = Describe(temporal.NewHeartbeatTimeoutError())
+	assert.Equal(t, FailureTimeout, failure.Kind)
+	assert.Equal(t, enumspb.TIMEOUT_TYPE_HEARTBEAT.String(), failure.TimeoutType)
+	assert.Empty(t, failure.ActivityType)
+	assert.Empty(t, failure.RetryState)
+}
+
+func TestDescribeCanceledError(t *testing.T) {
+	err := activityFailure(enumspb.RETRY_STATE_CANCEL_REQUESTED, failureOf(temporal.NewCanceledError("order canceled")))
+
+	failure := Describe(err)
+	assert.Equal(t, FailureCanceled, failure.Kind)
+	assert.Equal(t, "DebitAccount", failure.ActivityType)
+	assert.Equal(t, enumspb.RETRY_STATE_CANCEL_REQUESTED.String(), failure.RetryState)
+	assert.Equal(t, "canceled", failure.Message)
+}
+
+func TestDescribePanicError(t *testing.T) {
+	// Activity panics travel as an application failure of type PanicError
+	err := activityFailure(enumspb.RETRY_STATE_MAXIMUM_ATTEMPTS_REACHED, &failurepb.Failure{
+		Message:    "runtime error: index out of range",
+		StackTrace: "goroutine 1 [running]:",
+		FailureInfo: &failurepb.Failure_ApplicationFailureInfo{ApplicationFailureInfo: &failurepb.ApplicationFailureInfo{
+			Type: "PanicError",
+		}},
+	})
+
+	failure := Describe(err)
+	assert.Equal(t, FailurePanic, failure.Kind)
+	assert.Equal(t, Fatal, failure.Category, "a panic is a bug, whatever Classify guessed")
+	assert.Equal(t, "runtime error: index out of range", failure.Message)
+	assert.Equal(t, "DebitAccount", failure.ActivityType)
+}
+
+func TestDescribeNil(t *testing.T) {
+	assert.Equal(t, Failure{}, Describe(nil))
+}
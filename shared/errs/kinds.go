@@ -0,0 +1,58 @@
+package errs
+
+// Error kinds used across the examples
+// The type strings are what appears in the Temporal UI and in retry policies
+var (
+	// InvalidAccount means an account does not exist or cannot be used
+	InvalidAccount = Define("InvalidAccount", Validation)
+	// InvalidOrder means an order is missing required fields
+	InvalidOrder = Define("InvalidOrder", Validation)
+	// InvalidBatch means a batch file is missing or cannot be parsed
+	InvalidBatch = Define("InvalidBatch", Validation)
+	// UnsupportedCurrency means no rate exists for a currency
+	UnsupportedCurrency = Define("UnsupportedCurrency", Validation)
+	// FXQuoteExpired means a locked exchange rate ran out before it was used
+	FXQuoteExpired = Define("FXQuoteExpired", Validation)
+	// InvalidDeliveryChange means a change to a delivery, such as a new address, is malformed
+	InvalidDeliveryChange = Define("InvalidDeliveryChange", Validation)
+
+	// InsufficientFunds means the source account cannot cover the amount
+	InsufficientFunds = Define("InsufficientFunds", Business)
+	// TransferRejected means the risk stage declined a transfer
+	TransferRejected = Define("TransferRejected", Business)
+	// TransferCompensated means a transfer failed and its debit was reversed
+	TransferCompensated = Define("TransferCompensated", Business)
+	// PaymentDeclined means the payment gateway refused a payment
+	PaymentDeclined = Define("PaymentDeclined", Business)
+	// PaymentVoided means a payment was voided and must not be submitted or completed
+	PaymentVoided = Define("PaymentVoided", Business)
+	// OrderRejected means the fraud check declined an order
+	OrderRejected = Define("OrderRejected", Business)
+	// OutOfStock means there is not enough stock to reserve for an order
+	OutOfStock = Define("OutOfStock", Business)
+	// NotificationRejected means a recipient or notification endpoint refused a message
+	NotificationRejected = Define("NotificationRejected", Business)
+	// DeliveryDispatched means a delivery has left and can no longer be changed
+	DeliveryDispatched = Define("DeliveryDispatched", Business)
+	// ReservationReleased means stock was requested for a reservation the order already released
+	ReservationReleased = Define("ReservationReleased", Business)
+
+	// ServiceUnavailable means a service is temporarily down or timing out
+	ServiceUnavailable = Define("ServiceUnavailable", Transient)
+	// CircuitOpen means a circuit breaker is failing calls fast; its
+	// NextRetryDelay says when the circuit will let a probe through
+	CircuitOpen = Define("CircuitOpen", Transient)
+	// RateLimited means a rate limiter had no token soon enough; its
+	// NextRetryDelay says when the next token will be free
+	RateLimited = Define("RateLimited", Transient)
+
+	// DependencyFailure means a downstream system (database, gateway) is failing
+	DependencyFailure = Define("DependencyFailure", Dependency)
+
+	// CompensationFailed means a failed transfer could not be reversed
+	CompensationFailed = Define("CompensationFailed", Fatal)
+	// ManualReviewRequired means automation cannot resolve the problem
+	ManualReviewRequired = Define("ManualReviewRequired", Fatal)
+	// Internal means a bug or unexpected state in our own code
+	Internal = Define("Internal", Fatal)
+)
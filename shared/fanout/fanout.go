@@ -0,0 +1,116 @@
+// Package fanout runs independent workflow steps in parallel and gathers the results
+//
+// Each task runs in its own workflow.Go coroutine, at most Limit at a time. When
+// a required task fails, the others are canceled: there is no point reserving
+// stock for an order the fraud check just rejected. Failures are joined into one
+// error so the caller sees every reason, not just the first.
+package fanout
+
+import (
+	"errors"
+	"fmt"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Task statuses
+const (
+	Succeeded = "succeeded"
+	Failed    = "failed"
+	Canceled  = "canceled"
+)
+
+// Task is one independent step
+type Task struct {
+	Name string
+	// Optional tasks may fail without canceling the others or failing the fan-out
+	Optional bool
+	// Run must stop when ctx is canceled; activities started with ctx do so for you
+	Run func(ctx workflow.Context) error
+}
+
+// Result is the outcome of one task
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Finished int    `json:"finished"` // 1 for the first task to finish, 2 for the next, ...
+	err      error
+}
+
+// Err returns the task's error, if it failed or was canceled
+func (r Result) Err() error {
+	return r.err
+}
+
+// Run starts the tasks, at most limit at once (0 means no limit), and waits for all of them
+// Results come back in task order. The error joins every required task's failure;
+// tasks canceled because of such a failure are reported in the results only
+func Run(ctx workflow.Context, limit int, tasks ...Task) ([]Result, error) {
+	if limit <= 0 || limit > len(tasks) {
+		limit = len(tasks)
+	}
+	runCtx, cancel := workflow.WithCancel(ctx)
+	defer cancel()
+	semaphore := workflow.NewSemaphore(runCtx, int64(limit))
+
+	// Buffered so a finishing task never waits for the collector below
+	type done struct {
+		index int
+		err   error
+	}
+	finished := workflow.NewBufferedChannel(ctx, len(tasks))
+
+	for i, task := range tasks {
+		workflow.Go(runCtx, func(ctx workflow.Context) {
+			if err := semaphore.Acquire(ctx, 1); err != nil {
+				// Canceled while waiting for a slot: the task never started
+				finished.Send(ctx, done{index: i, err: err})
+				return
+			}
+			defer semaphore.Release(1)
+			finished.Send(ctx, done{index: i, err: task.Run(ctx)})
+		})
+	}
+
+	results := make([]Result, len(tasks))
+	var failures []error
+	canceled := false
+	for n := 1; n <= len(tasks); n++ {
+		var d done
+		finished.Receive(ctx, &d)
+		task := tasks[d.index]
+		result := Result{Name: task.Name, Status: Succeeded, Finished: n, err: d.err}
+
+		switch {
+		case d.err == nil:
+		case (canceled || ctx.Err() != nil) && isCanceled(d.err):
+			result.Status = Canceled
+		default:
+			result.Status = Failed
+			if !task.Optional {
+				failures = append(failures, fmt.Errorf("%s: %w", task.Name, d.err))
+				if !canceled {
+					workflow.GetLogger(ctx).Warn("Required task failed, canceling the others", "task", task.Name, "error", d.err)
+					canceled = true
+					cancel()
+				}
+			}
+		}
+		if d.err != nil {
+			result.Error = d.err.Error()
+		}
+		results[d.index] = result
+	}
+	if len(failures) == 0 && ctx.Err() != nil {
+		// The workflow itself was canceled
+		return results, ctx.Err()
+	}
+	return results, errors.Join(failures...)
+}
+
+// isCanceled reports whether err means the task stopped because it was canceled
+func isCanceled(err error) bool {
+	return temporal.IsCanceledError(err) || errors.Is(err, workflow.ErrCanceled)
+}
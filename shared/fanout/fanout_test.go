@@ -0,0 +1,171 @@
+package fanout
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+var (
+	errFraud   = errors.New("fraud check rejected the order")
+	errAddress = errors.New("address could not be verified")
+)
+
+// sleepTask finishes after d, or fails with err once d has passed
+func sleepTask(name string, d time.Duration, err error) Task {
+	return Task{
+		Name: name,
+		Run: func(ctx workflow.Context) error {
+			if sleepErr := workflow.Sleep(ctx, d); sleepErr != nil {
+				return sleepErr
+			}
+			return err
+		},
+	}
+}
+
+// runFanout runs the tasks inside a test workflow and returns Run's results,
+// how long the fan-out took in workflow time, and its error
+func runFanout(t *testing.T, limit int, tasks ...Task) ([]Result, time.Duration, error) {
+	var results []Result
+	var runErr error
+	var took time.Duration
+	fanoutWorkflow := func(ctx workflow.Context) error {
+		start := workflow.Now(ctx)
+		results, runErr = Run(ctx, limit, tasks...)
+		took = workflow.Now(ctx).Sub(start)
+		return nil
+	}
+
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflowWithOptions(fanoutWorkflow, workflow.RegisterOptions{Name: "FanoutWorkflow"})
+	env.ExecuteWorkflow("FanoutWorkflow")
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	return results, took, runErr
+}
+
+func TestRunReturnsResultsInTaskOrder(t *testing.T) {
+	results, _, err := runFanout(t, 0,
+		sleepTask("slow", 3*time.Second, nil),
+		sleepTask("fast", time.Second, nil),
+		sleepTask("medium", 2*time.Second, nil),
+	)
+
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for i, want := range []struct {
+		name     string
+		finished int
+	}{{"slow", 3}, {"fast", 1}, {"medium", 2}} {
+		assert.Equal(t, want.name, results[i].Name)
+		assert.Equal(t, Succeeded, results[i].Status)
+		assert.Equal(t, want.finished, results[i].Finished, want.name)
+	}
+}
+
+func TestRunRespectsConcurrencyLimit(t *testing.T) {
+	running, peak := 0, 0
+	var tasks []Task
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		tasks = append(tasks, Task{
+			Name: name,
+			Run: func(ctx workflow.Context) error {
+				running++
+				peak = max(peak, running)
+				defer func() { running-- }()
+				return workflow.Sleep(ctx, time.Second)
+			},
+		})
+	}
+
+	results, took, err := runFanout(t, 2, tasks...)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, peak)
+	// Five one-second tasks, two at a time, take three rounds
+	assert.Equal(t, 3*time.Second, took)
+	for _, result := range results {
+		assert.Equal(t, Succeeded, result.Status)
+	}
+}
+
+func TestRunCancelsOthersWhenRequiredTaskFails(t *testing.T) {
+	results, took, err := runFanout(t, 2,
+		sleepTask("fraud", time.Second, errFraud),
+		sleepTask("reserve", time.Hour, nil),
+		sleepTask("queued", time.Second, nil), // Still waiting for a slot when fraud fails
+	)
+
+	require.ErrorIs(t, err, errFraud)
+	assert.Less(t, took, time.Hour, "the other tasks should have been canceled")
+	assert.Equal(t, Failed, results[0].Status)
+	assert.ErrorIs(t, results[0].Err(), errFraud)
+	assert.Equal(t, Canceled, results[1].Status)
+	assert.Equal(t, Canceled, results[2].Status)
+	// Canceled tasks are reported in the results, not in the joined error
+	assert.NotContains(t, err.Error(), "reserve")
+	assert.NotContains(t, err.Error(), "queued")
+}
+
+func TestRunToleratesOptionalFailures(t *testing.T) {
+	address := sleepTask("address", time.Second, errAddress)
+	address.Optional = true
+
+	results, took, err := runFanout(t, 0,
+		address,
+		sleepTask("reserve", 2*time.Second, nil),
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, took)
+	assert.Equal(t, Failed, results[0].Status)
+	assert.Equal(t, errAddress.Error(), results[0].Error)
+	assert.Equal(t, Succeeded, results[1].Status)
+}
+
+func TestRunJoinsEveryRequiredFailure(t *testing.T) {
+	// Both fail before either can be canceled, so both reasons are reported
+	fail := func(name string, err error) Task {
+		return Task{Name: name, Run: func(ctx workflow.Context) error { return err }}
+	}
+
+	results, _, err := runFanout(t, 0,
+		fail("fraud", errFraud),
+		fail("address", errAddress),
+	)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errFraud)
+	assert.ErrorIs(t, err, errAddress)
+	assert.Contains(t, err.Error(), "fraud: "+errFraud.Error())
+	assert.Contains(t, err.Error(), "address: "+errAddress.Error())
+	assert.Equal(t, Failed, results[0].Status)
+	assert.Equal(t, Failed, results[1].Status)
+}
+
+func TestRunReturnsWorkflowCancellation(t *testing.T) {
+	var results []Result
+	var runErr error
+	fanoutWorkflow := func(ctx workflow.Context) error {
+		results, runErr = Run(ctx, 0, sleepTask("reserve", time.Hour, nil))
+		return nil
+	}
+
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflowWithOptions(fanoutWorkflow, workflow.RegisterOptions{Name: "FanoutWorkflow"})
+	env.RegisterDelayedCallback(env.CancelWorkflow, time.Minute)
+	env.ExecuteWorkflow("FanoutWorkflow")
+
+	require.True(t, env.IsWorkflowCompleted())
+	assert.ErrorIs(t, runErr, workflow.ErrCanceled)
+	require.Len(t, results, 1)
+	assert.Equal(t, Canceled, results[0].Status)
+}
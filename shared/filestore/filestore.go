@@ -0,0 +1,150 @@
+// Package filestore keeps a map of JSON records in one file that several
+// processes can update safely. It is how workers on one host (or on a shared
+// volume) share small pieces of state such as circuit breakers and rate limits
+// without running a database.
+package filestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Map is a JSON object of records of type T stored at Path
+// A lock file next to it serialises updates from every process
+type Map[T any] struct {
+	Path string
+}
+
+// lock takes an exclusive lock on the lock file, waiting up to two seconds
+//
+// The lock is the operating system's file lock (flock, or LockFileEx on
+// Windows) rather than the lock file's existence. The OS drops it as soon as
+// the holder closes the file or dies, so there is no stale lock to take over:
+// a crashed worker never blocks the others, and a slow holder never loses its
+// lock to a waiter that decided it was abandoned. The file itself stays on
+// disk; removing it would let a waiter lock a file no one else can see
+func (m *Map[T]) lock() (func(), error) {
+	lockPath := m.Path + ".lock"
+	lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(time.Second * 2)
+	for {
+		locked, err := tryLock(lockFile)
+		if err != nil {
+			lockFile.Close()
+			return nil, fmt.Errorf("lock %s: %w", lockPath, err)
+		}
+		if locked {
+			return func() {
+				unlockFile(lockFile)
+				lockFile.Close()
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			lockFile.Close()
+			return nil, fmt.Errorf("timed out waiting for %s", lockPath)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+// Read loads every record; a missing or empty file means no records yet
+func (m *Map[T]) Read() (map[string]T, error) {
+	records := make(map[string]T)
+	data, err := os.ReadFile(m.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return records, nil
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", m.Path, err)
+	}
+	return records, nil
+}
+
+// Update applies fn to the record stored under key while holding the lock
+// fn receives the zero value for a key seen for the first time
+func (m *Map[T]) Update(key string, fn func(*T)) (T, error) {
+	var record T
+	unlock, err := m.lock()
+	if err != nil {
+		return record, err
+	}
+	defer unlock()
+
+	records, err := m.Read()
+	if err != nil {
+		return record, err
+	}
+	record = records[key]
+	fn(&record)
+	records[key] = record
+	return record, m.write(records)
+}
+
+// UpdateExisting is Update for a key that is already stored
+// An unknown key is left alone, fn is not called, and ok is false
+func (m *Map[T]) UpdateExisting(key string, fn func(*T)) (record T, ok bool, err error) {
+	unlock, err := m.lock()
+	if err != nil {
+		return record, false, err
+	}
+	defer unlock()
+
+	records, err := m.Read()
+	if err != nil {
+		return record, false, err
+	}
+	record, ok = records[key]
+	if !ok {
+		return record, false, nil
+	}
+	fn(&record)
+	records[key] = record
+	return record, true, m.write(records)
+}
+
+// write replaces the file with records; the caller must hold the lock
+// It writes to a temp file and renames it so readers never see a half-written file
+func (m *Map[T]) write(records map[string]T) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.Path)
+}
+
+// Delete removes the record stored under key, returning it and whether it existed
+func (m *Map[T]) Delete(key string) (T, bool, error) {
+	var record T
+	unlock, err := m.lock()
+	if err != nil {
+		return record, false, err
+	}
+	defer unlock()
+
+	records, err := m.Read()
+	if err != nil {
+		return record, false, err
+	}
+	record, ok := records[key]
+	if !ok {
+		return record, false, nil
+	}
+	delete(records, key)
+	return record, true, m.write(records)
+}
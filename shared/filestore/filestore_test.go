@@ -0,0 +1,121 @@
+package filestore
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type counter struct {
+	N int `json:"n"`
+}
+
+func TestConcurrentUpdatesAreSerialised(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counters.json")
+	const workers, updates = 16, 25
+
+	// Each worker has its own Map, as separate processes would
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*updates)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m := &Map[counter]{Path: path}
+			for range updates {
+				_, err := m.Update("hits", func(c *counter) {
+					n := c.N
+					// Widen the window between read and write
+					time.Sleep(time.Microsecond * 100)
+					c.N = n + 1
+				})
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	records, err := (&Map[counter]{Path: path}).Read()
+	require.NoError(t, err)
+	assert.Equal(t, workers*updates, records["hits"].N, "every increment should survive")
+}
+
+func TestSlowHolderKeepsTheLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counters.json")
+	m := &Map[counter]{Path: path}
+	holding := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := m.Update("slow", func(c *counter) {
+			close(holding)
+			<-release
+			c.N = 1
+		})
+		done <- err
+	}()
+	<-holding
+
+	// A waiter times out instead of deciding the lock is abandoned
+	_, err := (&Map[counter]{Path: path}).Update("other", func(c *counter) { c.N = 2 })
+	require.ErrorContains(t, err, "timed out")
+
+	close(release)
+	require.NoError(t, <-done)
+	records, err := m.Read()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]counter{"slow": {N: 1}}, records)
+}
+
+func TestLockIsReleasedWithoutRemovingTheFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counters.json")
+	m := &Map[counter]{Path: path}
+
+	_, err := m.Update("a", func(c *counter) { c.N = 1 })
+	require.NoError(t, err)
+	_, err = os.Stat(path + ".lock")
+	require.NoError(t, err, "the lock file stays; only the OS lock on it is released")
+
+	// The next update gets the lock at once
+	_, err = m.Update("a", func(c *counter) { c.N++ })
+	require.NoError(t, err)
+	record, ok, err := m.Delete("a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, record.N)
+}
+
+func TestUpdateExistingLeavesUnknownKeysAlone(t *testing.T) {
+	m := &Map[counter]{Path: filepath.Join(t.TempDir(), "counters.json")}
+
+	called := false
+	_, ok, err := m.UpdateExisting("missing", func(c *counter) { called = true })
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, called)
+	records, err := m.Read()
+	require.NoError(t, err)
+	assert.Empty(t, records, "no zero record should be stored")
+
+	_, err = m.Update("a", func(c *counter) { c.N = 1 })
+	require.NoError(t, err)
+	record, ok, err := m.UpdateExisting("a", func(c *counter) { c.N++ })
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, record.N)
+}
+
+func TestReadMissingFile(t *testing.T) {
+	records, err := (&Map[counter]{Path: filepath.Join(t.TempDir(), "none.json")}).Read()
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
@@ -0,0 +1,18 @@
+//go:build !unix && !windows
+
+package filestore
+
+import (
+	"errors"
+	"os"
+)
+
+// tryLock fails on platforms without file locks; use an in-memory store there
+func tryLock(f *os.File) (bool, error) {
+	return false, errors.ErrUnsupported
+}
+
+// unlockFile is never reached, since tryLock never succeeds
+func unlockFile(f *os.File) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,53 @@
+package heartbeat
+
+import (
+	"context"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// Progress is the heartbeat detail of ProcessChunks
+type Progress struct {
+	Offset  int `json:"offset"`  // Items before Offset are done
+	Total   int `json:"total"`   // Items in the whole job
+	Resumes int `json:"resumes"` // Times the job resumed from an earlier attempt's heartbeat
+}
+
+// ProcessChunks calls fn for items [start, end) in chunks of chunkSize,
+// heartbeating after every chunk. A retried attempt skips the chunks the
+// previous attempt finished, so fn must tolerate a chunk being redone if the
+// worker died after finishing it but before the heartbeat was sent
+func ProcessChunks(ctx context.Context, total, chunkSize int, fn func(ctx context.Context, start, end int) error) (Progress, error) {
+	logger := activity.GetLogger(ctx)
+	if chunkSize <= 0 {
+		chunkSize = 1
+	}
+
+	progress := Progress{Total: total}
+	if Resume(ctx, &progress) {
+		progress.Resumes++
+		logger.Info("Resuming from last heartbeat", "offset", progress.Offset, "total", progress.Total)
+	}
+
+	h := Start(ctx, progress)
+	defer h.Stop()
+
+	for progress.Offset < total {
+		// Stop between chunks if the workflow canceled us or we timed out
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+		end := progress.Offset + chunkSize
+		if end > total {
+			end = total
+		}
+		if err := fn(ctx, progress.Offset, end); err != nil {
+			return progress, err
+		}
+		progress.Offset = end
+		if err := h.Record(progress); err != nil {
+			return progress, err
+		}
+	}
+	return progress, nil
+}
@@ -0,0 +1,104 @@
+// Package heartbeat helps long-running activities heartbeat and resume
+//
+// An activity with a HeartbeatTimeout must call activity.RecordHeartbeat more
+// often than the timeout, or Temporal assumes its worker died and retries it
+// elsewhere - after seconds instead of the full StartToCloseTimeout. The details
+// sent with the last heartbeat are handed to the next attempt, so a retried
+// activity can pick up where the previous one stopped instead of starting over.
+// Heartbeats are also how an activity learns its workflow asked it to cancel:
+// the activity's context is canceled on the next heartbeat.
+package heartbeat
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// DefaultInterval is used when the activity has no HeartbeatTimeout
+const DefaultInterval = time.Second * 10
+
+// Interval returns how often to heartbeat: a third of the HeartbeatTimeout,
+// so a slow heartbeat or two never trips the timeout
+func Interval(ctx context.Context) time.Duration {
+	timeout := activity.GetInfo(ctx).HeartbeatTimeout
+	if timeout <= 0 {
+		return DefaultInterval
+	}
+	return timeout / 3
+}
+
+// Resume loads the details of the previous attempt's last heartbeat into progress
+// It returns false on the first attempt, or when the previous attempt never heartbeated
+func Resume[T any](ctx context.Context, progress *T) bool {
+	if !activity.HasHeartbeatDetails(ctx) {
+		return false
+	}
+	if err := activity.GetHeartbeatDetails(ctx, progress); err != nil {
+		activity.GetLogger(ctx).Warn("Ignoring unreadable heartbeat details", "error", err)
+		return false
+	}
+	return true
+}
+
+// Heartbeater heartbeats the latest recorded progress on a fixed interval from
+// a background goroutine, so heartbeats keep flowing while a step is running
+type Heartbeater[T any] struct {
+	ctx    context.Context
+	mu     sync.Mutex
+	latest T
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// Start sends a first heartbeat with progress and keeps heartbeating until Stop
+func Start[T any](ctx context.Context, progress T) *Heartbeater[T] {
+	h := &Heartbeater[T]{
+		ctx:    ctx,
+		latest: progress,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	activity.RecordHeartbeat(ctx, progress)
+	go h.run(Interval(ctx))
+	return h
+}
+
+// run heartbeats until Stop is called or the activity's context ends
+func (h *Heartbeater[T]) run(interval time.Duration) {
+	defer close(h.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.mu.Lock()
+			progress := h.latest
+			h.mu.Unlock()
+			activity.RecordHeartbeat(h.ctx, progress)
+		case <-h.stop:
+			return
+		case <-h.ctx.Done():
+			return
+		}
+	}
+}
+
+// Record saves progress, heartbeats it, and reports whether the activity should stop
+// The error is the context's: canceled when the workflow asked the activity to
+// cancel, deadline exceeded when it timed out. Return it from the activity
+func (h *Heartbeater[T]) Record(progress T) error {
+	h.mu.Lock()
+	h.latest = progress
+	h.mu.Unlock()
+	activity.RecordHeartbeat(h.ctx, progress)
+	return h.ctx.Err()
+}
+
+// Stop ends the background heartbeats
+func (h *Heartbeater[T]) Stop() {
+	close(h.stop)
+	<-h.done
+}
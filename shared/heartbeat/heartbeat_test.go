@@ -0,0 +1,122 @@
+package heartbeat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+)
+
+// chunkRun records what one ProcessChunks activity did
+type chunkRun struct {
+	chunks     [][2]int
+	progress   Progress
+	err        error
+	heartbeats []Progress
+	cancel     func() // Called after the chunk starting at cancelAt
+	cancelAt   int
+}
+
+// newChunkEnv registers an activity that processes 10 items in chunks of 3
+func newChunkEnv(t *testing.T, run *chunkRun) *testsuite.TestActivityEnvironment {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestActivityEnvironment()
+	env.SetOnActivityHeartbeatListener(func(info *activity.Info, details converter.EncodedValues) {
+		var progress Progress
+		require.NoError(t, details.Get(&progress))
+		run.heartbeats = append(run.heartbeats, progress)
+	})
+	env.RegisterActivityWithOptions(func(ctx context.Context) (Progress, error) {
+		run.progress, run.err = ProcessChunks(ctx, 10, 3, func(ctx context.Context, start, end int) error {
+			run.chunks = append(run.chunks, [2]int{start, end})
+			if run.cancel != nil && start == run.cancelAt {
+				run.cancel()
+			}
+			return nil
+		})
+		return run.progress, run.err
+	}, activity.RegisterOptions{Name: "ProcessItems"})
+	return env
+}
+
+func TestProcessChunksFromTheStart(t *testing.T) {
+	run := &chunkRun{}
+	env := newChunkEnv(t, run)
+
+	_, err := env.ExecuteActivity("ProcessItems")
+	require.NoError(t, err)
+
+	assert.Equal(t, [][2]int{{0, 3}, {3, 6}, {6, 9}, {9, 10}}, run.chunks)
+	assert.Equal(t, Progress{Offset: 10, Total: 10}, run.progress)
+	// The first heartbeat goes out at once; the SDK throttles the later ones
+	require.NotEmpty(t, run.heartbeats)
+	assert.Equal(t, Progress{Total: 10}, run.heartbeats[0])
+}
+
+func TestProcessChunksResumesFromHeartbeat(t *testing.T) {
+	run := &chunkRun{}
+	env := newChunkEnv(t, run)
+	// The previous attempt finished two chunks before its worker died
+	env.SetHeartbeatDetails(Progress{Offset: 6, Total: 10})
+
+	_, err := env.ExecuteActivity("ProcessItems")
+	require.NoError(t, err)
+
+	assert.Equal(t, [][2]int{{6, 9}, {9, 10}}, run.chunks, "finished chunks should be skipped")
+	assert.Equal(t, Progress{Offset: 10, Total: 10, Resumes: 1}, run.progress)
+	require.NotEmpty(t, run.heartbeats)
+	assert.Equal(t, Progress{Offset: 6, Total: 10, Resumes: 1}, run.heartbeats[0])
+}
+
+func TestResumeWithoutHeartbeat(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestActivityEnvironment()
+	var resumed bool
+	env.RegisterActivityWithOptions(func(ctx context.Context) error {
+		var progress Progress
+		resumed = Resume(ctx, &progress)
+		return nil
+	}, activity.RegisterOptions{Name: "FirstAttempt"})
+
+	_, err := env.ExecuteActivity("FirstAttempt")
+	require.NoError(t, err)
+	assert.False(t, resumed)
+}
+
+func TestProcessChunksStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	run := &chunkRun{cancel: cancel, cancelAt: 3}
+	env := newChunkEnv(t, run)
+	// The activity's context derives from the worker's background context
+	env.SetWorkerOptions(worker.Options{BackgroundActivityContext: ctx})
+
+	_, err := env.ExecuteActivity("ProcessItems")
+	require.Error(t, err)
+
+	// The chunk that was running finishes and is recorded, then the activity
+	// returns the context's error instead of starting another chunk
+	assert.Equal(t, [][2]int{{0, 3}, {3, 6}}, run.chunks)
+	assert.ErrorIs(t, run.err, context.Canceled)
+	assert.Equal(t, Progress{Offset: 6, Total: 10}, run.progress)
+}
+
+func TestIntervalDefaultsWithoutHeartbeatTimeout(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestActivityEnvironment()
+	var interval time.Duration
+	env.RegisterActivityWithOptions(func(ctx context.Context) error {
+		interval = Interval(ctx)
+		return nil
+	}, activity.RegisterOptions{Name: "Interval"})
+
+	_, err := env.ExecuteActivity("Interval")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultInterval, interval)
+}
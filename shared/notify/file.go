@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"temporal-go-examples/shared/errs"
+)
+
+// FileNotifier appends messages as JSON lines to <Dir>/<channel>.jsonl
+// It stands in for channels with no real backend configured
+type FileNotifier struct {
+	Dir string
+}
+
+// Send appends the message to the channel's file
+func (f *FileNotifier) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return errs.DependencyFailure.Wrap(err, "unable to create notification directory")
+	}
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return errs.Internal.Wrap(err, "unable to encode notification")
+	}
+	file, err := os.OpenFile(filepath.Join(f.Dir, msg.Channel+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return errs.DependencyFailure.Wrap(err, "unable to open notification file")
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return errs.DependencyFailure.Wrap(err, "unable to write notification")
+	}
+	return nil
+}
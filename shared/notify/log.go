@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"temporal-go-examples/shared/filestore"
+)
+
+// Delivery records one delivered message
+type Delivery struct {
+	Key         string    `json:"key"`
+	Channel     string    `json:"channel"`
+	To          string    `json:"to"`
+	Subject     string    `json:"subject"`
+	DeliveredAt time.Time `json:"delivered_at"`
+}
+
+// DeliveryLog records delivered messages in a JSON file shared by every worker on a host
+type DeliveryLog struct {
+	file filestore.Map[Delivery]
+}
+
+// NewDeliveryLog creates a log backed by the file at path
+func NewDeliveryLog(path string) *DeliveryLog {
+	return &DeliveryLog{file: filestore.Map[Delivery]{Path: path}}
+}
+
+// DeliveryLogFromEnv uses NOTIFICATION_LOG_FILE, or a file in the temp directory
+func DeliveryLogFromEnv() *DeliveryLog {
+	path := os.Getenv("NOTIFICATION_LOG_FILE")
+	if path == "" {
+		path = filepath.Join(os.TempDir(), "temporal-notification-log.json")
+	}
+	return NewDeliveryLog(path)
+}
+
+// Record adds a delivery, replacing an earlier record with the same key
+func (l *DeliveryLog) Record(d Delivery) error {
+	_, err := l.file.Update(d.Key, func(stored *Delivery) { *stored = d })
+	return err
+}
+
+// Get returns the delivery of a message
+func (l *DeliveryLog) Get(key string) (Delivery, bool, error) {
+	deliveries, err := l.file.Read()
+	if err != nil {
+		return Delivery{}, false, err
+	}
+	d, ok := deliveries[key]
+	return d, ok, nil
+}
+
+// List returns every delivery, oldest first
+func (l *DeliveryLog) List() ([]Delivery, error) {
+	deliveries, err := l.file.Read()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Delivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].DeliveredAt.Before(list[j].DeliveredAt) })
+	return list, nil
+}
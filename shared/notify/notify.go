@@ -0,0 +1,153 @@
+// Package notify delivers templated notifications over pluggable channels
+//
+// A Notifier sends a Message over one channel: email through SMTP, an HTTP
+// webhook or a file sink. A Registry maps channel names to notifiers, and
+// Deliver records every delivered message in a DeliveryLog keyed by the
+// message's Key, so a retried activity never sends the same message twice.
+//
+// Errors follow shared/errs: a temporary failure (connection refused, SMTP 4xx,
+// HTTP 5xx) is a retryable ServiceUnavailable, and a recipient or endpoint that
+// refuses the message is a non-retryable NotificationRejected. Retry timing is
+// left to the activity policy of each channel's activity.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+	"time"
+
+	"temporal-go-examples/shared/errs"
+)
+
+// Channel names
+const (
+	Email   = "email"
+	Webhook = "webhook"
+	File    = "file"
+)
+
+// Message is one rendered notification for one recipient
+type Message struct {
+	Key     string            `json:"key"` // Identifies the message for deduplication, e.g. "order-1:pay_1:email:confirmation"
+	Channel string            `json:"channel"`
+	To      string            `json:"to"` // Email address, or whatever the channel understands
+	Subject string            `json:"subject"`
+	Body    string            `json:"body"`
+	Data    map[string]string `json:"data,omitempty"` // Structured fields for machine consumers such as webhooks
+}
+
+// Notifier sends messages over one channel
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Registry maps channel names to notifiers
+type Registry map[string]Notifier
+
+// Get returns the notifier for a channel, or a non-retryable error if none is configured
+func (r Registry) Get(channel string) (Notifier, error) {
+	n, ok := r[channel]
+	if !ok {
+		return nil, errs.NotificationRejected.New(fmt.Sprintf("notification channel %q is not configured", channel))
+	}
+	return n, nil
+}
+
+// Channels lists the configured channel names
+func (r Registry) Channels() []string {
+	channels := make([]string, 0, len(r))
+	for channel := range r {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// FromEnv builds the registry from the environment:
+//   - NOTIFY_SMTP_ADDR (host:port) and NOTIFY_SMTP_FROM configure email; without
+//     them email goes to the file sink, so the examples run without a mail server
+//   - NOTIFY_WEBHOOK_URL configures the webhook channel; it is absent without it
+//   - NOTIFY_FILE_DIR is where the file sink writes (default: the temp directory)
+func FromEnv() Registry {
+	dir := os.Getenv("NOTIFY_FILE_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "temporal-notifications")
+	}
+	registry := Registry{File: &FileNotifier{Dir: dir}}
+
+	if addr := os.Getenv("NOTIFY_SMTP_ADDR"); addr != "" {
+		from := os.Getenv("NOTIFY_SMTP_FROM")
+		if from == "" {
+			from = "orders@example.com"
+		}
+		registry[Email] = &SMTPNotifier{Addr: addr, From: from}
+	} else {
+		registry[Email] = &FileNotifier{Dir: dir}
+	}
+
+	if url := os.Getenv("NOTIFY_WEBHOOK_URL"); url != "" {
+		registry[Webhook] = &WebhookNotifier{URL: url}
+	}
+	return registry
+}
+
+// Template renders a subject and body with text/template
+type Template struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// MustParseTemplate parses a template and panics on error; for package-level templates
+func MustParseTemplate(name, subject, body string) Template {
+	return Template{
+		subject: template.Must(template.New(name + "-subject").Parse(subject)),
+		body:    template.Must(template.New(name + "-body").Parse(body)),
+	}
+}
+
+// Render fills in the template with data; a missing field is a non-retryable error
+func (t Template) Render(data interface{}) (subject, body string, err error) {
+	var s, b bytes.Buffer
+	if err := t.subject.Option("missingkey=error").Execute(&s, data); err != nil {
+		return "", "", errs.Internal.Wrap(err, "unable to render notification subject")
+	}
+	if err := t.body.Option("missingkey=error").Execute(&b, data); err != nil {
+		return "", "", errs.Internal.Wrap(err, "unable to render notification body")
+	}
+	return s.String(), b.String(), nil
+}
+
+// Deliver sends a message unless the log shows it was already delivered, then records it
+// It returns the delivery, and whether it was sent now rather than earlier
+func Deliver(ctx context.Context, registry Registry, log *DeliveryLog, msg Message) (Delivery, bool, error) {
+	if delivery, ok, err := log.Get(msg.Key); err != nil {
+		return Delivery{}, false, errs.DependencyFailure.Wrap(err, "unable to read delivery log")
+	} else if ok {
+		return delivery, false, nil
+	}
+
+	notifier, err := registry.Get(msg.Channel)
+	if err != nil {
+		return Delivery{}, false, err
+	}
+	if err := notifier.Send(ctx, msg); err != nil {
+		return Delivery{}, false, err
+	}
+
+	delivery := Delivery{
+		Key:         msg.Key,
+		Channel:     msg.Channel,
+		To:          msg.To,
+		Subject:     msg.Subject,
+		DeliveredAt: time.Now(),
+	}
+	// The message is out; failing now would only send it again on retry, so
+	// a delivery that could not be recorded is still reported as sent
+	_ = log.Record(delivery)
+	return delivery, true, nil
+}
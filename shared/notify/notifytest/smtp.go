@@ -0,0 +1,138 @@
+// Package notifytest provides a fake SMTP server for trying and testing the
+// email channel of package notify without a real mail server
+package notifytest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+)
+
+// ReceivedMail is a message accepted by an SMTPServer
+type ReceivedMail struct {
+	From string
+	To   []string
+	Data string // Headers and body as sent
+}
+
+// SMTPServer speaks just enough SMTP to accept mail from notify.SMTPNotifier
+// and net/smtp
+type SMTPServer struct {
+	// Reject lists recipients answered with 550, to try permanent failures
+	Reject map[string]bool
+	// OnMail is called for every accepted message
+	OnMail func(ReceivedMail)
+
+	listener net.Listener
+	mu       sync.Mutex
+	mail     []ReceivedMail
+}
+
+// ListenSMTP starts a fake SMTP server on addr (":0" picks a free port)
+func ListenSMTP(addr string) (*SMTPServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &SMTPServer{listener: listener}
+	go s.serve()
+	return s, nil
+}
+
+// Addr is the address the server listens on
+func (s *SMTPServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server
+func (s *SMTPServer) Close() error {
+	return s.listener.Close()
+}
+
+// Mail returns every message accepted so far
+func (s *SMTPServer) Mail() []ReceivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ReceivedMail(nil), s.mail...)
+}
+
+func (s *SMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle runs one SMTP session
+func (s *SMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 fake-smtp ready")
+	var current ReceivedMail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250 fake-smtp")
+		case "MAIL":
+			current = ReceivedMail{From: addressOf(line)}
+			reply("250 OK")
+		case "RCPT":
+			to := addressOf(line)
+			if s.Reject[to] {
+				reply("550 no such mailbox " + to)
+				continue
+			}
+			current.To = append(current.To, to)
+			reply("250 OK")
+		case "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if strings.TrimRight(dataLine, "\r\n") == "." {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			current.Data = data.String()
+			s.mu.Lock()
+			s.mail = append(s.mail, current)
+			s.mu.Unlock()
+			if s.OnMail != nil {
+				s.OnMail(current)
+			}
+			reply("250 OK queued")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+// addressOf extracts the address from "MAIL FROM:<a@b>" or "RCPT TO:<a@b>"
+func addressOf(line string) string {
+	start, end := strings.Index(line, "<"), strings.LastIndex(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"temporal-go-examples/shared/errs"
+)
+
+// SMTPNotifier sends email through an SMTP server
+type SMTPNotifier struct {
+	Addr string    // host:port
+	From string    // Envelope and header sender
+	Auth smtp.Auth // Optional
+}
+
+// Send delivers the message to msg.To; the context's deadline bounds the whole conversation
+func (s *SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return errs.NotificationRejected.Wrap(err, "invalid SMTP address")
+	}
+	// Header values come from order data; a CR or LF in one would end the
+	// header early and let the rest of the value inject headers of its own
+	for name, value := range map[string]string{"From": s.From, "To": msg.To, "Subject": msg.Subject, "Message-ID": msg.Key} {
+		if strings.ContainsAny(value, "\r\n") {
+			return errs.NotificationRejected.New(fmt.Sprintf("SMTP %s header must not contain CR or LF: %q", name, value))
+		}
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return errs.ServiceUnavailable.Wrap(err, "SMTP server unreachable")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return smtpError(err, "SMTP greeting failed")
+	}
+	defer client.Close()
+
+	if s.Auth != nil {
+		if err := client.Auth(s.Auth); err != nil {
+			return smtpError(err, "SMTP authentication failed")
+		}
+	}
+	if err := client.Mail(s.From); err != nil {
+		return smtpError(err, "SMTP server refused sender")
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return smtpError(err, "SMTP server refused recipient "+msg.To)
+	}
+	data, err := client.Data()
+	if err != nil {
+		return smtpError(err, "SMTP server refused message")
+	}
+	headers := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMessage-ID: <%s@temporal-go-examples>\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n",
+		s.From, msg.To, msg.Subject, strings.ReplaceAll(msg.Key, ":", "."), time.Now().Format(time.RFC1123Z))
+	body := strings.ReplaceAll(msg.Body, "\n", "\r\n")
+	if _, err := data.Write([]byte(headers + body)); err != nil {
+		return smtpError(err, "unable to write SMTP message")
+	}
+	if err := data.Close(); err != nil {
+		return smtpError(err, "SMTP server refused message")
+	}
+	return client.Quit()
+}
+
+// smtpError maps permanent (5xx) SMTP replies to NotificationRejected and
+// everything else, including 4xx "try again later", to ServiceUnavailable
+func smtpError(err error, message string) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return errs.NotificationRejected.Wrap(err, message)
+	}
+	return errs.ServiceUnavailable.Wrap(err, message)
+}
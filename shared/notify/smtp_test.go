@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"temporal-go-examples/shared/errs"
+	"temporal-go-examples/shared/notify/notifytest"
+)
+
+func confirmation(to string) Message {
+	return Message{
+		Key:     "order-1:pay_1:email:confirmation",
+		Channel: Email,
+		To:      to,
+		Subject: "Your order order-1 is confirmed",
+		Body:    "Thanks for your order!\nTotal charged: $24.99\n",
+	}
+}
+
+// fakeSMTP starts a fake SMTP server on a free port for the length of the test
+func fakeSMTP(t *testing.T) *notifytest.SMTPServer {
+	server, err := notifytest.ListenSMTP("127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func sendTimeout(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestSMTPNotifierDeliversToFakeServer(t *testing.T) {
+	server := fakeSMTP(t)
+	notifier := &SMTPNotifier{Addr: server.Addr(), From: "orders@example.com"}
+
+	require.NoError(t, notifier.Send(sendTimeout(t), confirmation("customer@example.com")))
+
+	mail := server.Mail()
+	require.Len(t, mail, 1)
+	assert.Equal(t, "orders@example.com", mail[0].From)
+	assert.Equal(t, []string{"customer@example.com"}, mail[0].To)
+	assert.Contains(t, mail[0].Data, "Subject: Your order order-1 is confirmed\r\n")
+	assert.Contains(t, mail[0].Data, "Message-ID: <order-1.pay_1.email.confirmation@temporal-go-examples>\r\n")
+	assert.Contains(t, mail[0].Data, "Thanks for your order!\r\nTotal charged: $24.99\r\n")
+}
+
+func TestSMTPNotifierRejectedRecipientIsPermanent(t *testing.T) {
+	server := fakeSMTP(t)
+	server.Reject = map[string]bool{"nobody@example.com": true}
+	notifier := &SMTPNotifier{Addr: server.Addr(), From: "orders@example.com"}
+
+	err := notifier.Send(sendTimeout(t), confirmation("nobody@example.com"))
+
+	require.Error(t, err)
+	assert.True(t, errs.NotificationRejected.Is(err), err.Error())
+	assert.False(t, errs.Classify(err).Retryable)
+	assert.Empty(t, server.Mail())
+}
+
+func TestSMTPNotifierRejectsHeaderInjection(t *testing.T) {
+	server := fakeSMTP(t)
+	notifier := &SMTPNotifier{Addr: server.Addr(), From: "orders@example.com"}
+
+	for name, edit := range map[string]func(*Message){
+		"to":      func(m *Message) { m.To = "customer@example.com\r\nBcc: everyone@example.com" },
+		"subject": func(m *Message) { m.Subject = "Your order\r\nBcc: everyone@example.com" },
+		"key":     func(m *Message) { m.Key = "order-1>\nBcc: everyone@example.com\n<x:pay_1:email:confirmation" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			msg := confirmation("customer@example.com")
+			edit(&msg)
+
+			err := notifier.Send(sendTimeout(t), msg)
+
+			require.Error(t, err)
+			assert.True(t, errs.NotificationRejected.Is(err), err.Error())
+			assert.False(t, errs.Classify(err).Retryable)
+		})
+	}
+	assert.Empty(t, server.Mail())
+}
+
+func TestSMTPNotifierUnreachableServerIsRetryable(t *testing.T) {
+	// Take a free port and close it again, so nothing is listening there
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+	notifier := &SMTPNotifier{Addr: addr, From: "orders@example.com"}
+
+	err = notifier.Send(sendTimeout(t), confirmation("customer@example.com"))
+
+	require.Error(t, err)
+	assert.True(t, errs.ServiceUnavailable.Is(err), err.Error())
+	assert.True(t, errs.Classify(err).Retryable)
+}
+
+func TestDeliverSendsEachKeyOnce(t *testing.T) {
+	server := fakeSMTP(t)
+	registry := Registry{Email: &SMTPNotifier{Addr: server.Addr(), From: "orders@example.com"}}
+	log := NewDeliveryLog(filepath.Join(t.TempDir(), "deliveries.json"))
+	msg := confirmation("customer@example.com")
+
+	_, sent, err := Deliver(sendTimeout(t), registry, log, msg)
+	require.NoError(t, err)
+	assert.True(t, sent)
+
+	// A retried activity finds the delivery and sends nothing
+	delivery, sent, err := Deliver(sendTimeout(t), registry, log, msg)
+	require.NoError(t, err)
+	assert.False(t, sent)
+	assert.Equal(t, msg.Key, delivery.Key)
+	assert.Len(t, server.Mail(), 1)
+
+	// Another run's payment makes another key, so it is sent again
+	msg.Key = "order-1:pay_2:email:confirmation"
+	_, sent, err = Deliver(sendTimeout(t), registry, log, msg)
+	require.NoError(t, err)
+	assert.True(t, sent)
+	assert.Len(t, server.Mail(), 2)
+}
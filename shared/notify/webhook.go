@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"temporal-go-examples/shared/errs"
+)
+
+// WebhookNotifier posts messages as JSON to a URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client // nil means http.DefaultClient
+}
+
+// Send posts the message; the Idempotency-Key header lets the receiver drop duplicates
+func (w *WebhookNotifier) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return errs.Internal.Wrap(err, "unable to encode notification")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return errs.NotificationRejected.Wrap(err, "invalid notification webhook URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", msg.Key)
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errs.ServiceUnavailable.Wrap(err, "notification webhook unreachable")
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return errs.ServiceUnavailable.New(fmt.Sprintf("notification webhook returned %d: %s", resp.StatusCode, bytes.TrimSpace(detail)))
+	default:
+		return errs.NotificationRejected.New(fmt.Sprintf("notification webhook returned %d: %s", resp.StatusCode, bytes.TrimSpace(detail)))
+	}
+}
@@ -0,0 +1,288 @@
+// Package policies is a catalog of named retry policies and activity timeouts
+//
+// Instead of copying a RetryPolicy literal into every workflow, activities are
+// mapped to named policies such as "payment-gateway" in a JSON catalog. Workflows
+// call policies.ExecuteActivity, which resolves the options for the activity type,
+// so timeouts and retry policies are never set at the call site: look up the
+// activity in BuiltinCatalog or the catalog file to see what it gets. Activities
+// that aren't mapped use the "default" policy.
+// Workers reload the catalog file when it changes, so operators can tune retries
+// without a redeploy.
+//
+// Resolving a policy records a marker in the workflow history. Executions that
+// started before a workflow switched to the catalog have no markers to replay,
+// so a "policy-catalog" version gate keeps them on the built-in policies without
+// recording anything.
+package policies
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"temporal-go-examples/shared/errs"
+)
+
+// Built-in policy names
+const (
+	Default                = "default"
+	FastTransient          = "fast-transient"
+	PaymentGateway         = "payment-gateway"
+	NotificationBestEffort = "notification-best-effort"
+	LongRunning            = "long-running"
+	LocalCheck             = "local-check"
+)
+
+// Duration is a time.Duration written as a string such as "30s" in JSON
+type Duration time.Duration
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON reads a duration string such as "1m30s"
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Policy is a named set of activity timeouts and retry settings
+// Zero fields mean "not set": in an override they keep the base policy's value
+type Policy struct {
+	StartToCloseTimeout    Duration `json:"start_to_close_timeout,omitempty"`
+	ScheduleToCloseTimeout Duration `json:"schedule_to_close_timeout,omitempty"`
+	HeartbeatTimeout       Duration `json:"heartbeat_timeout,omitempty"`
+	InitialInterval        Duration `json:"initial_interval,omitempty"`
+	BackoffCoefficient     float64  `json:"backoff_coefficient,omitempty"`
+	MaximumInterval        Duration `json:"maximum_interval,omitempty"`
+	MaximumAttempts        int32    `json:"maximum_attempts,omitempty"`
+	NonRetryableErrorTypes []string `json:"non_retryable_error_types,omitempty"`
+	// Local runs the activity as a local activity: in the workflow worker's process,
+	// without a round trip through the server. Only for short, cheap checks
+	Local bool `json:"local,omitempty"`
+}
+
+// merge returns p with every non-zero field of override applied
+func (p Policy) merge(override Policy) Policy {
+	if override.StartToCloseTimeout != 0 {
+		p.StartToCloseTimeout = override.StartToCloseTimeout
+	}
+	if override.ScheduleToCloseTimeout != 0 {
+		p.ScheduleToCloseTimeout = override.ScheduleToCloseTimeout
+	}
+	if override.HeartbeatTimeout != 0 {
+		p.HeartbeatTimeout = override.HeartbeatTimeout
+	}
+	if override.InitialInterval != 0 {
+		p.InitialInterval = override.InitialInterval
+	}
+	if override.BackoffCoefficient != 0 {
+		p.BackoffCoefficient = override.BackoffCoefficient
+	}
+	if override.MaximumInterval != 0 {
+		p.MaximumInterval = override.MaximumInterval
+	}
+	if override.MaximumAttempts != 0 {
+		p.MaximumAttempts = override.MaximumAttempts
+	}
+	if len(override.NonRetryableErrorTypes) > 0 {
+		p.NonRetryableErrorTypes = override.NonRetryableErrorTypes
+	}
+	// An override can only switch local execution on; map the activity to a
+	// remote policy to switch it off
+	if override.Local {
+		p.Local = true
+	}
+	return p
+}
+
+// ActivityOptions converts the policy to workflow activity options
+// Every non-retryable kind from shared/errs is always added to NonRetryableErrorTypes
+func (p Policy) ActivityOptions() workflow.ActivityOptions {
+	nonRetryable := append([]string{}, p.NonRetryableErrorTypes...)
+	nonRetryable = append(nonRetryable, errs.NonRetryableTypes()...)
+	return workflow.ActivityOptions{
+		StartToCloseTimeout:    time.Duration(p.StartToCloseTimeout),
+		ScheduleToCloseTimeout: time.Duration(p.ScheduleToCloseTimeout),
+		HeartbeatTimeout:       time.Duration(p.HeartbeatTimeout),
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:        time.Duration(p.InitialInterval),
+			BackoffCoefficient:     p.BackoffCoefficient,
+			MaximumInterval:        time.Duration(p.MaximumInterval),
+			MaximumAttempts:        p.MaximumAttempts,
+			NonRetryableErrorTypes: nonRetryable,
+		},
+	}
+}
+
+// LocalActivityOptions converts the policy to local activity options
+// Local activities have no heartbeat or schedule-to-start timeouts
+func (p Policy) LocalActivityOptions() workflow.LocalActivityOptions {
+	options := p.ActivityOptions()
+	return workflow.LocalActivityOptions{
+		StartToCloseTimeout:    options.StartToCloseTimeout,
+		ScheduleToCloseTimeout: options.ScheduleToCloseTimeout,
+		RetryPolicy:            options.RetryPolicy,
+	}
+}
+
+// ActivityPolicy maps one activity type to a named policy plus field overrides
+type ActivityPolicy struct {
+	Policy   string `json:"policy"`
+	Override Policy `json:"override"`
+}
+
+// Catalog holds the named policies and which activity uses which
+type Catalog struct {
+	Policies   map[string]Policy         `json:"policies"`
+	Activities map[string]ActivityPolicy `json:"activities"` // Keyed by activity type name
+}
+
+// Resolve returns the policy for an activity type
+// Unmapped activities, and mappings to unknown policies, fall back to the default policy
+func (c Catalog) Resolve(activityType string) Policy {
+	mapping, ok := c.Activities[activityType]
+	if !ok {
+		return c.Policies[Default]
+	}
+	base, ok := c.Policies[mapping.Policy]
+	if !ok {
+		base = c.Policies[Default]
+	}
+	return base.merge(mapping.Override)
+}
+
+// BuiltinCatalog returns the catalog used when no file is configured
+func BuiltinCatalog() Catalog {
+	return Catalog{
+		Policies: map[string]Policy{
+			// The values every example used to copy into its workflow
+			Default: {
+				StartToCloseTimeout: Duration(time.Minute * 2),
+				InitialInterval:     Duration(time.Second),
+				BackoffCoefficient:  2.0,
+				MaximumInterval:     Duration(time.Second * 30),
+				MaximumAttempts:     3,
+			},
+			// Cheap calls that usually succeed on a quick retry
+			FastTransient: {
+				StartToCloseTimeout: Duration(time.Second * 30),
+				InitialInterval:     Duration(time.Millisecond * 200),
+				BackoffCoefficient:  2.0,
+				MaximumInterval:     Duration(time.Second * 5),
+				MaximumAttempts:     10,
+			},
+			// Third-party gateways: back off harder, never hammer them
+			PaymentGateway: {
+				StartToCloseTimeout: Duration(time.Minute * 2),
+				InitialInterval:     Duration(time.Second * 2),
+				BackoffCoefficient:  3.0,
+				MaximumInterval:     Duration(time.Minute),
+				MaximumAttempts:     5,
+			},
+			// Nice to have: a few slow attempts, then give up
+			NotificationBestEffort: {
+				StartToCloseTimeout: Duration(time.Minute),
+				InitialInterval:     Duration(time.Second * 5),
+				BackoffCoefficient:  2.0,
+				MaximumInterval:     Duration(time.Minute),
+				MaximumAttempts:     3,
+			},
+			// Activities that run for minutes must heartbeat: a dead worker is
+			// noticed after HeartbeatTimeout, not after the long StartToCloseTimeout
+			LongRunning: {
+				StartToCloseTimeout: Duration(time.Minute * 30),
+				HeartbeatTimeout:    Duration(time.Second * 10),
+				InitialInterval:     Duration(time.Second),
+				BackoffCoefficient:  2.0,
+				MaximumInterval:     Duration(time.Second * 30),
+				MaximumAttempts:     10,
+			},
+			// Pure validation: run in-process as a local activity with quick retries
+			LocalCheck: {
+				StartToCloseTimeout: Duration(time.Second * 5),
+				InitialInterval:     Duration(time.Millisecond * 100),
+				BackoffCoefficient:  2.0,
+				MaximumInterval:     Duration(time.Second),
+				MaximumAttempts:     5,
+				Local:               true,
+			},
+		},
+		Activities: map[string]ActivityPolicy{
+			"ValidateOrder":           {Policy: LocalCheck},
+			"ProcessPayment":          {Policy: PaymentGateway, Override: Policy{ScheduleToCloseTimeout: Duration(time.Minute * 5)}}, // 2m per attempt to get the webhook, 5m overall
+			"VoidPayment":             {Policy: PaymentGateway, Override: Policy{MaximumAttempts: 10}},
+			"RefundPayment":           {Policy: PaymentGateway, Override: Policy{MaximumAttempts: 10}},
+			"SendConfirmationEmail":   {Policy: NotificationBestEffort},
+			"SendConfirmationWebhook": {Policy: FastTransient, Override: Policy{MaximumAttempts: 5}}, // Webhook receivers recover quickly
+			"WriteConfirmationFile":   {Policy: Default},
+			"ScoreFraud":              {Policy: FastTransient},
+			"PriceOrder":              {Policy: FastTransient},
+			"ReserveInventory":        {Policy: Default},
+			"CommitReservation":       {Policy: Default, Override: Policy{MaximumAttempts: 10}},
+			"ReleaseReservation":      {Policy: Default, Override: Policy{MaximumAttempts: 10}},
+			"VerifyAddress":           {Policy: FastTransient},
+			"ValidateAccounts":        {Policy: LocalCheck},
+			"GetFXQuote":              {Policy: FastTransient},
+			"RiskyTransferActivity":   {Policy: Default, Override: Policy{MaximumAttempts: 5}},
+			"ProcessOrderBatch":       {Policy: LongRunning},
+			"ListClosedTransfers":     {Policy: Default, Override: Policy{StartToCloseTimeout: Duration(time.Minute * 5)}},
+			"ListClosedTransfersPage": {Policy: Default}, // One page of closedTransfersPageSize runs fits the default timeout
+		},
+	}
+}
+
+var (
+	mu      sync.RWMutex
+	current = BuiltinCatalog()
+)
+
+// Current returns the catalog in use by this process
+func Current() Catalog {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Set replaces the catalog in use by this process
+func Set(c Catalog) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = c
+}
+
+// LoadFile reads a catalog from a JSON file
+// Policies and activity mappings in the file are added to (or replace) the built-in ones
+func LoadFile(path string) (Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Catalog{}, fmt.Errorf("read policy catalog: %w", err)
+	}
+	var file Catalog
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Catalog{}, fmt.Errorf("parse policy catalog %s: %w", path, err)
+	}
+
+	catalog := BuiltinCatalog()
+	for name, policy := range file.Policies {
+		catalog.Policies[name] = policy
+	}
+	for activityType, mapping := range file.Activities {
+		catalog.Activities[activityType] = mapping
+	}
+	return catalog, nil
+}
@@ -0,0 +1,55 @@
+package policies
+
+import (
+	"os"
+	"time"
+
+	"temporal-go-examples/shared"
+)
+
+// EnvFile names the environment variable that points workers at a catalog file
+const EnvFile = "ACTIVITY_POLICIES_FILE"
+
+// LoadFromEnv loads the catalog named by ACTIVITY_POLICIES_FILE, if set, and keeps
+// reloading it when the file changes. Workers call it once at startup
+func LoadFromEnv() error {
+	path := os.Getenv(EnvFile)
+	if path == "" {
+		shared.LogInfo("Using built-in activity policy catalog")
+		return nil
+	}
+	catalog, err := LoadFile(path)
+	if err != nil {
+		return err
+	}
+	Set(catalog)
+	shared.LogInfo("Loaded activity policy catalog from %s", path)
+
+	go Watch(path, time.Second*10)
+	return nil
+}
+
+// Watch polls a catalog file and installs it whenever its modification time changes
+// A file that fails to parse is logged and ignored, keeping the previous catalog
+func Watch(path string, interval time.Duration) {
+	var lastModified time.Time
+	if info, err := os.Stat(path); err == nil {
+		lastModified = info.ModTime()
+	}
+
+	for range time.Tick(interval) {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().After(lastModified) {
+			continue
+		}
+		lastModified = info.ModTime()
+
+		catalog, err := LoadFile(path)
+		if err != nil {
+			shared.LogError("Ignoring invalid activity policy catalog: %v", err)
+			continue
+		}
+		Set(catalog)
+		shared.LogInfo("Reloaded activity policy catalog from %s", path)
+	}
+}
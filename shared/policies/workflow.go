@@ -0,0 +1,87 @@
+package policies
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// catalogChangeID is the version gate for resolving policies through the catalog
+const catalogChangeID = "policy-catalog"
+
+// ActivityOptions resolves the catalog options for an activity inside a workflow
+func ActivityOptions(ctx workflow.Context, activity interface{}) workflow.ActivityOptions {
+	return resolve(ctx, activity).ActivityOptions()
+}
+
+// resolve returns the catalog policy for an activity inside a workflow
+//
+// The catalog lives in worker memory and can change at any time, so it must not
+// be read directly from workflow code. The resolved policy is recorded with
+// MutableSideEffect instead: replays see the recorded value, and a reloaded
+// catalog only affects activities scheduled after the change. That includes
+// the Local flag, so moving an activity between local and remote execution
+// never breaks the replay of a workflow that already ran it the other way.
+func resolve(ctx workflow.Context, activity interface{}) Policy {
+	activityType := ActivityType(activity)
+
+	// Executions that scheduled activities before they used the catalog have no
+	// policy markers in their history, so they must not record any now. They
+	// get the built-in policy, always as a regular activity, as they did before
+	if workflow.GetVersion(ctx, catalogChangeID, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		policy := BuiltinCatalog().Resolve(activityType)
+		policy.Local = false
+		return policy
+	}
+
+	var policy Policy
+	encoded := workflow.MutableSideEffect(ctx, "activity-policy:"+activityType,
+		func(ctx workflow.Context) interface{} {
+			return Current().Resolve(activityType)
+		},
+		func(a, b interface{}) bool {
+			return reflect.DeepEqual(a, b)
+		},
+	)
+	if err := encoded.Get(&policy); err != nil {
+		workflow.GetLogger(ctx).Error("Unable to decode activity policy, using default", "activityType", activityType, "error", err)
+		policy = BuiltinCatalog().Policies[Default]
+	}
+	return policy
+}
+
+// ExecuteActivity runs an activity with the catalog's options for it applied
+// Activities whose policy is Local run with workflow.ExecuteLocalActivity,
+// everything else with workflow.ExecuteActivity; the activity must be
+// registered with the worker either way
+func ExecuteActivity(ctx workflow.Context, activity interface{}, args ...interface{}) workflow.Future {
+	future, _ := ExecuteActivityWithPolicy(ctx, activity, args...)
+	return future
+}
+
+// ExecuteActivityWithPolicy is ExecuteActivity that also returns the policy it
+// applied, for callers that need its limits, e.g. how many attempts it allowed.
+// Resolving the policy again afterwards would record a second marker
+func ExecuteActivityWithPolicy(ctx workflow.Context, activity interface{}, args ...interface{}) (workflow.Future, Policy) {
+	policy := resolve(ctx, activity)
+	if policy.Local {
+		ctx = workflow.WithLocalActivityOptions(ctx, policy.LocalActivityOptions())
+		return workflow.ExecuteLocalActivity(ctx, activity, args...), policy
+	}
+	ctx = workflow.WithActivityOptions(ctx, policy.ActivityOptions())
+	return workflow.ExecuteActivity(ctx, activity, args...), policy
+}
+
+// ActivityType returns the name Temporal registers an activity under:
+// the function or method name without its package or receiver
+func ActivityType(activity interface{}) string {
+	if name, ok := activity.(string); ok {
+		return name
+	}
+	fullName := runtime.FuncForPC(reflect.ValueOf(activity).Pointer()).Name()
+	name := fullName[strings.LastIndex(fullName, ".")+1:]
+	// Method values such as checker.AssessTransferRisk get a "-fm" suffix
+	return strings.TrimSuffix(name, "-fm")
+}
@@ -0,0 +1,222 @@
+// Package ratelimit enforces token-bucket limits on activities that call
+// rate-limited third-party APIs
+//
+// Each activity type has a bucket, and optionally one bucket per key (for
+// example per merchant) on top. Buckets live in a Store; a FileStore lets every
+// worker on a host draw from the same buckets. An activity that finds no token
+// waits for one if the wait is short, otherwise it fails with a retryable
+// RateLimited error whose NextRetryDelay is when the next token will be free.
+//
+// For a hard cap enforced by the Temporal server itself, also set
+// TASK_QUEUE_ACTIVITIES_PER_SECOND on the workers (see shared.CreateTemporalWorker).
+// That caps every activity on the task queue, not one API, so the two work together.
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+
+	"temporal-go-examples/shared/errs"
+)
+
+// DefaultMaxWait is how long an activity waits for a token before giving up
+const DefaultMaxWait = time.Second * 10
+
+// Limit is a token bucket: Rate tokens are added per second up to Burst
+type Limit struct {
+	Rate  float64 `json:"rate_per_second"`
+	Burst int     `json:"burst"`
+}
+
+// Rule limits one activity type overall and, optionally, per key
+// A zero Limit means "no limit" at that level
+type Rule struct {
+	Activity Limit `json:"activity"`
+	PerKey   Limit `json:"per_key"`
+}
+
+// Rules maps activity type names to their rules
+type Rules map[string]Rule
+
+// DefaultRules returns limits matching the simulated third-party APIs
+func DefaultRules() Rules {
+	return Rules{
+		// The payment gateway allows 5 calls/s per account and 2 calls/s per merchant
+		"ProcessPayment": {
+			Activity: Limit{Rate: 5, Burst: 5},
+			PerKey:   Limit{Rate: 2, Burst: 2},
+		},
+		// The email provider allows 10 messages/s
+		"SendConfirmationEmail": {
+			Activity: Limit{Rate: 10, Burst: 10},
+		},
+	}
+}
+
+// LoadRules reads rules from a JSON file shaped like rate-limits.json
+func LoadRules(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse rate limits %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Denied is attached to RateLimited errors
+type Denied struct {
+	Bucket     string        `json:"bucket"`
+	RetryAfter time.Duration `json:"retry_after"`
+}
+
+// Limiter hands out tokens for activity calls
+type Limiter struct {
+	Store   Store
+	Rules   Rules
+	MaxWait time.Duration // Longest an activity waits in-process for a token
+}
+
+// New creates a limiter
+func New(store Store, rules Rules) *Limiter {
+	return &Limiter{Store: store, Rules: rules, MaxWait: DefaultMaxWait}
+}
+
+// FromEnv builds a limiter from RATE_LIMITS_FILE (rules, default DefaultRules)
+// and RATE_LIMIT_STORE_FILE (shared buckets, default in-memory per worker)
+func FromEnv() (*Limiter, error) {
+	rules := DefaultRules()
+	if path := os.Getenv("RATE_LIMITS_FILE"); path != "" {
+		var err error
+		if rules, err = LoadRules(path); err != nil {
+			return nil, err
+		}
+	}
+	var store Store = NewMemoryStore()
+	if path := os.Getenv("RATE_LIMIT_STORE_FILE"); path != "" {
+		store = NewFileStore(path)
+	}
+	return New(store, rules), nil
+}
+
+// Wait takes a token for the calling activity and key, sleeping until it is due
+// key may be empty when the activity has no per-key limit
+func (l *Limiter) Wait(ctx context.Context, key string) error {
+	activityType := activity.GetInfo(ctx).ActivityType.Name
+	delay, err := l.Reserve(activityType, key)
+	l.report(ctx, activityType, delay, err)
+	if err != nil || delay <= 0 {
+		return err
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// The caller gave up before using its token; hand it back so callers
+		// queued behind it don't wait for a call that never happens
+		l.release(activityType, key)
+		return ctx.Err()
+	}
+}
+
+// release hands back the tokens Reserve took for an activity type and key
+func (l *Limiter) release(activityType, key string) {
+	rule, ok := l.Rules[activityType]
+	if !ok {
+		return
+	}
+	if rule.Activity.Rate > 0 {
+		l.refund(activityType, rule.Activity)
+	}
+	if key != "" && rule.PerKey.Rate > 0 {
+		l.refund(activityType+"/"+key, rule.PerKey)
+	}
+}
+
+// Reserve takes a token from the activity's buckets and returns how long the
+// caller must wait before using it. If the wait would exceed MaxWait nothing is
+// taken and a RateLimited error is returned instead
+func (l *Limiter) Reserve(activityType, key string) (time.Duration, error) {
+	rule, ok := l.Rules[activityType]
+	if !ok {
+		return 0, nil
+	}
+
+	delay, err := l.reserve(activityType, rule.Activity)
+	if err != nil || key == "" || rule.PerKey.Rate <= 0 {
+		return delay, err
+	}
+	keyDelay, err := l.reserve(activityType+"/"+key, rule.PerKey)
+	if err != nil {
+		// Hand back the activity-wide token we took
+		l.refund(activityType, rule.Activity)
+		return 0, err
+	}
+	return time.Duration(math.Max(float64(delay), float64(keyDelay))), nil
+}
+
+// reserve takes one token from a bucket, letting it go negative by at most MaxWait worth of tokens
+func (l *Limiter) reserve(name string, limit Limit) (time.Duration, error) {
+	if limit.Rate <= 0 {
+		return 0, nil
+	}
+	var delay time.Duration
+	denied := false
+	_, err := l.Store.Update(name, func(b *Bucket) {
+		b.refill(limit, time.Now())
+		wait := b.waitFor(limit)
+		if wait > l.MaxWait {
+			denied = true
+			delay = wait
+			b.Limited++
+			return
+		}
+		b.Tokens--
+		b.Granted++
+		delay = wait
+	})
+	if err != nil {
+		return 0, errs.DependencyFailure.Wrap(err, "rate limiter store unavailable")
+	}
+	if denied {
+		return 0, errs.RateLimited.WithOptions(
+			fmt.Sprintf("rate limit for %s reached, retry in %s", name, delay.Round(time.Millisecond)),
+			temporal.ApplicationErrorOptions{
+				NextRetryDelay: delay,
+				Details:        []interface{}{Denied{Bucket: name, RetryAfter: delay}},
+			},
+		)
+	}
+	return delay, nil
+}
+
+// refund returns a token taken by a reservation that was then denied or abandoned
+func (l *Limiter) refund(name string, limit Limit) {
+	l.Store.Update(name, func(b *Bucket) {
+		b.Tokens = math.Min(b.Tokens+1, float64(limit.Burst))
+		b.Granted--
+	})
+}
+
+// report publishes limiter metrics through the activity's metrics handler
+func (l *Limiter) report(ctx context.Context, activityType string, delay time.Duration, err error) {
+	handler := activity.GetMetricsHandler(ctx).WithTags(map[string]string{"activity_type": activityType})
+	if err != nil {
+		handler.Counter("rate_limiter_limited").Inc(1)
+		return
+	}
+	handler.Counter("rate_limiter_granted").Inc(1)
+	handler.Timer("rate_limiter_wait").Record(delay)
+}
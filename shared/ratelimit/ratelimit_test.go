@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+)
+
+const limitedActivity = "CallGateway"
+
+// runCalls fires one limited activity call per key, all at once, through the
+// test activity environment and returns when each call finished
+func runCalls(limiter *Limiter, keys []string, callTimeout time.Duration) ([]time.Duration, []error) {
+	call := func(ctx context.Context, key string) error {
+		if callTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, callTimeout)
+			defer cancel()
+		}
+		return limiter.Wait(ctx, key)
+	}
+
+	suite := &testsuite.WorkflowTestSuite{}
+	start := time.Now()
+	finished := make([]time.Duration, len(keys))
+	failures := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			env := suite.NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(call, activity.RegisterOptions{Name: limitedActivity})
+			_, failures[i] = env.ExecuteActivity(limitedActivity, key)
+			finished[i] = time.Since(start)
+		}()
+	}
+	wg.Wait()
+	return finished, failures
+}
+
+// assertRate checks that calls beyond the burst arrived no faster than rate
+func assertRate(t *testing.T, finished []time.Duration, limit Limit) {
+	t.Helper()
+	var last time.Duration
+	for _, f := range finished {
+		last = max(last, f)
+	}
+	beyondBurst := len(finished) - limit.Burst
+	minimum := time.Duration(float64(beyondBurst) / limit.Rate * float64(time.Second))
+	// Allow a little timer slack, but never a whole extra token's worth
+	assert.GreaterOrEqual(t, last, minimum-time.Duration(float64(time.Second)/limit.Rate/2),
+		"%d calls finished in %s, faster than %.0f/s with a burst of %d allows", len(finished), last, limit.Rate, limit.Burst)
+}
+
+func TestWaitHoldsActivityRate(t *testing.T) {
+	limit := Limit{Rate: 20, Burst: 5}
+	limiter := New(NewMemoryStore(), Rules{limitedActivity: {Activity: limit}})
+
+	finished, errs := runCalls(limiter, make([]string, 25), 0)
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	assertRate(t, finished, limit)
+}
+
+func TestWaitHoldsPerKeyRate(t *testing.T) {
+	perKey := Limit{Rate: 5, Burst: 1}
+	limiter := New(NewMemoryStore(), Rules{limitedActivity: {
+		Activity: Limit{Rate: 100, Burst: 100},
+		PerKey:   perKey,
+	}})
+	var keys []string
+	for i := 0; i < 6; i++ {
+		keys = append(keys, "merchant-a", "merchant-b")
+	}
+
+	finished, errs := runCalls(limiter, keys, 0)
+
+	perMerchant := map[string][]time.Duration{}
+	for i, err := range errs {
+		require.NoError(t, err)
+		perMerchant[keys[i]] = append(perMerchant[keys[i]], finished[i])
+	}
+	for merchant, times := range perMerchant {
+		t.Run(merchant, func(t *testing.T) { assertRate(t, times, perKey) })
+	}
+}
+
+func TestWaitDeniesBeyondMaxWait(t *testing.T) {
+	limiter := New(NewMemoryStore(), Rules{limitedActivity: {Activity: Limit{Rate: 1, Burst: 1}}})
+	limiter.MaxWait = 100 * time.Millisecond
+
+	_, errs := runCalls(limiter, []string{"", ""}, 0)
+
+	denied := 0
+	for _, err := range errs {
+		if err != nil {
+			denied++
+			assert.Contains(t, err.Error(), "rate limit for "+limitedActivity)
+		}
+	}
+	assert.Equal(t, 1, denied)
+}
+
+func TestWaitRefundsAbandonedToken(t *testing.T) {
+	store := NewMemoryStore()
+	limiter := New(store, Rules{limitedActivity: {
+		Activity: Limit{Rate: 1, Burst: 1},
+		PerKey:   Limit{Rate: 1, Burst: 1},
+	}})
+
+	// The first call takes the only token; the second queues for the next one
+	// and gives up long before it is due
+	_, errs := runCalls(limiter, []string{"merchant-a"}, 0)
+	require.NoError(t, errs[0])
+	_, errs = runCalls(limiter, []string{"merchant-a"}, 20*time.Millisecond)
+	require.Error(t, errs[0])
+
+	buckets, err := store.List()
+	require.NoError(t, err)
+	for _, name := range []string{limitedActivity, limitedActivity + "/merchant-a"} {
+		bucket := buckets[name]
+		assert.Equal(t, int64(1), bucket.Granted, name)
+		assert.Greater(t, bucket.Tokens, -0.5, fmt.Sprintf("%s still holds the abandoned token", name))
+	}
+}
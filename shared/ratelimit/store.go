@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"temporal-go-examples/shared/filestore"
+)
+
+// Bucket is the persisted state of one token bucket
+type Bucket struct {
+	Tokens    float64   `json:"tokens"` // Negative while callers are queued for future tokens
+	UpdatedAt time.Time `json:"updated_at"`
+	Granted   int64     `json:"granted"`
+	Limited   int64     `json:"limited"` // Calls turned away with RateLimited
+}
+
+// refill adds the tokens earned since the last update
+// A bucket seen for the first time starts full
+func (b *Bucket) refill(limit Limit, now time.Time) {
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = float64(limit.Burst)
+	} else if elapsed := now.Sub(b.UpdatedAt); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+elapsed.Seconds()*limit.Rate)
+	}
+	b.UpdatedAt = now
+}
+
+// waitFor returns how long until the next token would be available
+func (b *Bucket) waitFor(limit Limit) time.Duration {
+	if b.Tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.Tokens) / limit.Rate * float64(time.Second))
+}
+
+// Store persists buckets
+// Update must apply fn atomically: no other update of the same store may interleave
+type Store interface {
+	Update(name string, fn func(*Bucket)) (Bucket, error)
+	List() (map[string]Bucket, error)
+}
+
+// MemoryStore keeps buckets in process memory; each worker enforces its own limits
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]Bucket
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]Bucket)}
+}
+
+// Update applies fn to a bucket
+func (m *MemoryStore) Update(name string, fn func(*Bucket)) (Bucket, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bucket := m.buckets[name]
+	fn(&bucket)
+	m.buckets[name] = bucket
+	return bucket, nil
+}
+
+// List returns a copy of every bucket
+func (m *MemoryStore) List() (map[string]Bucket, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	buckets := make(map[string]Bucket, len(m.buckets))
+	for name, b := range m.buckets {
+		buckets[name] = b
+	}
+	return buckets, nil
+}
+
+// FileStore keeps buckets in a JSON file so every worker sharing the file
+// draws from the same buckets
+type FileStore struct {
+	file filestore.Map[Bucket]
+}
+
+// NewFileStore creates a store backed by the file at path
+func NewFileStore(path string) *FileStore {
+	return &FileStore{file: filestore.Map[Bucket]{Path: path}}
+}
+
+// Update applies fn to a bucket under the file lock
+func (f *FileStore) Update(name string, fn func(*Bucket)) (Bucket, error) {
+	return f.file.Update(name, fn)
+}
+
+// List returns every bucket
+func (f *FileStore) List() (map[string]Bucket, error) {
+	return f.file.Read()
+}
@@ -0,0 +1,214 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+)
+
+// ScheduleConfig describes when and how a scheduled workflow runs
+// Use CronExpressions for calendar rules ("0 9 1 * *" = 09:00 on the 1st of every month)
+// or Interval for fixed periods; both may be combined
+type ScheduleConfig struct {
+	ID              string
+	CronExpressions []string
+	Interval        time.Duration
+	TimeZone        string                        // IANA name, e.g. "Europe/London"; empty means UTC
+	Overlap         enumspb.ScheduleOverlapPolicy // What to do if the previous run is still going
+	CatchupWindow   time.Duration                 // How far back missed runs are caught up after downtime
+	Jitter          time.Duration                 // Random delay added to each run to spread load
+	Paused          bool
+	Note            string
+}
+
+// overlapPolicies maps CLI-friendly names to overlap policies
+var overlapPolicies = map[string]enumspb.ScheduleOverlapPolicy{
+	"skip":            enumspb.SCHEDULE_OVERLAP_POLICY_SKIP,
+	"buffer-one":      enumspb.SCHEDULE_OVERLAP_POLICY_BUFFER_ONE,
+	"buffer-all":      enumspb.SCHEDULE_OVERLAP_POLICY_BUFFER_ALL,
+	"cancel-other":    enumspb.SCHEDULE_OVERLAP_POLICY_CANCEL_OTHER,
+	"terminate-other": enumspb.SCHEDULE_OVERLAP_POLICY_TERMINATE_OTHER,
+	"allow-all":       enumspb.SCHEDULE_OVERLAP_POLICY_ALLOW_ALL,
+}
+
+// ParseOverlapPolicy converts a name such as "skip" or "buffer-one" to an overlap policy
+// An empty name returns the server default (skip)
+func ParseOverlapPolicy(name string) (enumspb.ScheduleOverlapPolicy, error) {
+	if name == "" {
+		return enumspb.SCHEDULE_OVERLAP_POLICY_UNSPECIFIED, nil
+	}
+	policy, ok := overlapPolicies[strings.ToLower(name)]
+	if !ok {
+		return enumspb.SCHEDULE_OVERLAP_POLICY_UNSPECIFIED, fmt.Errorf("unknown overlap policy %q", name)
+	}
+	return policy, nil
+}
+
+// spec builds the schedule spec from the config
+func (cfg ScheduleConfig) spec() client.ScheduleSpec {
+	spec := client.ScheduleSpec{
+		CronExpressions: cfg.CronExpressions,
+		Jitter:          cfg.Jitter,
+		TimeZoneName:    cfg.TimeZone,
+	}
+	if cfg.Interval > 0 {
+		spec.Intervals = []client.ScheduleIntervalSpec{{Every: cfg.Interval}}
+	}
+	return spec
+}
+
+// CreateSchedule creates a schedule that starts workflowFunc with args on the shared task queue
+func CreateSchedule(ctx context.Context, c client.Client, cfg ScheduleConfig, workflowFunc interface{}, args ...interface{}) (client.ScheduleHandle, error) {
+	LogInfo("Creating schedule %s", cfg.ID)
+	return c.ScheduleClient().Create(ctx, client.ScheduleOptions{
+		ID:            cfg.ID,
+		Spec:          cfg.spec(),
+		Overlap:       cfg.Overlap,
+		CatchupWindow: cfg.CatchupWindow,
+		Paused:        cfg.Paused,
+		Note:          cfg.Note,
+		Action: &client.ScheduleWorkflowAction{
+			// The server appends the scheduled time to keep each run's ID unique
+			ID:        cfg.ID + "-run",
+			Workflow:  workflowFunc,
+			Args:      args,
+			TaskQueue: TaskQueue,
+		},
+	})
+}
+
+// ScheduleChanges lists what UpdateSchedule replaces
+// Nil fields keep the schedule's current value, so an update that only
+// changes the jitter leaves the cron, catch-up window and overlap alone
+type ScheduleChanges struct {
+	CronExpressions []string // An empty, non-nil slice removes every cron expression
+	Interval        *time.Duration
+	TimeZone        *string
+	Overlap         *enumspb.ScheduleOverlapPolicy
+	CatchupWindow   *time.Duration
+	Jitter          *time.Duration
+	Note            *string
+	// Args gets the scheduled workflow's current arguments and returns the new
+	// ones; decode the current ones with DecodeScheduleArg
+	Args func(current []interface{}) ([]interface{}, error)
+}
+
+// apply writes the changes into a schedule description
+func (ch ScheduleChanges) apply(schedule *client.Schedule) error {
+	if schedule.Spec == nil {
+		schedule.Spec = &client.ScheduleSpec{}
+	}
+	if ch.CronExpressions != nil {
+		schedule.Spec.CronExpressions = ch.CronExpressions
+	}
+	if ch.Interval != nil {
+		schedule.Spec.Intervals = nil
+		if *ch.Interval > 0 {
+			schedule.Spec.Intervals = []client.ScheduleIntervalSpec{{Every: *ch.Interval}}
+		}
+	}
+	if ch.TimeZone != nil {
+		schedule.Spec.TimeZoneName = *ch.TimeZone
+	}
+	if ch.Jitter != nil {
+		schedule.Spec.Jitter = *ch.Jitter
+	}
+
+	if schedule.Policy == nil {
+		schedule.Policy = &client.SchedulePolicies{}
+	}
+	if ch.Overlap != nil {
+		schedule.Policy.Overlap = *ch.Overlap
+	}
+	if ch.CatchupWindow != nil {
+		schedule.Policy.CatchupWindow = *ch.CatchupWindow
+	}
+
+	if ch.Note != nil {
+		if schedule.State == nil {
+			schedule.State = &client.ScheduleState{}
+		}
+		schedule.State.Note = *ch.Note
+	}
+
+	if ch.Args != nil {
+		action, ok := schedule.Action.(*client.ScheduleWorkflowAction)
+		if !ok {
+			return fmt.Errorf("schedule does not start a workflow")
+		}
+		args, err := ch.Args(action.Args)
+		if err != nil {
+			return err
+		}
+		action.Args = args
+	}
+	return nil
+}
+
+// DecodeScheduleArg decodes one of the arguments a schedule passes to its workflow
+// Described schedules hold their arguments as encoded payloads
+func DecodeScheduleArg(arg interface{}, valuePtr interface{}) error {
+	payload, ok := arg.(*commonpb.Payload)
+	if !ok {
+		return fmt.Errorf("schedule argument is %T, not an encoded payload", arg)
+	}
+	return converter.GetDefaultDataConverter().FromPayload(payload, valuePtr)
+}
+
+// UpdateSchedule changes an existing schedule; only the fields set in changes are replaced
+func UpdateSchedule(ctx context.Context, c client.Client, id string, changes ScheduleChanges) error {
+	LogInfo("Updating schedule %s", id)
+	handle := c.ScheduleClient().GetHandle(ctx, id)
+	return handle.Update(ctx, client.ScheduleUpdateOptions{
+		DoUpdate: func(input client.ScheduleUpdateInput) (*client.ScheduleUpdate, error) {
+			schedule := input.Description.Schedule
+			if err := changes.apply(&schedule); err != nil {
+				return nil, fmt.Errorf("schedule %s: %w", id, err)
+			}
+			return &client.ScheduleUpdate{Schedule: &schedule}, nil
+		},
+	})
+}
+
+// PauseSchedule stops a schedule from starting new runs
+func PauseSchedule(ctx context.Context, c client.Client, id, note string) error {
+	LogInfo("Pausing schedule %s", id)
+	return c.ScheduleClient().GetHandle(ctx, id).Pause(ctx, client.SchedulePauseOptions{Note: note})
+}
+
+// UnpauseSchedule resumes a paused schedule
+func UnpauseSchedule(ctx context.Context, c client.Client, id, note string) error {
+	LogInfo("Unpausing schedule %s", id)
+	return c.ScheduleClient().GetHandle(ctx, id).Unpause(ctx, client.ScheduleUnpauseOptions{Note: note})
+}
+
+// TriggerSchedule starts a run right now, outside the normal timetable
+func TriggerSchedule(ctx context.Context, c client.Client, id string, overlap enumspb.ScheduleOverlapPolicy) error {
+	LogInfo("Triggering schedule %s", id)
+	return c.ScheduleClient().GetHandle(ctx, id).Trigger(ctx, client.ScheduleTriggerOptions{Overlap: overlap})
+}
+
+// BackfillSchedule runs every action the schedule would have taken between start and end
+func BackfillSchedule(ctx context.Context, c client.Client, id string, start, end time.Time, overlap enumspb.ScheduleOverlapPolicy) error {
+	LogInfo("Backfilling schedule %s from %s to %s", id, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	return c.ScheduleClient().GetHandle(ctx, id).Backfill(ctx, client.ScheduleBackfillOptions{
+		Backfill: []client.ScheduleBackfill{{Start: start, End: end, Overlap: overlap}},
+	})
+}
+
+// DescribeSchedule returns the current definition and recent actions of a schedule
+func DescribeSchedule(ctx context.Context, c client.Client, id string) (*client.ScheduleDescription, error) {
+	return c.ScheduleClient().GetHandle(ctx, id).Describe(ctx)
+}
+
+// DeleteSchedule removes a schedule; workflows it already started keep running
+func DeleteSchedule(ctx context.Context, c client.Client, id string) error {
+	LogInfo("Deleting schedule %s", id)
+	return c.ScheduleClient().GetHandle(ctx, id).Delete(ctx)
+}
@@ -0,0 +1,239 @@
+package shared
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/mocks"
+	"go.temporal.io/sdk/workflow"
+)
+
+func rentSchedule(t *testing.T) client.Schedule {
+	payload, err := converter.GetDefaultDataConverter().ToPayload(map[string]interface{}{"amount": 1500.0, "reference": "Monthly rent"})
+	require.NoError(t, err)
+	return client.Schedule{
+		Spec: &client.ScheduleSpec{
+			CronExpressions: []string{"0 9 1 * *"},
+			TimeZoneName:    "Europe/London",
+		},
+		Policy: &client.SchedulePolicies{
+			Overlap:       enumspb.SCHEDULE_OVERLAP_POLICY_SKIP,
+			CatchupWindow: 24 * time.Hour,
+		},
+		Action: &client.ScheduleWorkflowAction{Args: []interface{}{payload}},
+	}
+}
+
+func TestScheduleChangesOnlyReplaceGivenFields(t *testing.T) {
+	schedule := rentSchedule(t)
+	jitter := 5 * time.Minute
+
+	require.NoError(t, ScheduleChanges{Jitter: &jitter}.apply(&schedule))
+
+	assert.Equal(t, jitter, schedule.Spec.Jitter)
+	assert.Equal(t, []string{"0 9 1 * *"}, schedule.Spec.CronExpressions)
+	assert.Equal(t, "Europe/London", schedule.Spec.TimeZoneName)
+	assert.Equal(t, enumspb.SCHEDULE_OVERLAP_POLICY_SKIP, schedule.Policy.Overlap)
+	assert.Equal(t, 24*time.Hour, schedule.Policy.CatchupWindow)
+	assert.Nil(t, schedule.State)
+}
+
+func TestScheduleChangesReplaceSpecAndPolicies(t *testing.T) {
+	schedule := rentSchedule(t)
+	every, zone, catchup := 24*time.Hour, "", time.Hour
+	overlap := enumspb.SCHEDULE_OVERLAP_POLICY_BUFFER_ONE
+	note := "Moved to daily"
+
+	require.NoError(t, ScheduleChanges{
+		CronExpressions: []string{},
+		Interval:        &every,
+		TimeZone:        &zone,
+		Overlap:         &overlap,
+		CatchupWindow:   &catchup,
+		Note:            &note,
+	}.apply(&schedule))
+
+	assert.Empty(t, schedule.Spec.CronExpressions)
+	assert.Equal(t, []client.ScheduleIntervalSpec{{Every: every}}, schedule.Spec.Intervals)
+	assert.Empty(t, schedule.Spec.TimeZoneName)
+	assert.Equal(t, overlap, schedule.Policy.Overlap)
+	assert.Equal(t, catchup, schedule.Policy.CatchupWindow)
+	assert.Equal(t, note, schedule.State.Note)
+}
+
+func TestScheduleChangesArgsStartFromScheduledValues(t *testing.T) {
+	schedule := rentSchedule(t)
+
+	err := ScheduleChanges{
+		Args: func(current []interface{}) ([]interface{}, error) {
+			var scheduled map[string]interface{}
+			if err := DecodeScheduleArg(current[0], &scheduled); err != nil {
+				return nil, err
+			}
+			scheduled["amount"] = 1550.0
+			return []interface{}{scheduled}, nil
+		},
+	}.apply(&schedule)
+	require.NoError(t, err)
+
+	args := schedule.Action.(*client.ScheduleWorkflowAction).Args
+	assert.Equal(t, []interface{}{map[string]interface{}{"amount": 1550.0, "reference": "Monthly rent"}}, args)
+}
+
+func TestParseOverlapPolicy(t *testing.T) {
+	policy, err := ParseOverlapPolicy("Buffer-One")
+	require.NoError(t, err)
+	assert.Equal(t, enumspb.SCHEDULE_OVERLAP_POLICY_BUFFER_ONE, policy)
+
+	policy, err = ParseOverlapPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, enumspb.SCHEDULE_OVERLAP_POLICY_UNSPECIFIED, policy)
+
+	_, err = ParseOverlapPolicy("sometimes")
+	assert.Error(t, err)
+}
+
+// scheduledWorkflow stands in for the workflow a schedule starts
+func scheduledWorkflow(ctx workflow.Context, amount float64) error { return nil }
+
+// mockScheduleClient returns a client whose schedule calls go to the returned mocks
+func mockScheduleClient(id string) (*mocks.Client, *mocks.ScheduleClient, *mocks.ScheduleHandle) {
+	c := &mocks.Client{}
+	schedules := &mocks.ScheduleClient{}
+	handle := &mocks.ScheduleHandle{}
+	c.On("ScheduleClient").Return(schedules)
+	schedules.On("GetHandle", mock.Anything, id).Return(handle).Maybe()
+	return c, schedules, handle
+}
+
+func TestCreateScheduleBuildsSpecPolicyAndAction(t *testing.T) {
+	c, schedules, handle := mockScheduleClient("rent")
+	var options client.ScheduleOptions
+	schedules.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		options = args.Get(1).(client.ScheduleOptions)
+	}).Return(handle, nil)
+
+	_, err := CreateSchedule(context.Background(), c, ScheduleConfig{
+		ID:              "rent",
+		CronExpressions: []string{"0 9 1 * *"},
+		Interval:        24 * time.Hour,
+		TimeZone:        "Europe/London",
+		Overlap:         enumspb.SCHEDULE_OVERLAP_POLICY_BUFFER_ONE,
+		CatchupWindow:   6 * time.Hour,
+		Jitter:          5 * time.Minute,
+		Paused:          true,
+		Note:            "Created by test",
+	}, scheduledWorkflow, 1500.0)
+
+	require.NoError(t, err)
+	schedules.AssertExpectations(t)
+	assert.Equal(t, "rent", options.ID)
+	assert.Equal(t, client.ScheduleSpec{
+		CronExpressions: []string{"0 9 1 * *"},
+		Intervals:       []client.ScheduleIntervalSpec{{Every: 24 * time.Hour}},
+		TimeZoneName:    "Europe/London",
+		Jitter:          5 * time.Minute,
+	}, options.Spec)
+	assert.Equal(t, enumspb.SCHEDULE_OVERLAP_POLICY_BUFFER_ONE, options.Overlap)
+	assert.Equal(t, 6*time.Hour, options.CatchupWindow)
+	assert.True(t, options.Paused)
+	assert.Equal(t, "Created by test", options.Note)
+
+	action, ok := options.Action.(*client.ScheduleWorkflowAction)
+	require.True(t, ok, "the schedule should start a workflow")
+	assert.Equal(t, "rent-run", action.ID)
+	assert.Equal(t, TaskQueue, action.TaskQueue)
+	assert.Equal(t, []interface{}{1500.0}, action.Args)
+	assert.NotNil(t, action.Workflow)
+}
+
+func TestCreateScheduleWithIntervalOnly(t *testing.T) {
+	c, schedules, handle := mockScheduleClient("daily")
+	var options client.ScheduleOptions
+	schedules.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		options = args.Get(1).(client.ScheduleOptions)
+	}).Return(handle, nil)
+
+	_, err := CreateSchedule(context.Background(), c, ScheduleConfig{ID: "daily", Interval: 24 * time.Hour}, scheduledWorkflow, 10.0)
+
+	require.NoError(t, err)
+	assert.Empty(t, options.Spec.CronExpressions, "an interval schedule must not get a cron as well")
+	assert.Equal(t, []client.ScheduleIntervalSpec{{Every: 24 * time.Hour}}, options.Spec.Intervals)
+}
+
+func TestUpdateScheduleAppliesChangesToCurrentSchedule(t *testing.T) {
+	c, _, handle := mockScheduleClient("rent")
+	var updated *client.ScheduleUpdate
+	handle.On("Update", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		var err error
+		updated, err = args.Get(1).(client.ScheduleUpdateOptions).DoUpdate(client.ScheduleUpdateInput{
+			Description: client.ScheduleDescription{Schedule: rentSchedule(t)},
+		})
+		require.NoError(t, err)
+	}).Return(nil)
+	jitter := 10 * time.Minute
+
+	require.NoError(t, UpdateSchedule(context.Background(), c, "rent", ScheduleChanges{Jitter: &jitter}))
+
+	handle.AssertExpectations(t)
+	require.NotNil(t, updated)
+	assert.Equal(t, jitter, updated.Schedule.Spec.Jitter)
+	assert.Equal(t, []string{"0 9 1 * *"}, updated.Schedule.Spec.CronExpressions)
+	assert.Equal(t, enumspb.SCHEDULE_OVERLAP_POLICY_SKIP, updated.Schedule.Policy.Overlap)
+}
+
+func TestUpdateScheduleRejectsArgsForNonWorkflowAction(t *testing.T) {
+	c, _, handle := mockScheduleClient("rent")
+	var updateErr error
+	handle.On("Update", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		schedule := rentSchedule(t)
+		schedule.Action = nil
+		_, updateErr = args.Get(1).(client.ScheduleUpdateOptions).DoUpdate(client.ScheduleUpdateInput{
+			Description: client.ScheduleDescription{Schedule: schedule},
+		})
+	}).Return(nil)
+
+	err := UpdateSchedule(context.Background(), c, "rent", ScheduleChanges{
+		Args: func(current []interface{}) ([]interface{}, error) { return current, nil },
+	})
+
+	require.NoError(t, err)
+	require.Error(t, updateErr)
+	assert.Contains(t, updateErr.Error(), "schedule rent")
+}
+
+func TestBackfillScheduleRequestsRangeWithOverlap(t *testing.T) {
+	c, _, handle := mockScheduleClient("rent")
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+	handle.On("Backfill", mock.Anything, client.ScheduleBackfillOptions{
+		Backfill: []client.ScheduleBackfill{{Start: start, End: end, Overlap: enumspb.SCHEDULE_OVERLAP_POLICY_ALLOW_ALL}},
+	}).Return(nil)
+
+	require.NoError(t, BackfillSchedule(context.Background(), c, "rent", start, end, enumspb.SCHEDULE_OVERLAP_POLICY_ALLOW_ALL))
+
+	handle.AssertExpectations(t)
+}
+
+func TestPauseTriggerAndDeleteUseTheSchedule(t *testing.T) {
+	c, _, handle := mockScheduleClient("rent")
+	handle.On("Pause", mock.Anything, client.SchedulePauseOptions{Note: "Moving out"}).Return(nil)
+	handle.On("Unpause", mock.Anything, client.ScheduleUnpauseOptions{Note: "Back"}).Return(nil)
+	handle.On("Trigger", mock.Anything, client.ScheduleTriggerOptions{Overlap: enumspb.SCHEDULE_OVERLAP_POLICY_BUFFER_ONE}).Return(nil)
+	handle.On("Delete", mock.Anything).Return(nil)
+	ctx := context.Background()
+
+	require.NoError(t, PauseSchedule(ctx, c, "rent", "Moving out"))
+	require.NoError(t, UnpauseSchedule(ctx, c, "rent", "Back"))
+	require.NoError(t, TriggerSchedule(ctx, c, "rent", enumspb.SCHEDULE_OVERLAP_POLICY_BUFFER_ONE))
+	require.NoError(t, DeleteSchedule(ctx, c, "rent"))
+
+	handle.AssertExpectations(t)
+}
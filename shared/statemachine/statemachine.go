@@ -0,0 +1,154 @@
+// Package statemachine is a small typed state machine for workflow state
+//
+// States are a string type of your own, so they serialise as plain strings.
+// A Machine only moves along transitions declared with Allow; a Guard can
+// refuse a declared transition based on the current data, and OnEnter actions
+// run after a state is entered. Every move is kept in the history, which
+// workflows expose through a query.
+//
+// The package has no Temporal dependency. Workflows pass workflow.Now(ctx) as
+// the transition time so a replay records the same history.
+package statemachine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var (
+	// ErrIllegalTransition means the transition was never declared with Allow
+	ErrIllegalTransition = errors.New("illegal transition")
+	// ErrGuardRejected means the transition is declared but a guard refused it
+	ErrGuardRejected = errors.New("transition rejected by guard")
+)
+
+// Transition is one move in a machine's history
+type Transition[S ~string] struct {
+	From   S         `json:"from"`
+	To     S         `json:"to"`
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+type edge[S ~string] struct {
+	from, to S
+}
+
+// Machine holds the current state, the allowed transitions and the history
+// Declare everything before the first Move; a Machine is not safe for
+// concurrent use, which workflow code never needs
+type Machine[S ~string] struct {
+	current      S
+	states       []S
+	allowed      map[S][]S
+	guards       map[edge[S]][]func() error
+	onEnter      map[S][]func(from S)
+	onTransition []func(Transition[S])
+	history      []Transition[S]
+}
+
+// New creates a machine in its initial state
+func New[S ~string](initial S) *Machine[S] {
+	return &Machine[S]{
+		current: initial,
+		states:  []S{initial},
+		allowed: make(map[S][]S),
+		guards:  make(map[edge[S]][]func() error),
+		onEnter: make(map[S][]func(from S)),
+	}
+}
+
+// Allow declares transitions from one state to each of the others
+func (m *Machine[S]) Allow(from S, to ...S) *Machine[S] {
+	m.addState(from)
+	for _, state := range to {
+		m.addState(state)
+		if !slices.Contains(m.allowed[from], state) {
+			m.allowed[from] = append(m.allowed[from], state)
+		}
+	}
+	return m
+}
+
+// Guard adds a check to a declared transition; a non-nil error refuses it
+func (m *Machine[S]) Guard(from, to S, guard func() error) *Machine[S] {
+	key := edge[S]{from, to}
+	m.guards[key] = append(m.guards[key], guard)
+	return m
+}
+
+// OnEnter adds an action that runs after the machine enters a state
+func (m *Machine[S]) OnEnter(state S, action func(from S)) *Machine[S] {
+	m.onEnter[state] = append(m.onEnter[state], action)
+	return m
+}
+
+// OnTransition adds an action that runs after every transition, before the entry actions
+func (m *Machine[S]) OnTransition(action func(Transition[S])) *Machine[S] {
+	m.onTransition = append(m.onTransition, action)
+	return m
+}
+
+// Current is the current state
+func (m *Machine[S]) Current() S {
+	return m.current
+}
+
+// States lists every declared state, in the order they were first declared
+func (m *Machine[S]) States() []S {
+	return slices.Clone(m.states)
+}
+
+// Allowed lists the states a state may move to; none means it is final
+func (m *Machine[S]) Allowed(from S) []S {
+	return slices.Clone(m.allowed[from])
+}
+
+// Final reports whether the current state has no way out
+func (m *Machine[S]) Final() bool {
+	return len(m.allowed[m.current]) == 0
+}
+
+// Can reports why the machine cannot move to a state now, or nil if it can
+func (m *Machine[S]) Can(to S) error {
+	if !slices.Contains(m.allowed[m.current], to) {
+		return fmt.Errorf("%w: %s -> %s", ErrIllegalTransition, m.current, to)
+	}
+	for _, guard := range m.guards[edge[S]{m.current, to}] {
+		if err := guard(); err != nil {
+			return fmt.Errorf("%w: %s -> %s: %w", ErrGuardRejected, m.current, to, err)
+		}
+	}
+	return nil
+}
+
+// Move transitions to a state, records it and runs the actions
+// On error the machine stays where it was and nothing runs
+func (m *Machine[S]) Move(to S, reason string, at time.Time) error {
+	if err := m.Can(to); err != nil {
+		return err
+	}
+	transition := Transition[S]{From: m.current, To: to, Reason: reason, At: at}
+	m.current = to
+	m.history = append(m.history, transition)
+	for _, action := range m.onTransition {
+		action(transition)
+	}
+	for _, action := range m.onEnter[to] {
+		action(transition.From)
+	}
+	return nil
+}
+
+// History returns every transition so far, oldest first
+func (m *Machine[S]) History() []Transition[S] {
+	return slices.Clone(m.history)
+}
+
+func (m *Machine[S]) addState(state S) {
+	if !slices.Contains(m.states, state) {
+		m.states = append(m.states, state)
+	}
+}
@@ -0,0 +1,147 @@
+package statemachine
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderState string
+
+const (
+	pending   orderState = "pending"
+	paid      orderState = "paid"
+	shipped   orderState = "shipped"
+	delivered orderState = "delivered"
+	canceled  orderState = "canceled"
+)
+
+// declared is every transition newOrderMachine allows; anything else is illegal
+var declared = map[orderState][]orderState{
+	pending: {paid, canceled},
+	paid:    {shipped, canceled},
+	shipped: {delivered},
+}
+
+var at = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// newOrderMachine declares the order transitions, starting in the given state
+func newOrderMachine(start orderState) *Machine[orderState] {
+	return New(start).
+		Allow(pending, paid, canceled).
+		Allow(paid, shipped, canceled).
+		Allow(shipped, delivered)
+}
+
+func TestMachineDeclaresStatesAndTransitions(t *testing.T) {
+	machine := newOrderMachine(pending)
+
+	assert.Equal(t, []orderState{pending, paid, canceled, shipped, delivered}, machine.States())
+	for _, from := range machine.States() {
+		assert.ElementsMatch(t, declared[from], machine.Allowed(from), "from %s", from)
+	}
+}
+
+func TestMachineRejectsEveryUndeclaredTransition(t *testing.T) {
+	states := newOrderMachine(pending).States()
+	illegal := 0
+	for _, from := range states {
+		for _, to := range states {
+			machine := newOrderMachine(from)
+			err := machine.Move(to, "test", at)
+
+			if slices.Contains(machine.Allowed(from), to) {
+				require.NoError(t, err, "%s -> %s", from, to)
+				assert.Equal(t, to, machine.Current())
+				assert.Equal(t, []Transition[orderState]{{From: from, To: to, Reason: "test", At: at}}, machine.History())
+				continue
+			}
+			illegal++
+			require.ErrorIs(t, err, ErrIllegalTransition, "%s -> %s", from, to)
+			assert.ErrorIs(t, machine.Can(to), ErrIllegalTransition)
+			assert.Equal(t, from, machine.Current(), "a rejected move should not change the state")
+			assert.Empty(t, machine.History(), "a rejected move should not be recorded")
+		}
+	}
+	// 25 pairs, 5 declared; staying put is never declared, so it counts too
+	assert.Equal(t, 20, illegal)
+}
+
+func TestMachineFinalStates(t *testing.T) {
+	for _, state := range newOrderMachine(pending).States() {
+		machine := newOrderMachine(state)
+		assert.Equal(t, len(declared[state]) == 0, machine.Final(), "%s", state)
+	}
+}
+
+func TestGuardRefusesDeclaredTransition(t *testing.T) {
+	errUnpaid := errors.New("payment not captured")
+	captured := false
+	entered := 0
+	machine := newOrderMachine(paid).
+		Guard(paid, shipped, func() error {
+			if !captured {
+				return errUnpaid
+			}
+			return nil
+		}).
+		OnEnter(shipped, func(from orderState) { entered++ })
+
+	err := machine.Move(shipped, "warehouse", at)
+	require.ErrorIs(t, err, ErrGuardRejected)
+	assert.ErrorIs(t, err, errUnpaid)
+	assert.NotErrorIs(t, err, ErrIllegalTransition)
+	assert.Equal(t, paid, machine.Current())
+	assert.Empty(t, machine.History())
+	assert.Zero(t, entered, "no action should run for a refused move")
+
+	// The guard only covers paid -> shipped
+	assert.NoError(t, newOrderMachine(paid).Guard(paid, shipped, func() error { return errUnpaid }).Can(canceled))
+
+	captured = true
+	require.NoError(t, machine.Move(shipped, "warehouse", at))
+	assert.Equal(t, shipped, machine.Current())
+	assert.Equal(t, 1, entered)
+}
+
+func TestIllegalTransitionSkipsGuards(t *testing.T) {
+	guarded := 0
+	machine := newOrderMachine(pending).Guard(pending, delivered, func() error {
+		guarded++
+		return nil
+	})
+
+	// A guard on an undeclared transition doesn't make it legal
+	err := machine.Move(delivered, "test", at)
+	require.ErrorIs(t, err, ErrIllegalTransition)
+	assert.NotErrorIs(t, err, ErrGuardRejected)
+	assert.Zero(t, guarded)
+}
+
+func TestMoveRunsActionsInOrder(t *testing.T) {
+	var calls []string
+	machine := newOrderMachine(pending).
+		OnTransition(func(t Transition[orderState]) {
+			calls = append(calls, "transition "+string(t.From)+" -> "+string(t.To))
+		}).
+		OnEnter(paid, func(from orderState) { calls = append(calls, "enter paid from "+string(from)) }).
+		OnEnter(shipped, func(from orderState) { calls = append(calls, "enter shipped from "+string(from)) })
+
+	require.NoError(t, machine.Move(paid, "payment", at))
+	require.NoError(t, machine.Move(shipped, "warehouse", at.Add(time.Hour)))
+
+	assert.Equal(t, []string{
+		"transition pending -> paid",
+		"enter paid from pending",
+		"transition paid -> shipped",
+		"enter shipped from paid",
+	}, calls)
+	assert.Equal(t, []Transition[orderState]{
+		{From: pending, To: paid, Reason: "payment", At: at},
+		{From: paid, To: shipped, Reason: "warehouse", At: at.Add(time.Hour)},
+	}, machine.History())
+}
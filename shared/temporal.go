@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 	"os"
+	"strconv"
 
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
@@ -45,8 +46,18 @@ func CreateTemporalClient() (client.Client, error) {
 
 // CreateTemporalWorker creates and returns a Temporal worker
 // Workers are responsible for executing workflows and activities
+// Set TASK_QUEUE_ACTIVITIES_PER_SECOND to have the server cap how many activities
+// per second all workers on the task queue may start
 func CreateTemporalWorker(c client.Client) worker.Worker {
-	w := worker.New(c, TaskQueue, worker.Options{})
+	options := worker.Options{}
+	if perSecond := os.Getenv("TASK_QUEUE_ACTIVITIES_PER_SECOND"); perSecond != "" {
+		rate, err := strconv.ParseFloat(perSecond, 64)
+		if err != nil {
+			log.Fatalln("Invalid TASK_QUEUE_ACTIVITIES_PER_SECOND", err)
+		}
+		options.TaskQueueActivitiesPerSecond = rate
+	}
+	w := worker.New(c, TaskQueue, options)
 	return w
 }
 